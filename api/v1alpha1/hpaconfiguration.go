@@ -0,0 +1,42 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// Equal tells whether the HPAConfigurations set the same replicas and the same metric targets.
+func (c HPAConfiguration) Equal(other HPAConfiguration) bool {
+	if c.Min != other.Min || c.Max != other.Max || c.TargetMetricValue != other.TargetMetricValue ||
+		len(c.Metrics) != len(other.Metrics) {
+		return false
+	}
+	for i := range c.Metrics {
+		if !c.Metrics[i].Equal(other.Metrics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal tells whether the MetricTargets are for the same metric, with the same targets.
+func (t MetricTarget) Equal(other MetricTarget) bool {
+	if t.Name != other.Name || t.Type != other.Type || t.TargetValue != other.TargetValue {
+		return false
+	}
+	if t.AverageValue == nil || other.AverageValue == nil {
+		return t.AverageValue == nil && other.AverageValue == nil
+	}
+	return t.AverageValue.Cmp(*other.AverageValue) == 0
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MetricExclusionSpec defines the time range to leave out of the metric window of the recommenders, such as a load
+// test or an incident, and the workloads it applies to.
+type MetricExclusionSpec struct {
+	// Start is the beginning of the time range to exclude.
+	Start metav1.Time `json:"start"`
+	// End is the end of the time range to exclude.
+	End metav1.Time `json:"end"`
+	// Reason tells why the time range is excluded, e.g. the load test or the incident it covers.
+	Reason string `json:"reason,omitempty"`
+	// Namespace limits the exclusion to the workloads of the namespace. Empty applies it to the whole cluster.
+	Namespace string `json:"namespace,omitempty"`
+	// Workload limits the exclusion to the workload of the given name in the Namespace. Empty applies it to all the
+	// workloads of the Namespace.
+	Workload string `json:"workload,omitempty"`
+}
+
+// Covers tells whether the exclusion applies to the workload.
+func (s MetricExclusionSpec) Covers(workloadSpec WorkloadSpec) bool {
+	if s.Namespace == "" {
+		return true
+	}
+	if s.Namespace != workloadSpec.Namespace {
+		return false
+	}
+	return s.Workload == "" || s.Workload == workloadSpec.Name
+}
+
+// MetricExclusionStatus defines the observed state of MetricExclusion
+type MetricExclusionStatus struct {
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+
+// MetricExclusion is the Schema for the metricexclusions API
+type MetricExclusion struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MetricExclusionSpec   `json:"spec,omitempty"`
+	Status MetricExclusionStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// MetricExclusionList contains a list of MetricExclusion
+type MetricExclusionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MetricExclusion `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MetricExclusion{}, &MetricExclusionList{})
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -33,6 +35,28 @@ type PolicySpec struct {
 	Min               int    `json:"min"`
 	TargetUtilization int    `json:"targetUtilization"`
 	IsDefault         bool   `json:"isDefault,omitempty"`
+	// Recommender is the name of the Recommender used for the workloads on this policy. The Recommender annotation
+	// on a workload takes precedence over it, and the cluster default is used when neither is set.
+	Recommender string `json:"recommender,omitempty"`
+	// CustomMetric is the metric the CustomMetricRecommender sizes the workloads on this policy on. The custom metric
+	// annotations on a workload take precedence over it, field by field.
+	CustomMetric *CustomMetricSpec `json:"customMetric,omitempty"`
+}
+
+// CustomMetricSpec describes a metric other than the utilization of a resource, such as the requests per second or
+// the depth of a queue, to autoscale a workload on.
+type CustomMetricSpec struct {
+	// Name is the name of the metric as served to the HPA by the metrics adapter.
+	Name string `json:"name,omitempty"`
+	// QueryTemplate is the PromQL query of the metric for a workload, aggregated to a single series over its pods.
+	// {{.Namespace}} and {{.Workload}} in the template are replaced by the namespace and the name of the workload.
+	QueryTemplate string `json:"queryTemplate,omitempty"`
+	// PerPodCapacity is the value of the metric a single pod can handle.
+	PerPodCapacity *resource.Quantity `json:"perPodCapacity,omitempty"`
+	// Type is Pods for a metric of the pods of the workload, or External for a metric that isn't attached to the
+	// pods, like the lag of a Kafka consumer group. Defaults to Pods.
+	// +kubebuilder:validation:Enum=Pods;External
+	Type autoscalingv2.MetricSourceType `json:"type,omitempty"`
 }
 
 // PolicyStatus defines the observed state of Policy
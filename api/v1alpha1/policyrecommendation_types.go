@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -36,6 +38,9 @@ type PolicyRecommendationSpec struct {
 	GeneratedAt            metav1.Time      `json:"generatedAt,omitempty"`
 	QueuedForExecution     bool             `json:"queuedForExecution"`
 	QueuedForExecutionAt   metav1.Time      `json:"queuedForExecutionAt,omitempty"`
+	// ShadowMode is resolved from the shadow mode annotation on the workload or its namespace. When set, the HPA is
+	// not applied and the would-be HPA spec is recorded in the status instead.
+	ShadowMode bool `json:"shadowMode,omitempty"`
 }
 
 type WorkloadSpec struct {
@@ -48,6 +53,219 @@ type HPAConfiguration struct {
 	Min               int `json:"min"`
 	Max               int `json:"max"`
 	TargetMetricValue int `json:"targetMetricValue"`
+	// Metrics lists the target of every metric the HPA scales on, for HPAs that scale on more than one metric. The
+	// HPA scales to the most replicas any of the metrics asks for. An HPA scaling on the cpu utilization alone leaves
+	// it empty and uses TargetMetricValue.
+	Metrics []MetricTarget `json:"metrics,omitempty"`
+}
+
+// MetricTarget is the target of one of the metrics of an HPA.
+type MetricTarget struct {
+	// Name is the name of the metric, cpu or memory for the utilization of the resource.
+	Name string `json:"name"`
+	// Type is the type of the source of the metric on the HPA. Empty means Resource.
+	Type autoscalingv2.MetricSourceType `json:"type,omitempty"`
+	// TargetValue is the target of the metric, the average utilization in percent for cpu and memory. For Pods and
+	// External metrics it is the utilization of the capacity of a pod that AverageValue stands for.
+	TargetValue int `json:"targetValue"`
+	// AverageValue is the target of the value of the metric per pod, for Pods and External metrics.
+	AverageValue *resource.Quantity `json:"averageValue,omitempty"`
+}
+
+// MetricContribution reports how much one of the metrics of a multi metric HPA drove the replicas in the simulation.
+type MetricContribution struct {
+	Name string `json:"name"`
+	// PeakReplicas is the number of replicas the metric asked for when the simulated replicas peaked. The metric
+	// with the most is the one that set the max replicas.
+	PeakReplicas int `json:"peakReplicas"`
+}
+
+// ConfigurationSource identifies what determined the value of a field in the TargetHPAConfiguration.
+type ConfigurationSource string
+
+const (
+	// RecommenderSource means the value was derived from the workload's metrics by the Recommender.
+	RecommenderSource ConfigurationSource = "Recommender"
+	// PolicySource means the value was bounded by the Policy assigned to the workload.
+	PolicySource ConfigurationSource = "Policy"
+)
+
+// HPAConfigurationSource records which side set each field of the TargetHPAConfiguration.
+type HPAConfigurationSource struct {
+	Min               ConfigurationSource `json:"min,omitempty"`
+	Max               ConfigurationSource `json:"max,omitempty"`
+	TargetMetricValue ConfigurationSource `json:"targetMetricValue,omitempty"`
+}
+
+// PolicyTransitionType describes the direction in which the Policy assigned to a workload was changed.
+type PolicyTransitionType string
+
+const (
+	// PolicyPromoted means the workload was moved to the next riskier Policy.
+	PolicyPromoted PolicyTransitionType = "Promoted"
+	// PolicyDemoted means the workload was moved to a safer Policy after a breach.
+	PolicyDemoted PolicyTransitionType = "Demoted"
+)
+
+// PolicyTransition records a change of the Policy assigned to the workload.
+type PolicyTransition struct {
+	Type           PolicyTransitionType `json:"type"`
+	FromPolicy     string               `json:"fromPolicy,omitempty"`
+	ToPolicy       string               `json:"toPolicy"`
+	TransitionTime metav1.Time          `json:"transitionTime"`
+}
+
+// BreachEvidence records the breach data points detected by the breach monitor for the workload.
+type BreachEvidence struct {
+	DetectedAt metav1.Time `json:"detectedAt"`
+	// Timestamps of the breached data points.
+	Timestamps []metav1.Time `json:"timestamps,omitempty"`
+	// PeakUtilizationPercent is the highest CPU utilization seen across the breached data points.
+	PeakUtilizationPercent int `json:"peakUtilizationPercent"`
+	// Severe is set when the peak utilization crossed the severe breach threshold.
+	Severe bool `json:"severe,omitempty"`
+}
+
+// Condition types recorded on the PolicyRecommendationStatus.
+const (
+	// RecommendationQueued is True while the PolicyRecommendation is queued for execution.
+	RecommendationQueued = "RecommendationQueued"
+	// RecommendationInProgress is True while the Recommender is running for the workload.
+	RecommendationInProgress = "RecommendationInProgress"
+	// RecommendationGenerated is True when the last execution generated a recommendation, and False with the reason
+	// of the failure otherwise.
+	RecommendationGenerated = "RecommendationGenerated"
+	// TargetReached is True when the TargetHPAConfiguration is the optimal HPAConfiguration, i.e. it is no longer
+	// bounded by the Policy assigned to the workload.
+	TargetReached = "TargetReached"
+	// BreachDetected is True when the breach monitor has detected a breach since the last promotion of the workload.
+	BreachDetected = "BreachDetected"
+	// RecommendationWarning is True when the Recommender had to adjust the last recommendation in a way the owners of
+	// the workload need to know about, e.g. capping the max replicas by the namespace quota.
+	RecommendationWarning = "RecommendationWarning"
+)
+
+// Condition reasons recorded on the PolicyRecommendationStatus.
+const (
+	ReasonCreated             = "Created"
+	ReasonTriggered           = "Triggered"
+	ReasonBreached            = "Breached"
+	ReasonSevereBreach        = "SevereBreach"
+	ReasonExecuted            = "Executed"
+	ReasonRunning             = "Running"
+	ReasonCompleted           = "Completed"
+	ReasonRecommendationReady = "RecommendationReady"
+	ReasonNoRecommendation    = "NoRecommendation"
+	ReasonRecommenderFailed   = "RecommenderFailed"
+	ReasonOptimalTarget       = "OptimalTarget"
+	ReasonBoundedByPolicy     = "BoundedByPolicy"
+	ReasonPromoted            = "Promoted"
+	ReasonNoWarnings          = "NoWarnings"
+)
+
+// RecommendationHistoryEntry records a TargetHPAConfiguration generated for the workload along with the Policy it
+// was generated for.
+type RecommendationHistoryEntry struct {
+	HPAConfiguration HPAConfiguration `json:"hpaConfig"`
+	Policy           string           `json:"policy,omitempty"`
+	GeneratedAt      metav1.Time      `json:"generatedAt"`
+}
+
+// BreachBudgetUsage reports the breach budget a recommendation was generated with, and how much of it the simulated
+// HPA consumed at the recommended target.
+type BreachBudgetUsage struct {
+	// Allowed is the total duration of breaches tolerated over the metric window.
+	Allowed metav1.Duration `json:"allowed"`
+	// MaxConsecutiveBreachDuration is the longest a single breach is allowed to last. Zero means no such limit.
+	MaxConsecutiveBreachDuration metav1.Duration `json:"maxConsecutiveBreachDuration,omitempty"`
+	// Consumed is the duration of breaches, weighted by their magnitude if so configured, in the simulation.
+	Consumed metav1.Duration `json:"consumed"`
+	// LongestBreach is the longest consecutive breach in the simulation.
+	LongestBreach metav1.Duration `json:"longestBreach"`
+}
+
+// ForecastSummary describes the forecast of the metric a recommendation was simulated against.
+type ForecastSummary struct {
+	// Mode is Forecast when the HPA was simulated against the forecast, and WorstOfForecastAndHistory when it was
+	// simulated against the larger of the forecast and the history one horizon earlier.
+	Mode string `json:"mode"`
+	// Horizon is how far ahead the metric was forecast.
+	Horizon metav1.Duration `json:"horizon"`
+	// SeasonalPeriods are the seasons the history was long enough to fit.
+	SeasonalPeriods []metav1.Duration `json:"seasonalPeriods,omitempty"`
+	// TrendPerDay is the change of the metric per day of the trend of the history.
+	TrendPerDay resource.Quantity `json:"trendPerDay"`
+	// HistoryPeak is the peak of the history of the metric.
+	HistoryPeak resource.Quantity `json:"historyPeak"`
+	// ForecastPeak is the peak of the metric the HPA was simulated against.
+	ForecastPeak resource.Quantity `json:"forecastPeak"`
+	// SeriesConfigMap is the name of the ConfigMap whose explanation records the forecast series the HPA was
+	// simulated against, under the forecast of every metric.
+	SeriesConfigMap string `json:"seriesConfigMap,omitempty"`
+}
+
+// AutoscalingCycleLag is the time the workload takes to get a new pod serving after its load went up, which the
+// recommendation was simulated with, broken down into the parts of the autoscaling cycle.
+type AutoscalingCycleLag struct {
+	// Total is the lag the HPA was simulated with.
+	Total metav1.Duration `json:"total"`
+	// Overridden is true when the Total was set by the autoscaling cycle lag annotation of the workload, in which
+	// case the parts aren't measured.
+	Overridden      bool             `json:"overridden,omitempty"`
+	MetricIngestion *metav1.Duration `json:"metricIngestion,omitempty"`
+	MetricProbe     *metav1.Duration `json:"metricProbe,omitempty"`
+	Scheduling      *metav1.Duration `json:"scheduling,omitempty"`
+	ImagePull       *metav1.Duration `json:"imagePull,omitempty"`
+	Readiness       *metav1.Duration `json:"readiness,omitempty"`
+}
+
+// ExcludedMetricRange is a time range of the metric window a recommendation was generated without.
+type ExcludedMetricRange struct {
+	// Exclusion is the name of the MetricExclusion that declared the time range.
+	Exclusion string      `json:"exclusion"`
+	Start     metav1.Time `json:"start"`
+	End       metav1.Time `json:"end"`
+	Reason    string      `json:"reason,omitempty"`
+}
+
+// ScalingMode is how the replicas of a workload are set.
+type ScalingMode string
+
+const (
+	// StaticReplicas is the mode of a workload that runs the replicas set on it.
+	StaticReplicas ScalingMode = "StaticReplicas"
+	// HPAScaling is the mode of a workload that an HPA scales on its cpu utilization.
+	HPAScaling ScalingMode = "HPA"
+)
+
+// CostEstimate compares the cpu core hours requested by the pods of the workload under its current scaling with those
+// under the recommended HPA, over the data points the recommendation was simulated against.
+type CostEstimate struct {
+	// CurrentScaling is how the workload is scaled today.
+	CurrentScaling ScalingMode `json:"currentScaling"`
+	// Window is the time the core hours are estimated over, which leaves out the gaps in the metrics. It spans the
+	// forecast horizon when the HPA was simulated against a forecast.
+	Window               metav1.Duration   `json:"window"`
+	CurrentCoreHours     resource.Quantity `json:"currentCoreHours"`
+	RecommendedCoreHours resource.Quantity `json:"recommendedCoreHours"`
+	// SavedCoreHours is the CurrentCoreHours less the RecommendedCoreHours, which is negative when the recommended HPA
+	// requests more than the current scaling.
+	SavedCoreHours resource.Quantity `json:"savedCoreHours"`
+	// SavingsPercentage is the SavedCoreHours as a percentage of the CurrentCoreHours.
+	SavingsPercentage int `json:"savingsPercentage"`
+	// ProjectedMonthlySavedCoreHours is the SavedCoreHours projected over 30 days.
+	ProjectedMonthlySavedCoreHours resource.Quantity `json:"projectedMonthlySavedCoreHours"`
+}
+
+// MinReplicasScheduleEntry raises the minReplicas of the HPA of a workload over a block of the week. The Start and
+// End are cron expressions in the Timezone, in the form a KEDA cron trigger takes them.
+type MinReplicasScheduleEntry struct {
+	Start       string `json:"start"`
+	End         string `json:"end"`
+	Timezone    string `json:"timezone"`
+	MinReplicas int    `json:"minReplicas"`
+	// PeakLoad is the peak of the cpu cores used by the workload over the block, in the weekly profile of its load.
+	PeakLoad resource.Quantity `json:"peakLoad"`
 }
 
 // PolicyRecommendationStatus defines the observed state of PolicyRecommendation
@@ -55,6 +273,42 @@ type PolicyRecommendationStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// OptimalHPAConfiguration is the data driven HPAConfiguration generated by the Recommender before it is bounded
+	// by the Policy.
+	OptimalHPAConfiguration *HPAConfiguration `json:"optimalHPAConfig,omitempty"`
+	// TargetHPAConfigurationSource records whether the Recommender or the Policy set each field of the
+	// TargetHPAConfiguration.
+	TargetHPAConfigurationSource HPAConfigurationSource `json:"targetHPAConfigSource,omitempty"`
+	// PolicyTransitions lists the most recent changes of the Policy assigned to the workload, oldest first.
+	PolicyTransitions []PolicyTransition `json:"policyTransitions,omitempty"`
+	// LastBreach is the evidence of the most recent breach detected for the workload.
+	LastBreach *BreachEvidence `json:"lastBreach,omitempty"`
+	// ShadowHPASpec is the HPA spec that would have been applied for the workload had it not been in shadow mode.
+	ShadowHPASpec *autoscalingv2.HorizontalPodAutoscalerSpec `json:"shadowHPASpec,omitempty"`
+	// RecommendationHistory lists the most recent distinct TargetHPAConfigurations generated for the workload, oldest
+	// first.
+	RecommendationHistory []RecommendationHistoryEntry `json:"recommendationHistory,omitempty"`
+	// Recommender is the name of the Recommender chosen for the workload in the last execution.
+	Recommender string `json:"recommender,omitempty"`
+	// BreachBudget reports the breach budget consumed by the last recommendation.
+	BreachBudget *BreachBudgetUsage `json:"breachBudget,omitempty"`
+	// MetricContributions reports the contribution of each metric to the peak replicas, for recommendations on more
+	// than one metric.
+	MetricContributions []MetricContribution `json:"metricContributions,omitempty"`
+	// Forecast summarizes the forecast the last recommendation was simulated against, if any.
+	Forecast *ForecastSummary `json:"forecast,omitempty"`
+	// ExcludedMetricRanges lists the time ranges left out of the metric window of the last recommendation.
+	ExcludedMetricRanges []ExcludedMetricRange `json:"excludedMetricRanges,omitempty"`
+	// AutoscalingCycleLag is the lag the last recommendation was simulated with.
+	AutoscalingCycleLag *AutoscalingCycleLag `json:"autoscalingCycleLag,omitempty"`
+	// ExplanationConfigMap is the name of the ConfigMap in the namespace of the workload that explains how the last
+	// recommendation was reached.
+	ExplanationConfigMap string `json:"explanationConfigMap,omitempty"`
+	// Cost estimates the savings of the last recommendation over the current scaling of the workload.
+	Cost *CostEstimate `json:"cost,omitempty"`
+	// MinReplicasSchedule lists the blocks of the week the last recommendation raises the minReplicas of the HPA
+	// over, for the workloads whose Recommender schedules them.
+	MinReplicasSchedule []MinReplicasScheduleEntry `json:"minReplicasSchedule,omitempty"`
 }
 
 //+kubebuilder:object:root=true
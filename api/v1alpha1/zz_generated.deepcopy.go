@@ -22,13 +22,185 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/api/autoscaling/v2"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoscalingCycleLag) DeepCopyInto(out *AutoscalingCycleLag) {
+	*out = *in
+	out.Total = in.Total
+	if in.MetricIngestion != nil {
+		in, out := &in.MetricIngestion, &out.MetricIngestion
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MetricProbe != nil {
+		in, out := &in.MetricProbe, &out.MetricProbe
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Scheduling != nil {
+		in, out := &in.Scheduling, &out.Scheduling
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ImagePull != nil {
+		in, out := &in.ImagePull, &out.ImagePull
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Readiness != nil {
+		in, out := &in.Readiness, &out.Readiness
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingCycleLag.
+func (in *AutoscalingCycleLag) DeepCopy() *AutoscalingCycleLag {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoscalingCycleLag)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BreachBudgetUsage) DeepCopyInto(out *BreachBudgetUsage) {
+	*out = *in
+	out.Allowed = in.Allowed
+	out.MaxConsecutiveBreachDuration = in.MaxConsecutiveBreachDuration
+	out.Consumed = in.Consumed
+	out.LongestBreach = in.LongestBreach
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BreachBudgetUsage.
+func (in *BreachBudgetUsage) DeepCopy() *BreachBudgetUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(BreachBudgetUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BreachEvidence) DeepCopyInto(out *BreachEvidence) {
+	*out = *in
+	in.DetectedAt.DeepCopyInto(&out.DetectedAt)
+	if in.Timestamps != nil {
+		in, out := &in.Timestamps, &out.Timestamps
+		*out = make([]v1.Time, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BreachEvidence.
+func (in *BreachEvidence) DeepCopy() *BreachEvidence {
+	if in == nil {
+		return nil
+	}
+	out := new(BreachEvidence)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostEstimate) DeepCopyInto(out *CostEstimate) {
+	*out = *in
+	out.Window = in.Window
+	out.CurrentCoreHours = in.CurrentCoreHours.DeepCopy()
+	out.RecommendedCoreHours = in.RecommendedCoreHours.DeepCopy()
+	out.SavedCoreHours = in.SavedCoreHours.DeepCopy()
+	out.ProjectedMonthlySavedCoreHours = in.ProjectedMonthlySavedCoreHours.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostEstimate.
+func (in *CostEstimate) DeepCopy() *CostEstimate {
+	if in == nil {
+		return nil
+	}
+	out := new(CostEstimate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomMetricSpec) DeepCopyInto(out *CustomMetricSpec) {
+	*out = *in
+	if in.PerPodCapacity != nil {
+		in, out := &in.PerPodCapacity, &out.PerPodCapacity
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomMetricSpec.
+func (in *CustomMetricSpec) DeepCopy() *CustomMetricSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomMetricSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExcludedMetricRange) DeepCopyInto(out *ExcludedMetricRange) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExcludedMetricRange.
+func (in *ExcludedMetricRange) DeepCopy() *ExcludedMetricRange {
+	if in == nil {
+		return nil
+	}
+	out := new(ExcludedMetricRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForecastSummary) DeepCopyInto(out *ForecastSummary) {
+	*out = *in
+	out.Horizon = in.Horizon
+	if in.SeasonalPeriods != nil {
+		in, out := &in.SeasonalPeriods, &out.SeasonalPeriods
+		*out = make([]v1.Duration, len(*in))
+		copy(*out, *in)
+	}
+	out.TrendPerDay = in.TrendPerDay.DeepCopy()
+	out.HistoryPeak = in.HistoryPeak.DeepCopy()
+	out.ForecastPeak = in.ForecastPeak.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForecastSummary.
+func (in *ForecastSummary) DeepCopy() *ForecastSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(ForecastSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HPAConfiguration) DeepCopyInto(out *HPAConfiguration) {
 	*out = *in
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = make([]MetricTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HPAConfiguration.
@@ -41,12 +213,169 @@ func (in *HPAConfiguration) DeepCopy() *HPAConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HPAConfigurationSource) DeepCopyInto(out *HPAConfigurationSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HPAConfigurationSource.
+func (in *HPAConfigurationSource) DeepCopy() *HPAConfigurationSource {
+	if in == nil {
+		return nil
+	}
+	out := new(HPAConfigurationSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricContribution) DeepCopyInto(out *MetricContribution) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricContribution.
+func (in *MetricContribution) DeepCopy() *MetricContribution {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricContribution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricExclusion) DeepCopyInto(out *MetricExclusion) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricExclusion.
+func (in *MetricExclusion) DeepCopy() *MetricExclusion {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricExclusion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MetricExclusion) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricExclusionList) DeepCopyInto(out *MetricExclusionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MetricExclusion, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricExclusionList.
+func (in *MetricExclusionList) DeepCopy() *MetricExclusionList {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricExclusionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MetricExclusionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricExclusionSpec) DeepCopyInto(out *MetricExclusionSpec) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricExclusionSpec.
+func (in *MetricExclusionSpec) DeepCopy() *MetricExclusionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricExclusionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricExclusionStatus) DeepCopyInto(out *MetricExclusionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricExclusionStatus.
+func (in *MetricExclusionStatus) DeepCopy() *MetricExclusionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricExclusionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricTarget) DeepCopyInto(out *MetricTarget) {
+	*out = *in
+	if in.AverageValue != nil {
+		in, out := &in.AverageValue, &out.AverageValue
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricTarget.
+func (in *MetricTarget) DeepCopy() *MetricTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MinReplicasScheduleEntry) DeepCopyInto(out *MinReplicasScheduleEntry) {
+	*out = *in
+	out.PeakLoad = in.PeakLoad.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MinReplicasScheduleEntry.
+func (in *MinReplicasScheduleEntry) DeepCopy() *MinReplicasScheduleEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(MinReplicasScheduleEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Policy) DeepCopyInto(out *Policy) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	out.Status = in.Status
 }
 
@@ -163,7 +492,7 @@ func (in *PolicyRecommendationList) DeepCopyObject() runtime.Object {
 func (in *PolicyRecommendationSpec) DeepCopyInto(out *PolicyRecommendationSpec) {
 	*out = *in
 	out.WorkloadSpec = in.WorkloadSpec
-	out.TargetHPAConfiguration = in.TargetHPAConfiguration
+	in.TargetHPAConfiguration.DeepCopyInto(&out.TargetHPAConfiguration)
 	in.Policy.DeepCopyInto(&out.Policy)
 	in.GeneratedAt.DeepCopyInto(&out.GeneratedAt)
 	in.QueuedForExecutionAt.DeepCopyInto(&out.QueuedForExecutionAt)
@@ -189,6 +518,75 @@ func (in *PolicyRecommendationStatus) DeepCopyInto(out *PolicyRecommendationStat
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.OptimalHPAConfiguration != nil {
+		in, out := &in.OptimalHPAConfiguration, &out.OptimalHPAConfiguration
+		*out = new(HPAConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
+	out.TargetHPAConfigurationSource = in.TargetHPAConfigurationSource
+	if in.PolicyTransitions != nil {
+		in, out := &in.PolicyTransitions, &out.PolicyTransitions
+		*out = make([]PolicyTransition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastBreach != nil {
+		in, out := &in.LastBreach, &out.LastBreach
+		*out = new(BreachEvidence)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ShadowHPASpec != nil {
+		in, out := &in.ShadowHPASpec, &out.ShadowHPASpec
+		*out = new(v2.HorizontalPodAutoscalerSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RecommendationHistory != nil {
+		in, out := &in.RecommendationHistory, &out.RecommendationHistory
+		*out = make([]RecommendationHistoryEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BreachBudget != nil {
+		in, out := &in.BreachBudget, &out.BreachBudget
+		*out = new(BreachBudgetUsage)
+		**out = **in
+	}
+	if in.MetricContributions != nil {
+		in, out := &in.MetricContributions, &out.MetricContributions
+		*out = make([]MetricContribution, len(*in))
+		copy(*out, *in)
+	}
+	if in.Forecast != nil {
+		in, out := &in.Forecast, &out.Forecast
+		*out = new(ForecastSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExcludedMetricRanges != nil {
+		in, out := &in.ExcludedMetricRanges, &out.ExcludedMetricRanges
+		*out = make([]ExcludedMetricRange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AutoscalingCycleLag != nil {
+		in, out := &in.AutoscalingCycleLag, &out.AutoscalingCycleLag
+		*out = new(AutoscalingCycleLag)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cost != nil {
+		in, out := &in.Cost, &out.Cost
+		*out = new(CostEstimate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MinReplicasSchedule != nil {
+		in, out := &in.MinReplicasSchedule, &out.MinReplicasSchedule
+		*out = make([]MinReplicasScheduleEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyRecommendationStatus.
@@ -204,6 +602,11 @@ func (in *PolicyRecommendationStatus) DeepCopy() *PolicyRecommendationStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PolicySpec) DeepCopyInto(out *PolicySpec) {
 	*out = *in
+	if in.CustomMetric != nil {
+		in, out := &in.CustomMetric, &out.CustomMetric
+		*out = new(CustomMetricSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicySpec.
@@ -231,6 +634,39 @@ func (in *PolicyStatus) DeepCopy() *PolicyStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyTransition) DeepCopyInto(out *PolicyTransition) {
+	*out = *in
+	in.TransitionTime.DeepCopyInto(&out.TransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyTransition.
+func (in *PolicyTransition) DeepCopy() *PolicyTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecommendationHistoryEntry) DeepCopyInto(out *RecommendationHistoryEntry) {
+	*out = *in
+	in.HPAConfiguration.DeepCopyInto(&out.HPAConfiguration)
+	in.GeneratedAt.DeepCopyInto(&out.GeneratedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecommendationHistoryEntry.
+func (in *RecommendationHistoryEntry) DeepCopy() *RecommendationHistoryEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(RecommendationHistoryEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkloadSpec) DeepCopyInto(out *WorkloadSpec) {
 	*out = *in
@@ -34,6 +34,7 @@ import (
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -67,12 +68,20 @@ type Config struct {
 		PrometheusUrl        string `yaml:"prometheusUrl"`
 		QueryTimeoutSec      int    `yaml:"queryTimeoutSec"`
 		QuerySplitIntervalHr int    `yaml:"querySplitIntervalHr"`
+		// MaxInterpolatedGapSec is the longest scrape outage filled in by interpolation. Longer outages are skipped by
+		// the recommenders.
+		MaxInterpolatedGapSec int `yaml:"maxInterpolatedGapSec"`
+		// ACLPercentile is the percentile of the startup of the pods of a workload in the ACL window its autoscaling
+		// cycle lag is computed from. 0 takes the fastest pod.
+		ACLPercentile   float64 `yaml:"aclPercentile"`
+		ACLWindowInDays int     `yaml:"aclWindowInDays"`
 	} `yaml:"metricsScraper"`
 
 	BreachMonitor struct {
-		PollingIntervalSec int     `yaml:"pollingIntervalSec"`
-		CpuRedLine         float64 `yaml:"cpuRedLine"`
-		StepSec            int     `yaml:"stepSec"`
+		PollingIntervalSec      int     `yaml:"pollingIntervalSec"`
+		CpuRedLine              float64 `yaml:"cpuRedLine"`
+		StepSec                 int     `yaml:"stepSec"`
+		SevereBreachUtilization float64 `yaml:"severeBreachUtilization"`
 	} `yaml:"breachMonitor"`
 
 	PeriodicTrigger struct {
@@ -83,20 +92,96 @@ type Config struct {
 		MaxConcurrentReconciles int `yaml:"maxConcurrentReconciles"`
 	} `yaml:"policyRecommendationController"`
 
+	PolicyPromoter struct {
+		SoakPeriodHours int `yaml:"soakPeriodHours"`
+	} `yaml:"policyPromoter"`
+
 	PolicyRecommendationRegistrar struct {
 		RequeueDelayMs int `yaml:"requeueDelayMs"`
 	} `yaml:"policyRecommendationRegistrar"`
 
+	Recommender struct {
+		Default string `yaml:"default"`
+	} `yaml:"recommender"`
+
 	CpuUtilizationBasedRecommender struct {
 		MetricWindowInDays int `yaml:"metricWindowInDays"`
 		StepSec            int `yaml:"stepSec"`
 		MinTarget          int `yaml:"minTarget"`
-		MaxTarget          int `yaml:"minTarget"`
+		MaxTarget          int `yaml:"maxTarget"`
+		// MaxHeadroomFactor scales the max replicas seen in the metric window to leave room for traffic growth.
+		MaxHeadroomFactor float64 `yaml:"maxHeadroomFactor"`
+		BreachBudget      struct {
+			MaxBreachMinutesPerWeek     int  `yaml:"maxBreachMinutesPerWeek"`
+			MaxConsecutiveBreachMinutes int  `yaml:"maxConsecutiveBreachMinutes"`
+			MagnitudeWeighted           bool `yaml:"magnitudeWeighted"`
+		} `yaml:"breachBudget"`
+		// MinReplicas and MaxReplicas bound the replicas of the simulated HPA. Zero leaves them unbounded.
+		MinReplicas int `yaml:"minReplicas"`
+		MaxReplicas int `yaml:"maxReplicas"`
+		// HPABehavior is the behavior of the HPA modeled when simulating the workload. Leaving it unset models an
+		// HPA that scales instantly.
+		HPABehavior struct {
+			Tolerance float64                `yaml:"tolerance"`
+			ScaleUp   *HPAScalingRulesConfig `yaml:"scaleUp"`
+			ScaleDown *HPAScalingRulesConfig `yaml:"scaleDown"`
+		} `yaml:"hpaBehavior"`
+		// CapacityBasis is Requests or Limits, the CPU resources of a pod its capacity is measured against. Leaving it
+		// unset measures it against the requests, as the HPA does.
+		CapacityBasis string `yaml:"capacityBasis"`
+		// Forecast configures the forecast of the metric the HPA is simulated against. Leaving the mode empty
+		// simulates the history alone.
+		Forecast struct {
+			Mode                 string `yaml:"mode"`
+			HorizonDays          int    `yaml:"horizonDays"`
+			SeasonalPeriodsHours []int  `yaml:"seasonalPeriodsHours"`
+		} `yaml:"forecast"`
 	} `yaml:"cpuUtilizationBasedRecommender"`
+
+	// MemoryUtilizationBasedRecommender shares the breach budget, the HPA behavior, the replica bounds and the
+	// forecast of the CpuUtilizationBasedRecommender.
+	MemoryUtilizationBasedRecommender struct {
+		// MemoryRedLine is the utilization of the memory limits the workload is allowed to reach.
+		MemoryRedLine      float64 `yaml:"memoryRedLine"`
+		MetricWindowInDays int     `yaml:"metricWindowInDays"`
+		StepSec            int     `yaml:"stepSec"`
+		MinTarget          int     `yaml:"minTarget"`
+		MaxTarget          int     `yaml:"maxTarget"`
+		MaxHeadroomFactor  float64 `yaml:"maxHeadroomFactor"`
+	} `yaml:"memoryUtilizationBasedRecommender"`
+
+	// CustomMetricRecommender shares the breach budget, the HPA behavior, the replica bounds and the forecast of the
+	// CpuUtilizationBasedRecommender.
+	CustomMetricRecommender struct {
+		// RedLine is the utilization of the per pod capacity of the metric the workload is allowed to reach.
+		RedLine            float64 `yaml:"redLine"`
+		MetricWindowInDays int     `yaml:"metricWindowInDays"`
+		StepSec            int     `yaml:"stepSec"`
+		MinTarget          int     `yaml:"minTarget"`
+		MaxTarget          int     `yaml:"maxTarget"`
+		MaxHeadroomFactor  float64 `yaml:"maxHeadroomFactor"`
+	} `yaml:"customMetricRecommender"`
+
+	// MinReplicasScheduleRecommender shares the configuration of the CpuUtilizationBasedRecommender.
+	MinReplicasScheduleRecommender struct {
+		// BlockMinutes is the length of the blocks of the week the minReplicas are scheduled for. It should divide a
+		// week, and defaults to an hour.
+		BlockMinutes int `yaml:"blockMinutes"`
+	} `yaml:"minReplicasScheduleRecommender"`
 	MetricIngestionTime float64 `yaml:"metricIngestionTime"`
 	MetricProbeTime     float64 `yaml:"metricProbeTime"`
 }
 
+type HPAScalingRulesConfig struct {
+	StabilizationWindowSec int    `yaml:"stabilizationWindowSec"`
+	SelectPolicy           string `yaml:"selectPolicy"`
+	Policies               []struct {
+		Type      string `yaml:"type"`
+		Value     int    `yaml:"value"`
+		PeriodSec int    `yaml:"periodSec"`
+	} `yaml:"policies"`
+}
+
 func main() {
 
 	opts := zap.Options{
@@ -147,45 +232,137 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = controller.NewPolicyRecommendationReconciler(mgr.GetClient(),
-		mgr.GetScheme(),
-		config.PolicyRecommendationController.MaxConcurrentReconciles).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "PolicyRecommendation")
-		os.Exit(1)
-	}
-
 	scraper, err := metrics.NewPrometheusScraper(config.MetricsScraper.PrometheusUrl,
 		time.Duration(config.MetricsScraper.QueryTimeoutSec)*time.Second,
 		time.Duration(config.MetricsScraper.QuerySplitIntervalHr)*time.Hour,
+		time.Duration(config.MetricsScraper.MaxInterpolatedGapSec)*time.Second,
 		config.MetricIngestionTime,
 		config.MetricProbeTime,
+		config.MetricsScraper.ACLPercentile,
+		time.Duration(config.MetricsScraper.ACLWindowInDays)*24*time.Hour,
 	)
 	if err != nil {
 		setupLog.Error(err, "unable to start prometheus scraper")
 		os.Exit(1)
 	}
 
-	_ = reco.NewCpuUtilizationBasedRecommender(mgr.GetClient(),
+	breachBudget := reco.BreachBudget{
+		MaxBreachDurationPerWeek: time.Duration(
+			config.CpuUtilizationBasedRecommender.BreachBudget.MaxBreachMinutesPerWeek) * time.Minute,
+		MaxConsecutiveBreachDuration: time.Duration(
+			config.CpuUtilizationBasedRecommender.BreachBudget.MaxConsecutiveBreachMinutes) * time.Minute,
+		MagnitudeWeighted: config.CpuUtilizationBasedRecommender.BreachBudget.MagnitudeWeighted,
+	}
+	replicaBounds := reco.ReplicaBounds{
+		Min: config.CpuUtilizationBasedRecommender.MinReplicas,
+		Max: config.CpuUtilizationBasedRecommender.MaxReplicas,
+	}
+	forecast := forecastConfig(config)
+
+	cpuUtilizationBasedRecommender := reco.NewCpuUtilizationBasedRecommender(mgr.GetClient(),
 		config.BreachMonitor.CpuRedLine,
 		time.Duration(config.CpuUtilizationBasedRecommender.MetricWindowInDays)*24*time.Hour,
 		scraper,
 		time.Duration(config.CpuUtilizationBasedRecommender.StepSec)*time.Second,
 		config.CpuUtilizationBasedRecommender.MinTarget,
 		config.CpuUtilizationBasedRecommender.MaxTarget,
+		config.CpuUtilizationBasedRecommender.MaxHeadroomFactor,
+		breachBudget,
+		hpaBehavior(config),
+		replicaBounds,
+		forecast,
+		reco.CapacityBasis(config.CpuUtilizationBasedRecommender.CapacityBasis),
+		logger)
+
+	memoryUtilizationBasedRecommender := reco.NewMemoryUtilizationBasedRecommender(mgr.GetClient(),
+		config.MemoryUtilizationBasedRecommender.MemoryRedLine,
+		time.Duration(config.MemoryUtilizationBasedRecommender.MetricWindowInDays)*24*time.Hour,
+		scraper,
+		time.Duration(config.MemoryUtilizationBasedRecommender.StepSec)*time.Second,
+		config.MemoryUtilizationBasedRecommender.MinTarget,
+		config.MemoryUtilizationBasedRecommender.MaxTarget,
+		config.MemoryUtilizationBasedRecommender.MaxHeadroomFactor,
+		breachBudget,
+		hpaBehavior(config),
+		replicaBounds,
+		forecast,
+		logger)
+
+	customMetricRecommender := reco.NewCustomMetricRecommender(mgr.GetClient(),
+		config.CustomMetricRecommender.RedLine,
+		time.Duration(config.CustomMetricRecommender.MetricWindowInDays)*24*time.Hour,
+		scraper,
+		time.Duration(config.CustomMetricRecommender.StepSec)*time.Second,
+		config.CustomMetricRecommender.MinTarget,
+		config.CustomMetricRecommender.MaxTarget,
+		config.CustomMetricRecommender.MaxHeadroomFactor,
+		breachBudget,
+		hpaBehavior(config),
+		replicaBounds,
+		forecast,
+		logger)
+
+	defaultRecommender := config.Recommender.Default
+	if defaultRecommender == "" {
+		defaultRecommender = reco.CpuUtilizationBasedRecommenderName
+	}
+	recommenderRegistry := reco.NewRegistry(defaultRecommender)
+	recommenderRegistry.Register(reco.CpuUtilizationBasedRecommenderName, cpuUtilizationBasedRecommender)
+	recommenderRegistry.Register(reco.MemoryUtilizationBasedRecommenderName, memoryUtilizationBasedRecommender)
+	recommenderRegistry.Register(reco.MultiMetricRecommenderName, reco.NewMultiMetricRecommender(
+		mgr.GetClient(),
+		config.CpuUtilizationBasedRecommender.MaxHeadroomFactor,
+		hpaBehavior(config),
+		replicaBounds,
+		logger,
+		cpuUtilizationBasedRecommender,
+		memoryUtilizationBasedRecommender))
+	recommenderRegistry.Register(reco.CustomMetricRecommenderName, customMetricRecommender)
+	recommenderRegistry.Register(reco.MinReplicasScheduleRecommenderName, reco.NewMinReplicasScheduleRecommender(
+		cpuUtilizationBasedRecommender,
+		time.Duration(config.MinReplicasScheduleRecommender.BlockMinutes)*time.Minute))
+
+	policyStore := policy.NewPolicyStore(mgr.GetClient())
+	policyPromoter := policy.NewTimeBasedPromoter(policyStore,
+		scraper,
+		time.Duration(config.PolicyPromoter.SoakPeriodHours)*time.Hour,
+		config.BreachMonitor.CpuRedLine,
+		time.Duration(config.BreachMonitor.StepSec)*time.Second,
 		logger)
 
+	if err = controller.NewPolicyRecommendationReconciler(mgr.GetClient(),
+		mgr.GetScheme(),
+		config.PolicyRecommendationController.MaxConcurrentReconciles,
+		recommenderRegistry,
+		policyPromoter).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PolicyRecommendation")
+		os.Exit(1)
+	}
+
+	if err = controller.NewHPAActuator(mgr.GetClient(),
+		mgr.GetScheme()).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HPAActuator")
+		os.Exit(1)
+	}
+
 	triggerHandler := trigger.NewK8sTriggerHandler(mgr.GetClient(), logger)
 	triggerHandler.Start()
 
+	breachHandler := trigger.NewBreachHandler(mgr.GetClient(),
+		policyStore,
+		config.BreachMonitor.SevereBreachUtilization,
+		time.Duration(config.BreachMonitor.PollingIntervalSec)*time.Second,
+		logger)
+
 	monitorManager := trigger.NewPolicyRecommendationMonitorManager(scraper,
 		time.Duration(config.PeriodicTrigger.PollingIntervalMin)*time.Minute,
 		time.Duration(config.BreachMonitor.PollingIntervalSec)*time.Second,
 		triggerHandler.QueueForExecution,
+		breachHandler.HandleBreach,
 		config.BreachMonitor.StepSec,
 		config.BreachMonitor.CpuRedLine,
 		logger)
 
-	policyStore := policy.NewPolicyStore(mgr.GetClient())
 	if err = controller.NewPolicyRecommendationRegistrar(mgr.GetClient(),
 		mgr.GetScheme(),
 		config.PolicyRecommendationRegistrar.RequeueDelayMs,
@@ -202,6 +379,12 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Policy")
 		os.Exit(1)
 	}
+
+	if err = controller.NewNamespaceCostReporter(mgr.GetClient(),
+		mgr.GetScheme()).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "NamespaceCostReporter")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -228,3 +411,48 @@ func main() {
 		os.Exit(0)
 	}()
 }
+
+func hpaBehavior(config Config) reco.HPABehavior {
+	behaviorConfig := config.CpuUtilizationBasedRecommender.HPABehavior
+	behavior := reco.HPABehavior{Tolerance: behaviorConfig.Tolerance}
+	if behaviorConfig.ScaleUp == nil && behaviorConfig.ScaleDown == nil {
+		return behavior
+	}
+	behavior.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{
+		ScaleUp:   hpaScalingRules(behaviorConfig.ScaleUp),
+		ScaleDown: hpaScalingRules(behaviorConfig.ScaleDown),
+	}
+	return behavior
+}
+
+func forecastConfig(config Config) reco.ForecastConfig {
+	forecastConfig := config.CpuUtilizationBasedRecommender.Forecast
+	forecast := reco.ForecastConfig{
+		Mode:    reco.ForecastMode(forecastConfig.Mode),
+		Horizon: time.Duration(forecastConfig.HorizonDays) * 24 * time.Hour,
+	}
+	for _, hours := range forecastConfig.SeasonalPeriodsHours {
+		forecast.SeasonalPeriods = append(forecast.SeasonalPeriods, time.Duration(hours)*time.Hour)
+	}
+	return forecast
+}
+
+func hpaScalingRules(rulesConfig *HPAScalingRulesConfig) *autoscalingv2.HPAScalingRules {
+	if rulesConfig == nil {
+		return nil
+	}
+	stabilizationWindowSeconds := int32(rulesConfig.StabilizationWindowSec)
+	rules := &autoscalingv2.HPAScalingRules{StabilizationWindowSeconds: &stabilizationWindowSeconds}
+	if rulesConfig.SelectPolicy != "" {
+		selectPolicy := autoscalingv2.ScalingPolicySelect(rulesConfig.SelectPolicy)
+		rules.SelectPolicy = &selectPolicy
+	}
+	for _, policy := range rulesConfig.Policies {
+		rules.Policies = append(rules.Policies, autoscalingv2.HPAScalingPolicy{
+			Type:          autoscalingv2.HPAScalingPolicyType(policy.Type),
+			Value:         int32(policy.Value),
+			PeriodSeconds: int32(policy.PeriodSec),
+		})
+	}
+	return rules
+}
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	ottoscaleriov1alpha1 "github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/reco"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// ExplanationKey is the key of the ConfigMap data holding the explanation of a recommendation, as JSON.
+	ExplanationKey = "explanation.json"
+
+	explanationFieldOwner = "ottoscalr-policyrecommendation-controller"
+)
+
+// explanationConfigMapName returns the name of the ConfigMap that explains the recommendations of the
+// PolicyRecommendation.
+func explanationConfigMapName(policyRecommendation *ottoscaleriov1alpha1.PolicyRecommendation) string {
+	return policyRecommendation.Name + "-explanation"
+}
+
+// storeExplanation records the explanation in a ConfigMap owned by the PolicyRecommendation, so that it is garbage
+// collected along with it, and returns the name of the ConfigMap. The ConfigMap is server side applied rather than
+// read first, so that the controller doesn't cache every ConfigMap of the cluster.
+func (r *PolicyRecommendationReconciler) storeExplanation(ctx context.Context,
+	policyRecommendation *ottoscaleriov1alpha1.PolicyRecommendation,
+	explanation *reco.Explanation) (string, error) {
+
+	data, err := json.Marshal(explanation)
+	if err != nil {
+		return "", err
+	}
+
+	configMap := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      explanationConfigMapName(policyRecommendation),
+			Namespace: policyRecommendation.Namespace,
+		},
+		Data: map[string]string{ExplanationKey: string(data)},
+	}
+	if err := controllerutil.SetOwnerReference(policyRecommendation, configMap, r.Scheme); err != nil {
+		return "", err
+	}
+	if err := r.Client.Patch(ctx, configMap, client.Apply, client.FieldOwner(explanationFieldOwner),
+		client.ForceOwnership); err != nil {
+		return "", err
+	}
+	return configMap.Name, nil
+}
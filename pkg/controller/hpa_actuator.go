@@ -0,0 +1,266 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	ottoscaleriov1alpha1 "github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/go-logr/logr"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "ottoscalr"
+)
+
+// HPAActuator materializes the TargetHPAConfiguration of a PolicyRecommendation as an autoscaling/v2
+// HorizontalPodAutoscaler for the workload. The HPA is owned by the PolicyRecommendation, so it is garbage collected
+// along with it when the recommendation goes away. An HPA that already targets the workload and isn't controlled by
+// anyone else is adopted instead of creating a new one. For a workload in shadow mode nothing is applied; the HPA spec
+// that would have been applied is recorded in the status of the PolicyRecommendation instead.
+type HPAActuator struct {
+	Client client.Client
+	Scheme *runtime.Scheme
+}
+
+func NewHPAActuator(client client.Client,
+	scheme *runtime.Scheme) *HPAActuator {
+	return &HPAActuator{
+		Client: client,
+		Scheme: scheme,
+	}
+}
+
+//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ottoscaler.io,resources=policyrecommendations,verbs=get;list;watch
+//+kubebuilder:rbac:groups=ottoscaler.io,resources=policyrecommendations/status,verbs=get;update;patch
+
+func (r *HPAActuator) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger = logger.WithValues("request", req)
+
+	policyRecommendation := ottoscaleriov1alpha1.PolicyRecommendation{}
+	if err := r.Client.Get(ctx, req.NamespacedName, &policyRecommendation); err != nil {
+		if errors.IsNotFound(err) {
+			// The HPA is owned by the PolicyRecommendation and is garbage collected along with it.
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get PolicyRecommendation. Requeue the request")
+		return ctrl.Result{}, err
+	}
+
+	if !policyRecommendation.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	if policyRecommendation.Spec.TargetHPAConfiguration.Max == 0 {
+		logger.Info("No target HPA configuration has been generated yet. Skipping.")
+		return ctrl.Result{}, nil
+	}
+
+	hpa, err := r.getHPAForWorkload(ctx, &policyRecommendation, logger)
+	if err != nil {
+		logger.Error(err, "Error while getting the HPA for the workload. Requeue the request")
+		return ctrl.Result{}, err
+	}
+
+	if policyRecommendation.Spec.ShadowMode {
+		return ctrl.Result{}, r.recordShadowHPASpec(ctx, &policyRecommendation, hpa, logger)
+	}
+
+	if policyRecommendation.Status.ShadowHPASpec != nil {
+		// The workload has been moved out of shadow mode.
+		policyRecommendation.Status.ShadowHPASpec = nil
+		if err := r.Client.Status().Update(ctx, &policyRecommendation); err != nil {
+			logger.Error(err, "Error while clearing the shadow HPA spec. Requeue the request")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if hpa == nil {
+		return ctrl.Result{}, nil
+	}
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, hpa, func() error {
+		if hpa.Labels == nil {
+			hpa.Labels = map[string]string{}
+		}
+		hpa.Labels[managedByLabel] = managedByValue
+		hpa.Spec = desiredHPASpec(policyRecommendation.Spec.WorkloadSpec,
+			policyRecommendation.Spec.TargetHPAConfiguration,
+			hpa.Spec.Behavior)
+		return controllerutil.SetControllerReference(&policyRecommendation, hpa, r.Scheme)
+	})
+	if err != nil {
+		logger.Error(err, "Error while applying the HPA. Requeue the request")
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("HPA reconciled", "hpa", hpa.Name, "operation", result)
+	return ctrl.Result{}, nil
+}
+
+// recordShadowHPASpec records the HPA spec that would have been applied for a workload in shadow mode. The HPA
+// itself, if any, is left untouched.
+func (r *HPAActuator) recordShadowHPASpec(ctx context.Context,
+	policyRecommendation *ottoscaleriov1alpha1.PolicyRecommendation,
+	hpa *autoscalingv2.HorizontalPodAutoscaler,
+	logger logr.Logger) error {
+
+	var behavior *autoscalingv2.HorizontalPodAutoscalerBehavior
+	if hpa != nil {
+		behavior = hpa.Spec.Behavior
+	}
+	shadowHPASpec := desiredHPASpec(policyRecommendation.Spec.WorkloadSpec,
+		policyRecommendation.Spec.TargetHPAConfiguration,
+		behavior)
+	if equality.Semantic.DeepEqual(policyRecommendation.Status.ShadowHPASpec, &shadowHPASpec) {
+		return nil
+	}
+
+	policyRecommendation.Status.ShadowHPASpec = &shadowHPASpec
+	if err := r.Client.Status().Update(ctx, policyRecommendation); err != nil {
+		logger.Error(err, "Error while recording the shadow HPA spec. Requeue the request")
+		return err
+	}
+	logger.Info("Workload is in shadow mode. Recorded the HPA spec without applying it.")
+	return nil
+}
+
+// getHPAForWorkload returns the HPA to be applied for the workload. It prefers an HPA that already targets the
+// workload, and returns nil if that HPA is controlled by another owner.
+func (r *HPAActuator) getHPAForWorkload(ctx context.Context,
+	policyRecommendation *ottoscaleriov1alpha1.PolicyRecommendation,
+	logger logr.Logger) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+
+	hpaList := autoscalingv2.HorizontalPodAutoscalerList{}
+	if err := r.Client.List(ctx, &hpaList, client.InNamespace(policyRecommendation.Namespace)); err != nil {
+		return nil, err
+	}
+
+	workloadSpec := policyRecommendation.Spec.WorkloadSpec
+	for i := range hpaList.Items {
+		hpa := &hpaList.Items[i]
+		if hpa.Spec.ScaleTargetRef.Kind != workloadSpec.Kind || hpa.Spec.ScaleTargetRef.Name != workloadSpec.Name {
+			continue
+		}
+		if owner := metav1.GetControllerOf(hpa); owner != nil && owner.UID != policyRecommendation.UID {
+			logger.Info("HPA for the workload is controlled by another owner. Skipping.", "hpa", hpa.Name,
+				"owner", fmt.Sprintf("%s/%s", owner.Kind, owner.Name))
+			return nil, nil
+		}
+		return hpa, nil
+	}
+
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      policyRecommendation.Name,
+			Namespace: policyRecommendation.Namespace,
+		},
+	}, nil
+}
+
+// desiredHPASpec builds the HPA spec for the workload from the HPAConfiguration. The scaling behavior of an existing
+// HPA is retained.
+func desiredHPASpec(workloadSpec ottoscaleriov1alpha1.WorkloadSpec,
+	hpaConfiguration ottoscaleriov1alpha1.HPAConfiguration,
+	behavior *autoscalingv2.HorizontalPodAutoscalerBehavior) autoscalingv2.HorizontalPodAutoscalerSpec {
+
+	// HPA requires at least one replica, and max replicas to be no less than min replicas.
+	minReplicas := int32(hpaConfiguration.Min)
+	if minReplicas < 1 {
+		minReplicas = 1
+	}
+	maxReplicas := int32(hpaConfiguration.Max)
+	if maxReplicas < minReplicas {
+		maxReplicas = minReplicas
+	}
+
+	return autoscalingv2.HorizontalPodAutoscalerSpec{
+		ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+			Kind:       workloadSpec.Kind,
+			Name:       workloadSpec.Name,
+			APIVersion: workloadSpec.APIVersion,
+		},
+		MinReplicas: &minReplicas,
+		MaxReplicas: maxReplicas,
+		Metrics:     desiredMetricSpecs(hpaConfiguration),
+		Behavior:    behavior,
+	}
+}
+
+// desiredMetricSpecs builds the metrics of the HPA from the metric targets of the HPAConfiguration, or from its
+// TargetMetricValue for an HPA scaling on the cpu utilization alone.
+func desiredMetricSpecs(hpaConfiguration ottoscaleriov1alpha1.HPAConfiguration) []autoscalingv2.MetricSpec {
+	if len(hpaConfiguration.Metrics) == 0 {
+		return []autoscalingv2.MetricSpec{resourceMetricSpec(corev1.ResourceCPU, hpaConfiguration.TargetMetricValue)}
+	}
+
+	metricSpecs := make([]autoscalingv2.MetricSpec, 0, len(hpaConfiguration.Metrics))
+	for _, metricTarget := range hpaConfiguration.Metrics {
+		switch metricTarget.Type {
+		case autoscalingv2.PodsMetricSourceType:
+			metricSpecs = append(metricSpecs, autoscalingv2.MetricSpec{
+				Type: autoscalingv2.PodsMetricSourceType,
+				Pods: &autoscalingv2.PodsMetricSource{
+					Metric: autoscalingv2.MetricIdentifier{Name: metricTarget.Name},
+					Target: averageValueMetricTarget(metricTarget),
+				},
+			})
+		case autoscalingv2.ExternalMetricSourceType:
+			metricSpecs = append(metricSpecs, autoscalingv2.MetricSpec{
+				Type: autoscalingv2.ExternalMetricSourceType,
+				External: &autoscalingv2.ExternalMetricSource{
+					Metric: autoscalingv2.MetricIdentifier{Name: metricTarget.Name},
+					Target: averageValueMetricTarget(metricTarget),
+				},
+			})
+		default:
+			metricSpecs = append(metricSpecs, resourceMetricSpec(corev1.ResourceName(metricTarget.Name),
+				metricTarget.TargetValue))
+		}
+	}
+	return metricSpecs
+}
+
+// averageValueMetricTarget targets the value of a Pods or External metric averaged over the pods of the workload.
+func averageValueMetricTarget(metricTarget ottoscaleriov1alpha1.MetricTarget) autoscalingv2.MetricTarget {
+	target := autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType}
+	if metricTarget.AverageValue != nil {
+		averageValue := metricTarget.AverageValue.DeepCopy()
+		target.AverageValue = &averageValue
+	}
+	return target
+}
+
+func resourceMetricSpec(resourceName corev1.ResourceName, targetUtilization int) autoscalingv2.MetricSpec {
+	averageUtilization := int32(targetUtilization)
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name: resourceName,
+			Target: autoscalingv2.MetricTarget{
+				Type:               autoscalingv2.UtilizationMetricType,
+				AverageUtilization: &averageUtilization,
+			},
+		},
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HPAActuator) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("HPAActuator").
+		For(&ottoscaleriov1alpha1.PolicyRecommendation{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Complete(r)
+}
@@ -0,0 +1,216 @@
+package controller
+
+import (
+	ottoscaleriov1alpha1 "github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"golang.org/x/net/context"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HPAActuator controller", func() {
+
+	const (
+		Namespace = "default"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	newPolicyRecommendation := func(name, kind, apiVersion string) *ottoscaleriov1alpha1.PolicyRecommendation {
+		return &ottoscaleriov1alpha1.PolicyRecommendation{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: Namespace,
+			},
+			Spec: ottoscaleriov1alpha1.PolicyRecommendationSpec{
+				WorkloadSpec: ottoscaleriov1alpha1.WorkloadSpec{
+					Name:      name,
+					Namespace: Namespace,
+					TypeMeta:  metav1.TypeMeta{Kind: kind, APIVersion: apiVersion},
+				},
+				TargetHPAConfiguration: ottoscaleriov1alpha1.HPAConfiguration{Min: 3, Max: 12, TargetMetricValue: 55},
+			},
+		}
+	}
+
+	Context("When a PolicyRecommendation has a target HPA configuration", func() {
+		It("Should create an HPA for a Deployment owned by the PolicyRecommendation", func() {
+			ctx := context.TODO()
+			policyRecommendation := newPolicyRecommendation("test-hpa-deployment", "Deployment", "apps/v1")
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: "test-hpa-deployment", Namespace: Namespace}, hpa)
+			}, timeout, interval).Should(Succeed())
+
+			Expect(hpa.Spec.ScaleTargetRef).Should(Equal(autoscalingv2.CrossVersionObjectReference{
+				Kind: "Deployment", Name: "test-hpa-deployment", APIVersion: "apps/v1"}))
+			Expect(*hpa.Spec.MinReplicas).Should(Equal(int32(3)))
+			Expect(hpa.Spec.MaxReplicas).Should(Equal(int32(12)))
+			Expect(hpa.Spec.Metrics).Should(HaveLen(1))
+			Expect(hpa.Spec.Metrics[0].Resource.Name).Should(Equal(corev1.ResourceCPU))
+			Expect(*hpa.Spec.Metrics[0].Resource.Target.AverageUtilization).Should(Equal(int32(55)))
+			Expect(hpa.Labels[managedByLabel]).Should(Equal(managedByValue))
+			Expect(metav1.IsControlledBy(hpa, policyRecommendation)).Should(BeTrue())
+
+			By("Updating the target HPA configuration")
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-hpa-deployment", Namespace: Namespace},
+				policyRecommendation)).Should(Succeed())
+			policyRecommendation.Spec.TargetHPAConfiguration.Max = 20
+			Expect(k8sClient.Update(ctx, policyRecommendation)).Should(Succeed())
+
+			Eventually(func() int32 {
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: "test-hpa-deployment", Namespace: Namespace},
+					hpa); err != nil {
+					return 0
+				}
+				return hpa.Spec.MaxReplicas
+			}, timeout, interval).Should(Equal(int32(20)))
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+
+		It("Should point the HPA at an Argo Rollout", func() {
+			ctx := context.TODO()
+			policyRecommendation := newPolicyRecommendation("test-hpa-rollout", "Rollout", "argoproj.io/v1alpha1")
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: "test-hpa-rollout", Namespace: Namespace}, hpa)
+			}, timeout, interval).Should(Succeed())
+
+			Expect(hpa.Spec.ScaleTargetRef).Should(Equal(autoscalingv2.CrossVersionObjectReference{
+				Kind: "Rollout", Name: "test-hpa-rollout", APIVersion: "argoproj.io/v1alpha1"}))
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+
+		It("Should scale the HPA on every metric of a multi metric configuration", func() {
+			ctx := context.TODO()
+			policyRecommendation := newPolicyRecommendation("test-hpa-multi-metric", "Deployment", "apps/v1")
+			policyRecommendation.Spec.TargetHPAConfiguration.Metrics = []ottoscaleriov1alpha1.MetricTarget{
+				{Name: "cpu", TargetValue: 55},
+				{Name: "memory", TargetValue: 120},
+			}
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: "test-hpa-multi-metric", Namespace: Namespace},
+					hpa)
+			}, timeout, interval).Should(Succeed())
+
+			targets := map[corev1.ResourceName]int32{}
+			for _, metric := range hpa.Spec.Metrics {
+				targets[metric.Resource.Name] = *metric.Resource.Target.AverageUtilization
+			}
+			Expect(targets).Should(Equal(map[corev1.ResourceName]int32{
+				corev1.ResourceCPU:    55,
+				corev1.ResourceMemory: 120,
+			}))
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+
+		It("Should scale the HPA on the memory utilization of a memory based configuration", func() {
+			ctx := context.TODO()
+			policyRecommendation := newPolicyRecommendation("test-hpa-memory", "Deployment", "apps/v1")
+			// The configuration as the MemoryUtilizationBasedRecommender generates it.
+			policyRecommendation.Spec.TargetHPAConfiguration = ottoscaleriov1alpha1.HPAConfiguration{
+				Min: 3,
+				Max: 12,
+				Metrics: []ottoscaleriov1alpha1.MetricTarget{
+					{Name: "memory", Type: autoscalingv2.ResourceMetricSourceType, TargetValue: 104},
+				},
+			}
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: "test-hpa-memory", Namespace: Namespace}, hpa)
+			}, timeout, interval).Should(Succeed())
+
+			Expect(hpa.Spec.Metrics).Should(HaveLen(1))
+			Expect(hpa.Spec.Metrics[0].Type).Should(Equal(autoscalingv2.ResourceMetricSourceType))
+			Expect(hpa.Spec.Metrics[0].Resource.Name).Should(Equal(corev1.ResourceMemory))
+			Expect(*hpa.Spec.Metrics[0].Resource.Target.AverageUtilization).Should(Equal(int32(104)))
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+
+		It("Should scale the HPA on the average value of a custom metric", func() {
+			ctx := context.TODO()
+			policyRecommendation := newPolicyRecommendation("test-hpa-custom-metric", "Deployment", "apps/v1")
+			averageValue := resource.MustParse("150")
+			policyRecommendation.Spec.TargetHPAConfiguration.Metrics = []ottoscaleriov1alpha1.MetricTarget{
+				{Name: "http_requests_per_second", Type: autoscalingv2.PodsMetricSourceType, TargetValue: 50,
+					AverageValue: &averageValue},
+			}
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: "test-hpa-custom-metric", Namespace: Namespace},
+					hpa)
+			}, timeout, interval).Should(Succeed())
+
+			Expect(hpa.Spec.Metrics).Should(HaveLen(1))
+			Expect(hpa.Spec.Metrics[0].Type).Should(Equal(autoscalingv2.PodsMetricSourceType))
+			Expect(hpa.Spec.Metrics[0].Pods.Metric.Name).Should(Equal("http_requests_per_second"))
+			Expect(hpa.Spec.Metrics[0].Pods.Target.Type).Should(Equal(autoscalingv2.AverageValueMetricType))
+			Expect(hpa.Spec.Metrics[0].Pods.Target.AverageValue.Cmp(averageValue)).Should(Equal(0))
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+
+		It("Should adopt an existing HPA that targets the workload", func() {
+			ctx := context.TODO()
+			minReplicas := int32(1)
+			existingHPA := &autoscalingv2.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "existing-hpa",
+					Namespace: Namespace,
+				},
+				Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+						Kind: "Deployment", Name: "test-hpa-adopted", APIVersion: "apps/v1"},
+					MinReplicas: &minReplicas,
+					MaxReplicas: 5,
+				},
+			}
+			Expect(k8sClient.Create(ctx, existingHPA)).Should(Succeed())
+
+			policyRecommendation := newPolicyRecommendation("test-hpa-adopted", "Deployment", "apps/v1")
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			Eventually(func() bool {
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: "existing-hpa", Namespace: Namespace},
+					hpa); err != nil {
+					return false
+				}
+				return metav1.IsControlledBy(hpa, policyRecommendation)
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(*hpa.Spec.MinReplicas).Should(Equal(int32(3)))
+			Expect(hpa.Spec.MaxReplicas).Should(Equal(int32(12)))
+
+			By("Not creating another HPA for the workload")
+			Consistently(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: "test-hpa-adopted", Namespace: Namespace},
+					&autoscalingv2.HorizontalPodAutoscaler{})
+			}, time.Second, interval).ShouldNot(Succeed())
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+	})
+})
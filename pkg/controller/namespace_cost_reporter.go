@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"context"
+	ottoscaleriov1alpha1 "github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var (
+	namespaceEstimatedWorkloads = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ottoscalr_namespace_estimated_workloads",
+		Help: "Number of workloads of the namespace with a cost estimate.",
+	}, []string{"namespace"})
+	namespaceCurrentCoreHours = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ottoscalr_namespace_current_core_hours",
+		Help: "Cpu core hours requested by the workloads of the namespace under their current scaling.",
+	}, []string{"namespace"})
+	namespaceRecommendedCoreHours = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ottoscalr_namespace_recommended_core_hours",
+		Help: "Cpu core hours requested by the workloads of the namespace under the recommended HPAs.",
+	}, []string{"namespace"})
+	namespaceSavedCoreHours = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ottoscalr_namespace_saved_core_hours",
+		Help: "Cpu core hours the recommended HPAs save the workloads of the namespace.",
+	}, []string{"namespace"})
+	namespaceProjectedMonthlySavedCoreHours = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ottoscalr_namespace_projected_monthly_saved_core_hours",
+		Help: "Cpu core hours the recommended HPAs save the workloads of the namespace, projected over 30 days.",
+	}, []string{"namespace"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(namespaceEstimatedWorkloads,
+		namespaceCurrentCoreHours,
+		namespaceRecommendedCoreHours,
+		namespaceSavedCoreHours,
+		namespaceProjectedMonthlySavedCoreHours)
+}
+
+// NamespaceCost is the sum of the CostEstimates of the PolicyRecommendations of a namespace.
+type NamespaceCost struct {
+	Workloads                      int
+	CurrentCoreHours               float64
+	RecommendedCoreHours           float64
+	SavedCoreHours                 float64
+	ProjectedMonthlySavedCoreHours float64
+}
+
+// NamespaceCostReporter aggregates the CostEstimates of the PolicyRecommendations of every namespace, and reports them
+// as the ottoscalr_namespace metrics served by the manager. It is reconciled by the name of the namespace.
+type NamespaceCostReporter struct {
+	Client client.Client
+	Scheme *runtime.Scheme
+}
+
+func NewNamespaceCostReporter(client client.Client,
+	scheme *runtime.Scheme) *NamespaceCostReporter {
+	return &NamespaceCostReporter{
+		Client: client,
+		Scheme: scheme,
+	}
+}
+
+//+kubebuilder:rbac:groups=ottoscaler.io,resources=policyrecommendations,verbs=get;list;watch
+
+func (r *NamespaceCostReporter) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger = logger.WithValues("request", req)
+
+	policyRecommendations := ottoscaleriov1alpha1.PolicyRecommendationList{}
+	if err := r.Client.List(ctx, &policyRecommendations, client.InNamespace(req.Name)); err != nil {
+		logger.Error(err, "Error while listing the PolicyRecommendations of the namespace. Requeue the request")
+		return ctrl.Result{}, err
+	}
+
+	cost := aggregateNamespaceCost(policyRecommendations.Items)
+	if cost.Workloads == 0 {
+		namespaceEstimatedWorkloads.DeleteLabelValues(req.Name)
+		namespaceCurrentCoreHours.DeleteLabelValues(req.Name)
+		namespaceRecommendedCoreHours.DeleteLabelValues(req.Name)
+		namespaceSavedCoreHours.DeleteLabelValues(req.Name)
+		namespaceProjectedMonthlySavedCoreHours.DeleteLabelValues(req.Name)
+		return ctrl.Result{}, nil
+	}
+	namespaceEstimatedWorkloads.WithLabelValues(req.Name).Set(float64(cost.Workloads))
+	namespaceCurrentCoreHours.WithLabelValues(req.Name).Set(cost.CurrentCoreHours)
+	namespaceRecommendedCoreHours.WithLabelValues(req.Name).Set(cost.RecommendedCoreHours)
+	namespaceSavedCoreHours.WithLabelValues(req.Name).Set(cost.SavedCoreHours)
+	namespaceProjectedMonthlySavedCoreHours.WithLabelValues(req.Name).Set(cost.ProjectedMonthlySavedCoreHours)
+	return ctrl.Result{}, nil
+}
+
+// aggregateNamespaceCost sums the CostEstimates of the PolicyRecommendations that have one.
+func aggregateNamespaceCost(policyRecommendations []ottoscaleriov1alpha1.PolicyRecommendation) NamespaceCost {
+	cost := NamespaceCost{}
+	for _, policyRecommendation := range policyRecommendations {
+		estimate := policyRecommendation.Status.Cost
+		if estimate == nil || !policyRecommendation.DeletionTimestamp.IsZero() {
+			continue
+		}
+		cost.Workloads++
+		cost.CurrentCoreHours += estimate.CurrentCoreHours.AsApproximateFloat64()
+		cost.RecommendedCoreHours += estimate.RecommendedCoreHours.AsApproximateFloat64()
+		cost.SavedCoreHours += estimate.SavedCoreHours.AsApproximateFloat64()
+		cost.ProjectedMonthlySavedCoreHours += estimate.ProjectedMonthlySavedCoreHours.AsApproximateFloat64()
+	}
+	return cost
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NamespaceCostReporter) SetupWithManager(mgr ctrl.Manager) error {
+	enqueueNamespaceFunc := func(obj client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: obj.GetNamespace()}}}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("NamespaceCostReporter").
+		Watches(
+			&source.Kind{Type: &ottoscaleriov1alpha1.PolicyRecommendation{}},
+			handler.EnqueueRequestsFromMapFunc(enqueueNamespaceFunc),
+		).
+		Complete(r)
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	ottoscaleriov1alpha1 "github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/net/context"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NamespaceCostReporter", func() {
+	const namespace = "test-cost-ns"
+
+	newCostEstimate := func(current, recommended string) *ottoscaleriov1alpha1.CostEstimate {
+		currentCoreHours := resource.MustParse(current)
+		recommendedCoreHours := resource.MustParse(recommended)
+		saved := currentCoreHours.DeepCopy()
+		saved.Sub(recommendedCoreHours)
+		return &ottoscaleriov1alpha1.CostEstimate{
+			CurrentScaling:                 ottoscaleriov1alpha1.StaticReplicas,
+			CurrentCoreHours:               currentCoreHours,
+			RecommendedCoreHours:           recommendedCoreHours,
+			SavedCoreHours:                 saved,
+			ProjectedMonthlySavedCoreHours: saved,
+		}
+	}
+
+	It("should sum the cost estimates of the PolicyRecommendations", func() {
+		deleting := metav1.Now()
+		cost := aggregateNamespaceCost([]ottoscaleriov1alpha1.PolicyRecommendation{
+			{Status: ottoscaleriov1alpha1.PolicyRecommendationStatus{Cost: newCostEstimate("100", "40")}},
+			{Status: ottoscaleriov1alpha1.PolicyRecommendationStatus{Cost: newCostEstimate("10", "12.5")}},
+			{},
+			{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleting},
+				Status:     ottoscaleriov1alpha1.PolicyRecommendationStatus{Cost: newCostEstimate("50", "0")},
+			},
+		})
+		Expect(cost).To(Equal(NamespaceCost{
+			Workloads:                      2,
+			CurrentCoreHours:               110,
+			RecommendedCoreHours:           52.5,
+			SavedCoreHours:                 57.5,
+			ProjectedMonthlySavedCoreHours: 57.5,
+		}))
+	})
+
+	It("should report the cost of the namespace and stop once no workload has one", func() {
+		ctx := context.TODO()
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).
+			Should(Succeed())
+
+		policyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cost-reco", Namespace: namespace},
+			Spec: ottoscaleriov1alpha1.PolicyRecommendationSpec{
+				WorkloadSpec: ottoscaleriov1alpha1.WorkloadSpec{
+					Name:      "test-cost-reco",
+					Namespace: namespace,
+					TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+		policyRecommendation.Status.Cost = newCostEstimate("24", "6")
+		Expect(k8sClient.Status().Update(ctx, policyRecommendation)).Should(Succeed())
+
+		reporter := NewNamespaceCostReporter(k8sClient, k8sClient.Scheme())
+		request := ctrl.Request{NamespacedName: types.NamespacedName{Name: namespace}}
+		_, err := reporter.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(testutil.ToFloat64(namespaceEstimatedWorkloads.WithLabelValues(namespace))).Should(Equal(1.0))
+		Expect(testutil.ToFloat64(namespaceCurrentCoreHours.WithLabelValues(namespace))).Should(Equal(24.0))
+		Expect(testutil.ToFloat64(namespaceRecommendedCoreHours.WithLabelValues(namespace))).Should(Equal(6.0))
+		Expect(testutil.ToFloat64(namespaceSavedCoreHours.WithLabelValues(namespace))).Should(Equal(18.0))
+
+		Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		_, err = reporter.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(testutil.CollectAndCount(namespaceCurrentCoreHours)).Should(BeZero())
+	})
+})
@@ -18,7 +18,18 @@ package controller
 
 import (
 	"context"
+	"github.com/flipkart-incubator/ottoscalr/pkg/policy"
+	"github.com/flipkart-incubator/ottoscalr/pkg/reco"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -28,48 +39,312 @@ import (
 	ottoscaleriov1alpha1 "github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
 )
 
+// maxRecommendationHistory is the number of generated HPAConfigurations retained in the status.
+const maxRecommendationHistory = 10
+
+// insufficientDataRequeueDelay is the delay a workload with too little metrics to recommend on is retried after. The
+// metrics only come in with time, so retrying with backoff doesn't help.
+const insufficientDataRequeueDelay = 15 * time.Minute
+
+// RecommenderAnnotation selects the Recommender for a Deployment/Rollout by the name it is registered with. It takes
+// precedence over the Recommender set on the Policy assigned to the workload.
+const RecommenderAnnotation = "ottoscalr.io/recommender"
+
 // PolicyRecommendationReconciler reconciles a PolicyRecommendation object
 type PolicyRecommendationReconciler struct {
 	Client                  client.Client
 	Scheme                  *runtime.Scheme
 	MaxConcurrentReconciles int
+	RecommenderRegistry     *reco.Registry
+	PolicyPromoter          policy.Promoter
 }
 
 func NewPolicyRecommendationReconciler(client client.Client,
 	scheme *runtime.Scheme,
-	maxConcurrentReconciles int) *PolicyRecommendationReconciler {
+	maxConcurrentReconciles int,
+	recommenderRegistry *reco.Registry,
+	policyPromoter policy.Promoter) *PolicyRecommendationReconciler {
 	return &PolicyRecommendationReconciler{
 		Client:                  client,
 		Scheme:                  scheme,
 		MaxConcurrentReconciles: maxConcurrentReconciles,
+		RecommenderRegistry:     recommenderRegistry,
+		PolicyPromoter:          policyPromoter,
 	}
 }
 
 //+kubebuilder:rbac:groups=ottoscaler.io,resources=policyrecommendations,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=ottoscaler.io,resources=policyrecommendations/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=ottoscaler.io,resources=policyrecommendations/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=resourcequotas,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=ottoscaler.io,resources=metricexclusions,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;create;patch
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the PolicyRecommendation object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
+// Reconcile picks up the PolicyRecommendations that have been queued for execution by the trigger handler,
+// runs the Recommender chosen for the workload, promotes the workload up the policy ladder when it is eligible and
+// records the generated HPAConfiguration after bounding it with the Policy assigned to the workload. The shadow mode
+// of the workload is resolved on every run.
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.1/pkg/reconcile
 func (r *PolicyRecommendationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	logger := log.FromContext(ctx)
+	logger = logger.WithValues("request", req)
+
+	policyRecommendation := ottoscaleriov1alpha1.PolicyRecommendation{}
+	if err := r.Client.Get(ctx, req.NamespacedName, &policyRecommendation); err != nil {
+		if errors.IsNotFound(err) {
+			// Ignore not found errors, as the object might have been deleted
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get PolicyRecommendation. Requeue the request")
+		return ctrl.Result{}, err
+	}
+
+	if !policyRecommendation.Spec.QueuedForExecution {
+		return ctrl.Result{}, nil
+	}
+
+	// The condition is patched into the status, which leaves the generation as is, so that the request isn't queued
+	// again while the Recommender runs.
+	inProgressPatch := client.MergeFrom(policyRecommendation.DeepCopy())
+	policyRecommendation.Status.SetCondition(ottoscaleriov1alpha1.RecommendationInProgress, metav1.ConditionTrue,
+		ottoscaleriov1alpha1.ReasonRunning, "Recommender is running for the workload")
+	if err := r.Client.Status().Patch(ctx, &policyRecommendation, inProgressPatch); err != nil {
+		logger.Error(err, "Error while updating PolicyRecommendation status. Requeue the request")
+		return ctrl.Result{}, err
+	}
+
+	workload, err := reco.GetWorkload(r.Client, policyRecommendation.Spec.WorkloadSpec)
+	if err != nil {
+		if reco.IsRetryable(err) {
+			logger.Error(err, "Error while getting the workload. Requeue the request")
+			return ctrl.Result{}, err
+		}
+		// A workload that is gone or of an unsupported kind has no annotations, and is left for the Recommender to
+		// report.
+		workload = &appsv1.Deployment{}
+	}
+
+	recommenderName, recommender, err := r.RecommenderRegistry.Get(recommenderNameForWorkload(workload,
+		policyRecommendation.Spec.Policy))
+	policyRecommendation.Status.Recommender = recommenderName
+	var recommendation *reco.Recommendation
+	if err == nil {
+		recommendation, err = recommender.Recommend(policyRecommendation.Spec.WorkloadSpec)
+	}
+	if err != nil {
+		// The last generated TargetHPAConfiguration is retained.
+		reason := ottoscaleriov1alpha1.ReasonRecommenderFailed
+		if kind := reco.KindOf(err); kind != "" {
+			reason = string(kind)
+		}
+		status := policyRecommendation.Status.DeepCopy()
+		status.SetCondition(ottoscaleriov1alpha1.RecommendationInProgress, metav1.ConditionFalse,
+			ottoscaleriov1alpha1.ReasonCompleted, "")
+		status.SetCondition(ottoscaleriov1alpha1.RecommendationGenerated, metav1.ConditionFalse, reason, err.Error())
+
+		if reco.IsRetryable(err) {
+			// The workload stays queued and the request is retried.
+			logger.Error(err, "Error while generating recommendation. Requeue the request")
+			policyRecommendation.Status = *status
+			if statusErr := r.Client.Status().Update(ctx, &policyRecommendation); statusErr != nil {
+				logger.Error(statusErr, "Error while updating PolicyRecommendation status.")
+			}
+			return retryResult(err)
+		}
+
+		// Retrying won't help until the workload changes, so the workload waits for the next trigger instead.
+		logger.Error(err, "Error while generating recommendation. Dequeuing the PolicyRecommendation")
+		status.SetCondition(ottoscaleriov1alpha1.RecommendationQueued, metav1.ConditionFalse,
+			ottoscaleriov1alpha1.ReasonExecuted, "")
+		policyRecommendation.Spec.QueuedForExecution = false
+		return ctrl.Result{}, r.updateSpecAndStatus(ctx, &policyRecommendation, status, logger)
+	}
+
+	// The status is captured before the spec update, as the update overwrites the object with the server state.
+	status := policyRecommendation.Status.DeepCopy()
+	if recommendation == nil {
+		// Nothing could be recommended in this run. Retain the previous configuration and wait for the next trigger.
+		logger.Info("No recommendation generated for the workload.")
+		status.SetCondition(ottoscaleriov1alpha1.RecommendationGenerated, metav1.ConditionFalse,
+			ottoscaleriov1alpha1.ReasonNoRecommendation, "Recommender didn't generate a recommendation")
+	} else {
+		hpaConfiguration := &recommendation.HPAConfiguration
+		promotedPolicy, err := r.PolicyPromoter.GetPromotedPolicy(&policyRecommendation, hpaConfiguration)
+		if err != nil {
+			logger.Error(err, "Error while evaluating policy promotion. Continuing with the current policy.")
+		} else if promotedPolicy != nil {
+			logger.Info("Promoting the workload to the next policy", "from",
+				policyRecommendation.Spec.Policy.Spec.ID, "to", promotedPolicy.Spec.ID)
+			status.PolicyTransitions = policy.AppendTransition(status.PolicyTransitions,
+				ottoscaleriov1alpha1.PolicyTransition{
+					Type:           ottoscaleriov1alpha1.PolicyPromoted,
+					FromPolicy:     policyRecommendation.Spec.Policy.Spec.ID,
+					ToPolicy:       promotedPolicy.Spec.ID,
+					TransitionTime: metav1.NewTime(time.Now()),
+				})
+			if meta.FindStatusCondition(status.Conditions, ottoscaleriov1alpha1.BreachDetected) != nil {
+				status.SetCondition(ottoscaleriov1alpha1.BreachDetected, metav1.ConditionFalse,
+					ottoscaleriov1alpha1.ReasonPromoted, "Workload was promoted after a breach free soak period")
+			}
+			policyRecommendation.Spec.Policy = *promotedPolicy
+		}
+
+		targetHPAConfiguration, source := reco.ApplyPolicy(*hpaConfiguration, policyRecommendation.Spec.Policy)
+		policyRecommendation.Spec.TargetHPAConfiguration = targetHPAConfiguration
+		policyRecommendation.Spec.GeneratedAt = metav1.NewTime(time.Now())
+		status.OptimalHPAConfiguration = hpaConfiguration
+		status.BreachBudget = recommendation.BreachBudget
+		status.MetricContributions = recommendation.MetricContributions
+		status.ExcludedMetricRanges = recommendation.ExcludedMetricRanges
+		status.AutoscalingCycleLag = recommendation.ACL
+		// The cost is that of the HPA applied, so it is estimated on the configuration bounded by the policy. It is
+		// only informational, so failing to estimate it doesn't hold the recommendation back.
+		status.Cost, err = recommendation.EstimateCost(targetHPAConfiguration)
+		if err != nil {
+			logger.Error(err, "Error while estimating the cost of the recommendation.")
+		}
+		status.MinReplicasSchedule = recommendation.MinReplicasSchedule
+		status.Forecast = nil
+		if recommendation.Forecast != nil {
+			status.Forecast = recommendation.Forecast.Summary()
+		}
+		if recommendation.Explanation != nil {
+			// The explanation is only informational, so failing to store it doesn't hold the recommendation back.
+			name, err := r.storeExplanation(ctx, &policyRecommendation, recommendation.Explanation)
+			if err != nil {
+				logger.Error(err, "Error while storing the explanation of the recommendation.")
+			} else {
+				status.ExplanationConfigMap = name
+				if status.Forecast != nil {
+					status.Forecast.SeriesConfigMap = name
+				}
+			}
+		}
+		status.TargetHPAConfigurationSource = source
+		status.RecommendationHistory = appendRecommendationHistory(status.RecommendationHistory,
+			ottoscaleriov1alpha1.RecommendationHistoryEntry{
+				HPAConfiguration: targetHPAConfiguration,
+				Policy:           policyRecommendation.Spec.Policy.Spec.ID,
+				GeneratedAt:      policyRecommendation.Spec.GeneratedAt,
+			})
+
+		status.SetCondition(ottoscaleriov1alpha1.RecommendationGenerated, metav1.ConditionTrue,
+			ottoscaleriov1alpha1.ReasonRecommendationReady, "Target HPA configuration has been generated")
+		if targetHPAConfiguration.Equal(*hpaConfiguration) {
+			status.SetCondition(ottoscaleriov1alpha1.TargetReached, metav1.ConditionTrue,
+				ottoscaleriov1alpha1.ReasonOptimalTarget, "Target HPA configuration is the optimal HPA configuration")
+		} else {
+			status.SetCondition(ottoscaleriov1alpha1.TargetReached, metav1.ConditionFalse,
+				ottoscaleriov1alpha1.ReasonBoundedByPolicy,
+				"Target HPA configuration is bounded by the policy "+policyRecommendation.Spec.Policy.Spec.ID)
+		}
+		setWarningCondition(status, recommendation.Warnings)
+	}
+	status.SetCondition(ottoscaleriov1alpha1.RecommendationInProgress, metav1.ConditionFalse,
+		ottoscaleriov1alpha1.ReasonCompleted, "")
+	status.SetCondition(ottoscaleriov1alpha1.RecommendationQueued, metav1.ConditionFalse,
+		ottoscaleriov1alpha1.ReasonExecuted, "")
+
+	// The registrar keeps the shadow mode in sync on annotation changes. It is resolved again here the same way, so
+	// that a missed event doesn't get the HPA applied for a workload in shadow mode.
+	shadowMode, err := isShadowModeEnabled(ctx, r.Client, policyRecommendation.Spec.WorkloadSpec.Namespace,
+		workload.GetAnnotations())
+	if err != nil {
+		logger.Error(err, "Error while resolving the shadow mode. Retaining the current mode.")
+	} else {
+		policyRecommendation.Spec.ShadowMode = shadowMode
+	}
+	policyRecommendation.Spec.QueuedForExecution = false
 
-	// TODO(user): your logic here
+	if err := r.updateSpecAndStatus(ctx, &policyRecommendation, status, logger); err != nil {
+		return ctrl.Result{}, err
+	}
 
+	logger.Info("PolicyRecommendation executed successfully", "targetHPAConfig",
+		policyRecommendation.Spec.TargetHPAConfiguration)
 	return ctrl.Result{}, nil
 }
 
+// retryResult returns the Result a retryable error of the Recommender is retried with. A workload with too little
+// metrics is retried after a fixed delay, and the other errors are retried with backoff.
+func retryResult(err error) (ctrl.Result, error) {
+	if reco.KindOf(err) == reco.InsufficientData {
+		return ctrl.Result{RequeueAfter: insufficientDataRequeueDelay}, nil
+	}
+	return ctrl.Result{}, err
+}
+
+// setWarningCondition records the warnings raised by the Recommender as the RecommendationWarning condition.
+func setWarningCondition(status *ottoscaleriov1alpha1.PolicyRecommendationStatus, warnings []reco.Warning) {
+	if len(warnings) == 0 {
+		status.SetCondition(ottoscaleriov1alpha1.RecommendationWarning, metav1.ConditionFalse,
+			ottoscaleriov1alpha1.ReasonNoWarnings, "")
+		return
+	}
+
+	messages := make([]string, 0, len(warnings))
+	for _, warning := range warnings {
+		messages = append(messages, warning.Message)
+	}
+	status.SetCondition(ottoscaleriov1alpha1.RecommendationWarning, metav1.ConditionTrue, warnings[0].Reason,
+		strings.Join(messages, "; "))
+}
+
+// recommenderNameForWorkload returns the name of the Recommender asked for by the annotation on the workload, or by
+// the Policy assigned to it. An empty name stands for the default Recommender.
+func recommenderNameForWorkload(workload client.Object, policy ottoscaleriov1alpha1.Policy) string {
+	if name := workload.GetAnnotations()[RecommenderAnnotation]; name != "" {
+		return name
+	}
+	return policy.Spec.Recommender
+}
+
+// updateSpecAndStatus updates the spec of the PolicyRecommendation followed by its status. The status is passed
+// separately, as the spec update overwrites the object with the server state.
+func (r *PolicyRecommendationReconciler) updateSpecAndStatus(ctx context.Context,
+	policyRecommendation *ottoscaleriov1alpha1.PolicyRecommendation,
+	status *ottoscaleriov1alpha1.PolicyRecommendationStatus,
+	logger logr.Logger) error {
+
+	if err := r.Client.Update(ctx, policyRecommendation); err != nil {
+		logger.Error(err, "Error while updating PolicyRecommendation. Requeue the request")
+		return err
+	}
+
+	policyRecommendation.Status = *status
+	if err := r.Client.Status().Update(ctx, policyRecommendation); err != nil {
+		logger.Error(err, "Error while updating PolicyRecommendation status. Requeue the request")
+		return err
+	}
+	return nil
+}
+
+// appendRecommendationHistory appends the entry to the history when the HPAConfiguration or the Policy differs from
+// the latest entry, and retains the most recent maxRecommendationHistory entries.
+func appendRecommendationHistory(history []ottoscaleriov1alpha1.RecommendationHistoryEntry,
+	entry ottoscaleriov1alpha1.RecommendationHistoryEntry) []ottoscaleriov1alpha1.RecommendationHistoryEntry {
+	if len(history) > 0 {
+		latest := history[len(history)-1]
+		if latest.HPAConfiguration.Equal(entry.HPAConfiguration) && latest.Policy == entry.Policy {
+			return history
+		}
+	}
+	history = append(history, entry)
+	if len(history) > maxRecommendationHistory {
+		history = history[len(history)-maxRecommendationHistory:]
+	}
+	return history
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *PolicyRecommendationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&ottoscaleriov1alpha1.PolicyRecommendation{}).
+		// The status updates of the reconciler leave the generation as is, and don't queue the request again.
+		For(&ottoscaleriov1alpha1.PolicyRecommendation{},
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		// # of concurrent executions can be increased by tweaking this parameter.
 		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Complete(r)
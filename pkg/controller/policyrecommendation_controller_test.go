@@ -0,0 +1,562 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	ottoscaleriov1alpha1 "github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/reco"
+	"golang.org/x/net/context"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PolicyRecommendationReconciler controller", func() {
+
+	const (
+		PolicyRecoName      = "test-queued-reco"
+		PolicyRecoNamespace = "default"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	Context("When a PolicyRecommendation is queued for execution", func() {
+		It("Should generate the recommendation and clear the queue flag", func() {
+			By("Creating a queued PolicyRecommendation")
+			ctx := context.TODO()
+			policyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      PolicyRecoName,
+					Namespace: PolicyRecoNamespace,
+				},
+				Spec: ottoscaleriov1alpha1.PolicyRecommendationSpec{
+					WorkloadSpec: ottoscaleriov1alpha1.WorkloadSpec{
+						Name:      PolicyRecoName,
+						Namespace: PolicyRecoNamespace,
+						TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+					},
+					QueuedForExecution:   true,
+					QueuedForExecutionAt: metav1.NewTime(time.Now()),
+				},
+			}
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			updatedPolicyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{}
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: PolicyRecoName, Namespace: PolicyRecoNamespace},
+					updatedPolicyRecommendation)
+				if err != nil {
+					return false
+				}
+				return !updatedPolicyRecommendation.Spec.QueuedForExecution
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(updatedPolicyRecommendation.Spec.TargetHPAConfiguration).Should(Equal(
+				ottoscaleriov1alpha1.HPAConfiguration{Min: 10, Max: 60, TargetMetricValue: 50}))
+			Expect(updatedPolicyRecommendation.Spec.GeneratedAt.IsZero()).Should(BeFalse())
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+
+		It("Should store the explanation of the recommendation in a ConfigMap", func() {
+			const explainedRecoName = "test-explained-reco"
+			ctx := context.TODO()
+			policyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      explainedRecoName,
+					Namespace: PolicyRecoNamespace,
+				},
+				Spec: ottoscaleriov1alpha1.PolicyRecommendationSpec{
+					WorkloadSpec: ottoscaleriov1alpha1.WorkloadSpec{
+						Name:      explainedRecoName,
+						Namespace: PolicyRecoNamespace,
+						TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+					},
+					QueuedForExecution:   true,
+					QueuedForExecutionAt: metav1.NewTime(time.Now()),
+				},
+			}
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			updatedPolicyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{}
+			Eventually(func() string {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: explainedRecoName, Namespace: PolicyRecoNamespace},
+					updatedPolicyRecommendation)
+				if err != nil {
+					return ""
+				}
+				return updatedPolicyRecommendation.Status.ExplanationConfigMap
+			}, timeout, interval).Should(Equal(explainedRecoName + "-explanation"))
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: explainedRecoName + "-explanation",
+				Namespace: PolicyRecoNamespace}, configMap)).Should(Succeed())
+			Expect(configMap.OwnerReferences).Should(HaveLen(1))
+			Expect(configMap.OwnerReferences[0].UID).Should(Equal(updatedPolicyRecommendation.UID))
+
+			explanation := reco.Explanation{}
+			Expect(json.Unmarshal([]byte(configMap.Data[ExplanationKey]), &explanation)).Should(Succeed())
+			Expect(explanation.Metrics).Should(HaveLen(1))
+			Expect(explanation.Metrics[0].Target).Should(Equal(50))
+			Expect(explanation.Metrics[0].Candidates).Should(HaveLen(1))
+
+			Expect(k8sClient.Delete(ctx, configMap)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+
+		It("Should bound the recommendation with the assigned policy", func() {
+			By("Creating a queued PolicyRecommendation with a conservative policy")
+			ctx := context.TODO()
+			policyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      PolicyRecoName,
+					Namespace: PolicyRecoNamespace,
+				},
+				Spec: ottoscaleriov1alpha1.PolicyRecommendationSpec{
+					WorkloadSpec: ottoscaleriov1alpha1.WorkloadSpec{
+						Name:      PolicyRecoName,
+						Namespace: PolicyRecoNamespace,
+						TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+					},
+					Policy: ottoscaleriov1alpha1.Policy{
+						Spec: ottoscaleriov1alpha1.PolicySpec{ID: "conservative", Min: 20, TargetUtilization: 40},
+					},
+					QueuedForExecution:   true,
+					QueuedForExecutionAt: metav1.NewTime(time.Now()),
+				},
+			}
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			updatedPolicyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{}
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: PolicyRecoName, Namespace: PolicyRecoNamespace},
+					updatedPolicyRecommendation)
+				if err != nil {
+					return false
+				}
+				return updatedPolicyRecommendation.Status.OptimalHPAConfiguration != nil
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(updatedPolicyRecommendation.Spec.TargetHPAConfiguration).Should(Equal(
+				ottoscaleriov1alpha1.HPAConfiguration{Min: 20, Max: 75, TargetMetricValue: 40}))
+			Expect(*updatedPolicyRecommendation.Status.OptimalHPAConfiguration).Should(Equal(
+				ottoscaleriov1alpha1.HPAConfiguration{Min: 10, Max: 60, TargetMetricValue: 50}))
+			Expect(updatedPolicyRecommendation.Status.TargetHPAConfigurationSource).Should(Equal(
+				ottoscaleriov1alpha1.HPAConfigurationSource{
+					Min:               ottoscaleriov1alpha1.PolicySource,
+					Max:               ottoscaleriov1alpha1.PolicySource,
+					TargetMetricValue: ottoscaleriov1alpha1.PolicySource,
+				}))
+
+			By("Recording the conditions and the history")
+			conditions := updatedPolicyRecommendation.Status.Conditions
+			Expect(meta.IsStatusConditionTrue(conditions, ottoscaleriov1alpha1.RecommendationGenerated)).Should(BeTrue())
+			Expect(meta.IsStatusConditionFalse(conditions, ottoscaleriov1alpha1.RecommendationWarning)).Should(BeTrue())
+			Expect(meta.IsStatusConditionFalse(conditions, ottoscaleriov1alpha1.RecommendationQueued)).Should(BeTrue())
+			Expect(meta.IsStatusConditionFalse(conditions,
+				ottoscaleriov1alpha1.RecommendationInProgress)).Should(BeTrue())
+			targetReached := meta.FindStatusCondition(conditions, ottoscaleriov1alpha1.TargetReached)
+			Expect(targetReached).ShouldNot(BeNil())
+			Expect(targetReached.Status).Should(Equal(metav1.ConditionFalse))
+			Expect(targetReached.Reason).Should(Equal(ottoscaleriov1alpha1.ReasonBoundedByPolicy))
+			Expect(updatedPolicyRecommendation.Status.RecommendationHistory).Should(HaveLen(1))
+			Expect(updatedPolicyRecommendation.Status.RecommendationHistory[0].HPAConfiguration).Should(Equal(
+				updatedPolicyRecommendation.Spec.TargetHPAConfiguration))
+			Expect(updatedPolicyRecommendation.Status.RecommendationHistory[0].Policy).Should(Equal("conservative"))
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+
+		It("Should promote the workload and record the transition", func() {
+			By("Creating a queued PolicyRecommendation on a promotable policy")
+			ctx := context.TODO()
+			policyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      PolicyRecoName,
+					Namespace: PolicyRecoNamespace,
+				},
+				Spec: ottoscaleriov1alpha1.PolicyRecommendationSpec{
+					WorkloadSpec: ottoscaleriov1alpha1.WorkloadSpec{
+						Name:      PolicyRecoName,
+						Namespace: PolicyRecoNamespace,
+						TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+					},
+					Policy: ottoscaleriov1alpha1.Policy{
+						Spec: ottoscaleriov1alpha1.PolicySpec{ID: "promotable", Min: 20, TargetUtilization: 30},
+					},
+					QueuedForExecution:   true,
+					QueuedForExecutionAt: metav1.NewTime(time.Now()),
+				},
+			}
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			updatedPolicyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{}
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: PolicyRecoName, Namespace: PolicyRecoNamespace},
+					updatedPolicyRecommendation)
+				if err != nil {
+					return false
+				}
+				return len(updatedPolicyRecommendation.Status.PolicyTransitions) > 0
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(updatedPolicyRecommendation.Spec.Policy.Spec.ID).Should(Equal("nextSafestPolicy"))
+			Expect(updatedPolicyRecommendation.Spec.TargetHPAConfiguration).Should(Equal(
+				ottoscaleriov1alpha1.HPAConfiguration{Min: 10, Max: 67, TargetMetricValue: 45}))
+			transition := updatedPolicyRecommendation.Status.PolicyTransitions[0]
+			Expect(transition.Type).Should(Equal(ottoscaleriov1alpha1.PolicyPromoted))
+			Expect(transition.FromPolicy).Should(Equal("promotable"))
+			Expect(transition.ToPolicy).Should(Equal("nextSafestPolicy"))
+			Expect(transition.TransitionTime.IsZero()).Should(BeFalse())
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+
+		It("Should record the failure and keep the workload queued when the recommender fails", func() {
+			ctx := context.TODO()
+			policyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      failingWorkloadName,
+					Namespace: PolicyRecoNamespace,
+				},
+				Spec: ottoscaleriov1alpha1.PolicyRecommendationSpec{
+					WorkloadSpec: ottoscaleriov1alpha1.WorkloadSpec{
+						Name:      failingWorkloadName,
+						Namespace: PolicyRecoNamespace,
+						TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+					},
+					QueuedForExecution:   true,
+					QueuedForExecutionAt: metav1.NewTime(time.Now()),
+				},
+			}
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			updatedPolicyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{}
+			var generated *metav1.Condition
+			Eventually(func() *metav1.Condition {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: failingWorkloadName,
+					Namespace: PolicyRecoNamespace}, updatedPolicyRecommendation)
+				if err != nil {
+					return nil
+				}
+				generated = meta.FindStatusCondition(updatedPolicyRecommendation.Status.Conditions,
+					ottoscaleriov1alpha1.RecommendationGenerated)
+				return generated
+			}, timeout, interval).ShouldNot(BeNil())
+
+			Expect(generated.Status).Should(Equal(metav1.ConditionFalse))
+			Expect(generated.Reason).Should(Equal(string(reco.MetricsUnavailable)))
+			Expect(generated.Message).Should(ContainSubstring("prometheus is down"))
+			Expect(updatedPolicyRecommendation.Spec.QueuedForExecution).Should(BeTrue())
+			Expect(updatedPolicyRecommendation.Status.RecommendationHistory).Should(BeEmpty())
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+	})
+
+	Context("When the recommender fails with a non retryable error", func() {
+		It("Should dequeue the PolicyRecommendation and keep the last generated configuration", func() {
+			ctx := context.TODO()
+			lastTarget := ottoscaleriov1alpha1.HPAConfiguration{Min: 3, Max: 9, TargetMetricValue: 40}
+			policyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      unsupportedWorkloadName,
+					Namespace: PolicyRecoNamespace,
+				},
+				Spec: ottoscaleriov1alpha1.PolicyRecommendationSpec{
+					WorkloadSpec: ottoscaleriov1alpha1.WorkloadSpec{
+						Name:      unsupportedWorkloadName,
+						Namespace: PolicyRecoNamespace,
+						TypeMeta:  metav1.TypeMeta{Kind: "StatefulSet", APIVersion: "apps/v1"},
+					},
+					TargetHPAConfiguration: lastTarget,
+					QueuedForExecution:     true,
+					QueuedForExecutionAt:   metav1.NewTime(time.Now()),
+				},
+			}
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			updatedPolicyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{}
+			var generated *metav1.Condition
+			Eventually(func() *metav1.Condition {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: unsupportedWorkloadName,
+					Namespace: PolicyRecoNamespace}, updatedPolicyRecommendation)
+				if err != nil {
+					return nil
+				}
+				generated = meta.FindStatusCondition(updatedPolicyRecommendation.Status.Conditions,
+					ottoscaleriov1alpha1.RecommendationGenerated)
+				return generated
+			}, timeout, interval).ShouldNot(BeNil())
+
+			Expect(generated.Status).Should(Equal(metav1.ConditionFalse))
+			Expect(generated.Reason).Should(Equal(string(reco.UnsupportedWorkloadKind)))
+			Expect(updatedPolicyRecommendation.Spec.QueuedForExecution).Should(BeFalse())
+			Expect(updatedPolicyRecommendation.Spec.TargetHPAConfiguration).Should(Equal(lastTarget))
+			Expect(meta.IsStatusConditionFalse(updatedPolicyRecommendation.Status.Conditions,
+				ottoscaleriov1alpha1.RecommendationQueued)).Should(BeTrue())
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+	})
+
+	Context("When the reconciler updates the status of a PolicyRecommendation", func() {
+		It("Should run the recommender once per execution", func() {
+			ctx := context.TODO()
+			const name = "test-recommended-once"
+			policyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: PolicyRecoNamespace,
+				},
+				Spec: ottoscaleriov1alpha1.PolicyRecommendationSpec{
+					WorkloadSpec: ottoscaleriov1alpha1.WorkloadSpec{
+						Name:      name,
+						Namespace: PolicyRecoNamespace,
+						TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+					},
+					QueuedForExecution:   true,
+					QueuedForExecutionAt: metav1.NewTime(time.Now()),
+				},
+			}
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			updatedPolicyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{}
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: PolicyRecoNamespace},
+					updatedPolicyRecommendation)
+				return err == nil && meta.IsStatusConditionTrue(updatedPolicyRecommendation.Status.Conditions,
+					ottoscaleriov1alpha1.RecommendationGenerated)
+			}, timeout, interval).Should(BeTrue())
+			Consistently(func() int {
+				return recommendCallsFor(name)
+			}, 2*time.Second, interval).Should(Equal(1))
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+	})
+
+	Context("When the workload was deleted", func() {
+		It("Should dequeue the PolicyRecommendation instead of retrying it", func() {
+			ctx := context.TODO()
+			policyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      deletedWorkloadName,
+					Namespace: PolicyRecoNamespace,
+				},
+				Spec: ottoscaleriov1alpha1.PolicyRecommendationSpec{
+					WorkloadSpec: ottoscaleriov1alpha1.WorkloadSpec{
+						Name:      deletedWorkloadName,
+						Namespace: PolicyRecoNamespace,
+						TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+					},
+					QueuedForExecution:   true,
+					QueuedForExecutionAt: metav1.NewTime(time.Now()),
+				},
+			}
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			updatedPolicyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{}
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: deletedWorkloadName,
+					Namespace: PolicyRecoNamespace}, updatedPolicyRecommendation)
+				return err == nil && !updatedPolicyRecommendation.Spec.QueuedForExecution
+			}, timeout, interval).Should(BeTrue())
+
+			generated := meta.FindStatusCondition(updatedPolicyRecommendation.Status.Conditions,
+				ottoscaleriov1alpha1.RecommendationGenerated)
+			Expect(generated).NotTo(BeNil())
+			Expect(generated.Reason).Should(Equal(string(reco.WorkloadNotFound)))
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+	})
+
+	Context("When retrying a failed recommendation", func() {
+		It("Should wait a fixed delay for a workload with too little metrics", func() {
+			result, err := retryResult(&reco.RecommendationError{Kind: reco.InsufficientData,
+				Err: errors.New("no cpu utilization data points")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).Should(Equal(insufficientDataRequeueDelay))
+		})
+
+		It("Should retry the other errors with backoff", func() {
+			result, err := retryResult(&reco.RecommendationError{Kind: reco.MetricsUnavailable,
+				Err: errors.New("prometheus is down")})
+			Expect(err).To(HaveOccurred())
+			Expect(result.RequeueAfter).Should(BeZero())
+		})
+	})
+
+	Context("When choosing the recommender for a workload", func() {
+		getGeneratedPolicyRecommendation := func(ctx context.Context,
+			name string) *ottoscaleriov1alpha1.PolicyRecommendation {
+			policyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{}
+			Eventually(func() *metav1.Condition {
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: PolicyRecoNamespace},
+					policyRecommendation); err != nil {
+					return nil
+				}
+				return meta.FindStatusCondition(policyRecommendation.Status.Conditions,
+					ottoscaleriov1alpha1.RecommendationGenerated)
+			}, timeout, interval).ShouldNot(BeNil())
+			return policyRecommendation
+		}
+
+		newQueuedPolicyRecommendation := func(name, recommender string) *ottoscaleriov1alpha1.PolicyRecommendation {
+			return &ottoscaleriov1alpha1.PolicyRecommendation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: PolicyRecoNamespace,
+				},
+				Spec: ottoscaleriov1alpha1.PolicyRecommendationSpec{
+					WorkloadSpec: ottoscaleriov1alpha1.WorkloadSpec{
+						Name:      name,
+						Namespace: PolicyRecoNamespace,
+						TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+					},
+					Policy: ottoscaleriov1alpha1.Policy{
+						Spec: ottoscaleriov1alpha1.PolicySpec{ID: "selecting", Recommender: recommender},
+					},
+					QueuedForExecution:   true,
+					QueuedForExecutionAt: metav1.NewTime(time.Now()),
+				},
+			}
+		}
+
+		It("Should use the recommender set on the policy", func() {
+			ctx := context.TODO()
+			policyRecommendation := newQueuedPolicyRecommendation("test-policy-recommender", "alternate")
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			updatedPolicyRecommendation := getGeneratedPolicyRecommendation(ctx, policyRecommendation.Name)
+			Expect(updatedPolicyRecommendation.Status.Recommender).Should(Equal("alternate"))
+			Expect(updatedPolicyRecommendation.Spec.TargetHPAConfiguration).Should(Equal(
+				ottoscaleriov1alpha1.HPAConfiguration{Min: 2, Max: 8, TargetMetricValue: 70}))
+
+			By("Recording the warnings raised by the recommender")
+			warning := meta.FindStatusCondition(updatedPolicyRecommendation.Status.Conditions,
+				ottoscaleriov1alpha1.RecommendationWarning)
+			Expect(warning).ShouldNot(BeNil())
+			Expect(warning.Status).Should(Equal(metav1.ConditionTrue))
+			Expect(warning.Reason).Should(Equal(reco.MaxCappedByQuota))
+			Expect(warning.Message).Should(ContainSubstring("capped from 12 to 8"))
+
+			By("Reporting the breach budget consumed by the recommendation")
+			Expect(updatedPolicyRecommendation.Status.BreachBudget).ShouldNot(BeNil())
+			Expect(updatedPolicyRecommendation.Status.BreachBudget.Allowed.Duration).Should(Equal(40 * time.Minute))
+			Expect(updatedPolicyRecommendation.Status.BreachBudget.Consumed.Duration).Should(Equal(5 * time.Minute))
+
+			By("Summarizing the forecast the recommendation was simulated against")
+			Expect(updatedPolicyRecommendation.Status.Forecast).ShouldNot(BeNil())
+			Expect(updatedPolicyRecommendation.Status.Forecast.Mode).Should(Equal("Forecast"))
+			Expect(updatedPolicyRecommendation.Status.Forecast.Horizon.Duration).Should(Equal(7 * 24 * time.Hour))
+			Expect(updatedPolicyRecommendation.Status.Forecast.ForecastPeak.String()).Should(Equal("13500m"))
+
+			By("Pointing at the forecast series stored with the explanation")
+			Expect(updatedPolicyRecommendation.Status.Forecast.SeriesConfigMap).Should(Equal(
+				"test-policy-recommender-explanation"))
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-policy-recommender-explanation",
+				Namespace: policyRecommendation.Namespace}, configMap)).Should(Succeed())
+			explanation := reco.Explanation{}
+			Expect(json.Unmarshal([]byte(configMap.Data[ExplanationKey]), &explanation)).Should(Succeed())
+			Expect(explanation.Metrics[0].Forecast).ShouldNot(BeNil())
+			Expect(explanation.Metrics[0].Forecast.Points).Should(HaveLen(1))
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+
+		It("Should use the recommender annotated on the workload", func() {
+			ctx := context.TODO()
+			name := "test-annotated-recommender"
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Namespace:   PolicyRecoNamespace,
+					Annotations: map[string]string{RecommenderAnnotation: "alternate"},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "test-container", Image: "nginx:1.17.5"}},
+						},
+					},
+				},
+			}
+			// The registrar creates the PolicyRecommendation for the deployment.
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			updatedPolicyRecommendation := getGeneratedPolicyRecommendation(ctx, name)
+			Expect(updatedPolicyRecommendation.Status.Recommender).Should(Equal("alternate"))
+			Expect(updatedPolicyRecommendation.Spec.TargetHPAConfiguration.TargetMetricValue).Should(Equal(70))
+
+			Expect(k8sClient.Delete(ctx, deployment)).Should(Succeed())
+		})
+
+		It("Should prefer the recommender annotated on the workload over the policy", func() {
+			policy := ottoscaleriov1alpha1.Policy{Spec: ottoscaleriov1alpha1.PolicySpec{Recommender: "alternate"}}
+			annotated := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{RecommenderAnnotation: "fake"}}}
+
+			Expect(recommenderNameForWorkload(annotated, policy)).Should(Equal("fake"))
+			Expect(recommenderNameForWorkload(&appsv1.Deployment{}, policy)).Should(Equal("alternate"))
+			Expect(recommenderNameForWorkload(&appsv1.Deployment{},
+				ottoscaleriov1alpha1.Policy{})).Should(BeEmpty())
+		})
+
+		It("Should dequeue the workload when the recommender isn't registered", func() {
+			ctx := context.TODO()
+			policyRecommendation := newQueuedPolicyRecommendation("test-unknown-recommender", "missing")
+			Expect(k8sClient.Create(ctx, policyRecommendation)).Should(Succeed())
+
+			updatedPolicyRecommendation := getGeneratedPolicyRecommendation(ctx, policyRecommendation.Name)
+			generated := meta.FindStatusCondition(updatedPolicyRecommendation.Status.Conditions,
+				ottoscaleriov1alpha1.RecommendationGenerated)
+			Expect(generated.Status).Should(Equal(metav1.ConditionFalse))
+			Expect(generated.Reason).Should(Equal(string(reco.UnknownRecommender)))
+			Expect(updatedPolicyRecommendation.Status.Recommender).Should(Equal("missing"))
+			Expect(updatedPolicyRecommendation.Spec.QueuedForExecution).Should(BeFalse())
+
+			Expect(k8sClient.Delete(ctx, policyRecommendation)).Should(Succeed())
+		})
+	})
+
+	Context("When appending to the recommendation history", func() {
+		newEntry := func(max int, policy string) ottoscaleriov1alpha1.RecommendationHistoryEntry {
+			return ottoscaleriov1alpha1.RecommendationHistoryEntry{
+				HPAConfiguration: ottoscaleriov1alpha1.HPAConfiguration{Min: 1, Max: max, TargetMetricValue: 50},
+				Policy:           policy,
+				GeneratedAt:      metav1.NewTime(time.Now()),
+			}
+		}
+
+		It("Should skip an entry identical to the latest one", func() {
+			history := appendRecommendationHistory(nil, newEntry(10, "safe"))
+			history = appendRecommendationHistory(history, newEntry(10, "safe"))
+			Expect(history).Should(HaveLen(1))
+
+			history = appendRecommendationHistory(history, newEntry(10, "risky"))
+			Expect(history).Should(HaveLen(2))
+		})
+
+		It("Should retain only the most recent entries", func() {
+			var history []ottoscaleriov1alpha1.RecommendationHistoryEntry
+			for i := 1; i <= maxRecommendationHistory+5; i++ {
+				history = appendRecommendationHistory(history, newEntry(i, "safe"))
+			}
+			Expect(history).Should(HaveLen(maxRecommendationHistory))
+			Expect(history[0].HPAConfiguration.Max).Should(Equal(6))
+			Expect(history[maxRecommendationHistory-1].HPAConfiguration.Max).Should(Equal(maxRecommendationHistory + 5))
+		})
+	})
+})
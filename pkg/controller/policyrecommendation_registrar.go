@@ -8,6 +8,7 @@ import (
 	"github.com/flipkart-incubator/ottoscalr/pkg/trigger"
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -50,6 +51,7 @@ func NewPolicyRecommendationRegistrar(client client.Client,
 
 // +kubebuilder:rbac:groups=argoproj.io,resources=rollouts,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups=your-group.io,resources=policyrecommendations,verbs=create;get;list;watch;update;delete
 //+kubebuilder:rbac:groups=ottoscaler.io,resources=policyrecommendations/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=ottoscaler.io,resources=policyrecommendations/finalizers,verbs=update
@@ -96,12 +98,18 @@ func (controller *PolicyRecommendationRegistrar) createPolicyRecommendation(
 	instance client.Object,
 	logger logr.Logger) (*ottoscaleriov1alpha1.PolicyRecommendation, error) {
 
+	shadowMode, err := isShadowModeEnabled(ctx, controller.Client, instance.GetNamespace(), instance.GetAnnotations())
+	if err != nil {
+		logger.Error(err, "Error resolving the shadow mode - requeue the request")
+		return nil, err
+	}
+
 	// Check if a PolicyRecommendation object already exists
 	policyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{}
-	err := controller.Client.Get(ctx, types.NamespacedName{Name: instance.GetName(), Namespace: instance.GetNamespace()}, policyRecommendation)
+	err = controller.Client.Get(ctx, types.NamespacedName{Name: instance.GetName(), Namespace: instance.GetNamespace()}, policyRecommendation)
 	if err == nil {
 		logger.Info("PolicyRecommendation object already exists")
-		return nil, nil
+		return nil, controller.syncShadowMode(ctx, policyRecommendation, shadowMode, logger)
 	} else if !errors.IsNotFound(err) {
 		logger.Error(err, "Error reading the object - requeue the request")
 		return nil, err
@@ -126,10 +134,12 @@ func (controller *PolicyRecommendationRegistrar) createPolicyRecommendation(
 		},
 		Spec: ottoscaleriov1alpha1.PolicyRecommendationSpec{
 			WorkloadSpec: ottoscaleriov1alpha1.WorkloadSpec{Name: instance.GetName(),
-				TypeMeta: metav1.TypeMeta{Kind: gvk.Kind, APIVersion: gvk.GroupVersion().String()}},
+				Namespace: instance.GetNamespace(),
+				TypeMeta:  metav1.TypeMeta{Kind: gvk.Kind, APIVersion: gvk.GroupVersion().String()}},
 			Policy:               *safestPolicy,
 			QueuedForExecution:   true,
 			QueuedForExecutionAt: metav1.NewTime(time.Now()),
+			ShadowMode:           shadowMode,
 		},
 	}
 
@@ -145,11 +155,31 @@ func (controller *PolicyRecommendationRegistrar) createPolicyRecommendation(
 	return newPolicyRecommendation, nil
 }
 
+// syncShadowMode updates the shadow mode of an existing PolicyRecommendation when the annotation on the workload or
+// its namespace has changed.
+func (controller *PolicyRecommendationRegistrar) syncShadowMode(ctx context.Context,
+	policyRecommendation *ottoscaleriov1alpha1.PolicyRecommendation,
+	shadowMode bool,
+	logger logr.Logger) error {
+
+	if policyRecommendation.Spec.ShadowMode == shadowMode {
+		return nil
+	}
+
+	logger.Info("Updating the shadow mode of the PolicyRecommendation", "shadowMode", shadowMode)
+	policyRecommendation.Spec.ShadowMode = shadowMode
+	if err := controller.Client.Update(ctx, policyRecommendation); err != nil {
+		logger.Error(err, "Error updating the shadow mode - requeue the request")
+		return err
+	}
+	return nil
+}
+
 func (controller *PolicyRecommendationRegistrar) handleReconcile(ctx context.Context,
 	object client.Object,
 	logger logr.Logger) error {
 
-	_, err := controller.createPolicyRecommendation(ctx, object, logger)
+	policyRecommendation, err := controller.createPolicyRecommendation(ctx, object, logger)
 
 	if err == nil {
 		controller.MonitorManager.RegisterMonitor(object.GetObjectKind().GroupVersionKind().Kind,
@@ -158,6 +188,15 @@ func (controller *PolicyRecommendationRegistrar) handleReconcile(ctx context.Con
 				Namespace: object.GetNamespace(),
 			})
 	}
+
+	if policyRecommendation != nil {
+		policyRecommendation.Status.SetCondition(ottoscaleriov1alpha1.RecommendationQueued, metav1.ConditionTrue,
+			ottoscaleriov1alpha1.ReasonCreated, "Queued for execution on registration of the workload")
+		if err := controller.Client.Status().Update(ctx, policyRecommendation); err != nil {
+			// The recommendation is queued regardless, so the condition is not worth requeueing the request for.
+			logger.Error(err, "Error updating the status of the PolicyRecommendation")
+		}
+	}
 	return err
 }
 
@@ -171,8 +210,23 @@ func (controller *PolicyRecommendationRegistrar) SetupWithManager(mgr ctrl.Manag
 			return false
 		},
 		UpdateFunc: func(e event.UpdateEvent) bool {
+			return shadowModeAnnotationChanged(e)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
 			return false
 		},
+	}
+
+	namespacePredicate := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return shadowModeAnnotationChanged(e)
+		},
 		GenericFunc: func(e event.GenericEvent) bool {
 			return false
 		},
@@ -183,6 +237,26 @@ func (controller *PolicyRecommendationRegistrar) SetupWithManager(mgr ctrl.Manag
 			Namespace: obj.GetNamespace()}}}
 	}
 
+	// A change of the shadow mode annotation on a namespace is resolved for all the workloads in it.
+	enqueueNamespaceWorkloadsFunc := func(obj client.Object) []reconcile.Request {
+		var requests []reconcile.Request
+		rollouts := argov1alpha1.RolloutList{}
+		if err := controller.Client.List(context.Background(), &rollouts,
+			client.InNamespace(obj.GetName())); err == nil {
+			for _, rollout := range rollouts.Items {
+				requests = append(requests, enqueueFunc(&rollout)...)
+			}
+		}
+		deployments := appsv1.DeploymentList{}
+		if err := controller.Client.List(context.Background(), &deployments,
+			client.InNamespace(obj.GetName())); err == nil {
+			for _, deployment := range deployments.Items {
+				requests = append(requests, enqueueFunc(&deployment)...)
+			}
+		}
+		return requests
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		Named("PolicyRecommendationRegistrar").
 		Watches(
@@ -195,5 +269,10 @@ func (controller *PolicyRecommendationRegistrar) SetupWithManager(mgr ctrl.Manag
 			handler.EnqueueRequestsFromMapFunc(enqueueFunc),
 			builder.WithPredicates(createPredicate),
 		).
+		Watches(
+			&source.Kind{Type: &corev1.Namespace{}},
+			handler.EnqueueRequestsFromMapFunc(enqueueNamespaceWorkloadsFunc),
+			builder.WithPredicates(namespacePredicate),
+		).
 		Complete(controller)
 }
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"context"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"strconv"
+)
+
+// ShadowModeAnnotation switches a Deployment/Rollout, or all the workloads in a Namespace, to shadow mode. In shadow
+// mode the whole pipeline runs but the HPA is never applied; the would-be HPA spec is only recorded on the
+// PolicyRecommendation. The annotation on the workload takes precedence over the one on its namespace.
+const ShadowModeAnnotation = "ottoscalr.io/shadow-mode"
+
+// isShadowModeEnabled resolves the shadow mode for a workload from the annotations on the workload and its namespace.
+func isShadowModeEnabled(ctx context.Context,
+	k8sClient client.Client,
+	namespace string,
+	workloadAnnotations map[string]string) (bool, error) {
+
+	if enabled, ok := parseShadowModeAnnotation(workloadAnnotations); ok {
+		return enabled, nil
+	}
+
+	ns := corev1.Namespace{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: namespace}, &ns); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	enabled, _ := parseShadowModeAnnotation(ns.GetAnnotations())
+	return enabled, nil
+}
+
+func parseShadowModeAnnotation(annotations map[string]string) (bool, bool) {
+	value, ok := annotations[ShadowModeAnnotation]
+	if !ok {
+		return false, false
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, false
+	}
+	return enabled, true
+}
+
+func shadowModeAnnotationChanged(e event.UpdateEvent) bool {
+	return e.ObjectOld.GetAnnotations()[ShadowModeAnnotation] != e.ObjectNew.GetAnnotations()[ShadowModeAnnotation]
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	ottoscaleriov1alpha1 "github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"golang.org/x/net/context"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shadow mode", func() {
+
+	const (
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	newDeployment := func(name, namespace string, annotations map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   namespace,
+				Annotations: annotations,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": name},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{"app": name},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "test-container",
+								Image: "nginx:1.17.5",
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	getShadowMode := func(ctx context.Context, name types.NamespacedName) func() (bool, error) {
+		return func() (bool, error) {
+			policyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{}
+			if err := k8sClient.Get(ctx, name, policyRecommendation); err != nil {
+				return false, err
+			}
+			return policyRecommendation.Spec.ShadowMode, nil
+		}
+	}
+
+	Context("When a Deployment is annotated for shadow mode", func() {
+		It("Should record the HPA spec without applying it", func() {
+			ctx := context.TODO()
+			deployment := newDeployment("test-shadow-deployment", "default",
+				map[string]string{ShadowModeAnnotation: "true"})
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+			name := types.NamespacedName{Name: deployment.Name, Namespace: deployment.Namespace}
+
+			policyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{}
+			Eventually(func() *autoscalingv2.HorizontalPodAutoscalerSpec {
+				if err := k8sClient.Get(ctx, name, policyRecommendation); err != nil {
+					return nil
+				}
+				return policyRecommendation.Status.ShadowHPASpec
+			}, timeout, interval).ShouldNot(BeNil())
+
+			Expect(policyRecommendation.Spec.ShadowMode).Should(BeTrue())
+			Expect(policyRecommendation.Spec.TargetHPAConfiguration.Max).Should(Equal(60))
+			shadowHPASpec := policyRecommendation.Status.ShadowHPASpec
+			Expect(shadowHPASpec.ScaleTargetRef.Name).Should(Equal(deployment.Name))
+			Expect(shadowHPASpec.MaxReplicas).Should(Equal(int32(60)))
+			Expect(*shadowHPASpec.Metrics[0].Resource.Target.AverageUtilization).Should(Equal(int32(50)))
+
+			hpaList := &autoscalingv2.HorizontalPodAutoscalerList{}
+			Consistently(func() []autoscalingv2.HorizontalPodAutoscaler {
+				Expect(k8sClient.List(ctx, hpaList)).Should(Succeed())
+				var hpas []autoscalingv2.HorizontalPodAutoscaler
+				for _, hpa := range hpaList.Items {
+					if hpa.Spec.ScaleTargetRef.Name == deployment.Name {
+						hpas = append(hpas, hpa)
+					}
+				}
+				return hpas
+			}, time.Second*2, interval).Should(BeEmpty())
+
+			Expect(k8sClient.Delete(ctx, deployment)).Should(Succeed())
+		})
+	})
+
+	Context("When a Namespace is annotated for shadow mode", func() {
+		It("Should resolve the mode for its workloads with the workload annotation taking precedence", func() {
+			ctx := context.TODO()
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-shadow-namespace"}}
+			Expect(k8sClient.Create(ctx, namespace)).Should(Succeed())
+
+			deployment := newDeployment("test-shadow-ns-deployment", namespace.Name, nil)
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+			name := types.NamespacedName{Name: deployment.Name, Namespace: deployment.Namespace}
+			Eventually(getShadowMode(ctx, name), timeout, interval).Should(BeFalse())
+
+			By("Annotating the namespace")
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: namespace.Name}, namespace)).Should(Succeed())
+			namespace.Annotations = map[string]string{ShadowModeAnnotation: "true"}
+			Expect(k8sClient.Update(ctx, namespace)).Should(Succeed())
+			Eventually(getShadowMode(ctx, name), timeout, interval).Should(BeTrue())
+
+			By("Opting the workload out of shadow mode")
+			Expect(k8sClient.Get(ctx, name, deployment)).Should(Succeed())
+			deployment.Annotations = map[string]string{ShadowModeAnnotation: "false"}
+			Expect(k8sClient.Update(ctx, deployment)).Should(Succeed())
+			Eventually(getShadowMode(ctx, name), timeout, interval).Should(BeFalse())
+
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, name, hpa)
+			}, timeout, interval).Should(Succeed())
+			Expect(hpa.Spec.MaxReplicas).Should(Equal(int32(60)))
+
+			Expect(k8sClient.Delete(ctx, deployment)).Should(Succeed())
+		})
+	})
+})
@@ -17,15 +17,20 @@ limitations under the License.
 package controller
 
 import (
+	"errors"
 	rolloutv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/reco"
 	"github.com/flipkart-incubator/ottoscalr/pkg/testutil"
 	"github.com/flipkart-incubator/ottoscalr/pkg/trigger"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"golang.org/x/net/context"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sync"
 	"testing"
+	"time"
 
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
@@ -91,6 +96,23 @@ var _ = BeforeSuite(func() {
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
+	recommenderRegistry := reco.NewRegistry("fake")
+	recommenderRegistry.Register("fake", &FakeRecommender{})
+	recommenderRegistry.Register("alternate", &FakeAlternateRecommender{})
+	err = (&PolicyRecommendationReconciler{
+		Client:              k8sManager.GetClient(),
+		Scheme:              k8sManager.GetScheme(),
+		RecommenderRegistry: recommenderRegistry,
+		PolicyPromoter:      &FakePromoter{},
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&HPAActuator{
+		Client: k8sManager.GetClient(),
+		Scheme: k8sManager.GetScheme(),
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
 	err = (&PolicyWatcher{
 		Client: k8sManager.GetClient(),
 		Scheme: k8sManager.GetScheme(),
@@ -136,3 +158,94 @@ func (ps *FakePolicyStore) GetNextPolicy(currentPolicy *ottoscaleriov1alpha1.Pol
 	error) {
 	return &ottoscaleriov1alpha1.Policy{Spec: ottoscaleriov1alpha1.PolicySpec{ID: "nextSafestPolicy"}}, nil
 }
+
+func (ps *FakePolicyStore) GetPreviousPolicy(currentPolicy *ottoscaleriov1alpha1.Policy) (*ottoscaleriov1alpha1.Policy,
+	error) {
+	return &ottoscaleriov1alpha1.Policy{Spec: ottoscaleriov1alpha1.PolicySpec{ID: "safestPolicy"}}, nil
+}
+
+const (
+	// failingWorkloadName is the workload for which the FakeRecommender fails with a retryable error.
+	failingWorkloadName = "test-failing-reco"
+	// unsupportedWorkloadName is the workload for which the FakeRecommender fails with a non retryable error.
+	unsupportedWorkloadName = "test-unsupported-reco"
+	// deletedWorkloadName is the workload for which the FakeRecommender fails as if it was deleted.
+	deletedWorkloadName = "test-deleted-reco"
+)
+
+var (
+	// recommendCalls counts the runs of the FakeRecommender by workload.
+	recommendCalls      = map[string]int{}
+	recommendCallsMutex sync.Mutex
+)
+
+// recommendCallsFor returns the number of times the FakeRecommender ran for the workload.
+func recommendCallsFor(workload string) int {
+	recommendCallsMutex.Lock()
+	defer recommendCallsMutex.Unlock()
+	return recommendCalls[workload]
+}
+
+type FakeRecommender struct{}
+
+func (r *FakeRecommender) Recommend(workloadSpec ottoscaleriov1alpha1.WorkloadSpec) (
+	*reco.Recommendation, error) {
+	recommendCallsMutex.Lock()
+	recommendCalls[workloadSpec.Name]++
+	recommendCallsMutex.Unlock()
+
+	switch workloadSpec.Name {
+	case failingWorkloadName:
+		return nil, &reco.RecommendationError{Kind: reco.MetricsUnavailable, Err: errors.New("prometheus is down")}
+	case unsupportedWorkloadName:
+		return nil, &reco.RecommendationError{Kind: reco.UnsupportedWorkloadKind,
+			Err: errors.New("unsupported objectKind: StatefulSet")}
+	case deletedWorkloadName:
+		return nil, &reco.RecommendationError{Kind: reco.WorkloadNotFound,
+			Err: errors.New("deployments.apps \"test-deleted-reco\" not found")}
+	}
+	return &reco.Recommendation{
+		HPAConfiguration: ottoscaleriov1alpha1.HPAConfiguration{Min: 10, Max: 60, TargetMetricValue: 50},
+		Explanation: &reco.Explanation{Metrics: []reco.MetricExplanation{{
+			Metric:     "cpu",
+			Target:     50,
+			Candidates: []reco.CandidateTarget{{Target: 50, Accepted: true, MinReplicas: 10, MaxReplicas: 60}},
+		}}},
+	}, nil
+}
+
+type FakeAlternateRecommender struct{}
+
+// Recommend raises a warning along with the recommendation, as if the max replicas was capped by the quota.
+func (r *FakeAlternateRecommender) Recommend(workloadSpec ottoscaleriov1alpha1.WorkloadSpec) (
+	*reco.Recommendation, error) {
+	return &reco.Recommendation{
+		HPAConfiguration: ottoscaleriov1alpha1.HPAConfiguration{Min: 2, Max: 8, TargetMetricValue: 70},
+		Warnings: []reco.Warning{{Reason: reco.MaxCappedByQuota,
+			Message: "max replicas capped from 12 to 8 by the cpu quota of namespace default"}},
+		BreachBudget: &ottoscaleriov1alpha1.BreachBudgetUsage{
+			Allowed:  metav1.Duration{Duration: 40 * time.Minute},
+			Consumed: metav1.Duration{Duration: 5 * time.Minute},
+		},
+		Forecast: &reco.Forecast{Mode: reco.ForecastOnly, Horizon: 7 * 24 * time.Hour, TrendPerDay: 0.5,
+			HistoryPeak: 10, ForecastPeak: 13.5},
+		Explanation: &reco.Explanation{Metrics: []reco.MetricExplanation{{
+			Metric: "cpu",
+			Target: 70,
+			Forecast: &reco.ForecastSeries{Mode: reco.ForecastOnly, Step: metav1.Duration{Duration: time.Hour},
+				Points: []reco.ForecastPoint{{Timestamp: metav1.Now(), Value: 13.5}}},
+		}}},
+	}, nil
+}
+
+type FakePromoter struct{}
+
+// GetPromotedPolicy promotes only the workloads that are on the "promotable" policy.
+func (p *FakePromoter) GetPromotedPolicy(policyRecommendation *ottoscaleriov1alpha1.PolicyRecommendation,
+	optimal *ottoscaleriov1alpha1.HPAConfiguration) (*ottoscaleriov1alpha1.Policy, error) {
+	if policyRecommendation.Spec.Policy.Spec.ID != "promotable" {
+		return nil, nil
+	}
+	return &ottoscaleriov1alpha1.Policy{Spec: ottoscaleriov1alpha1.PolicySpec{ID: "nextSafestPolicy",
+		Min: 2, TargetUtilization: 45}}, nil
+}
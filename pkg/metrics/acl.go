@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/common/model"
+	"math"
+	"sort"
+	"time"
+)
+
+// AutoscalingCycleLag is the time it takes a workload to get a new pod serving after its load went up, broken down
+// into the parts of the autoscaling cycle.
+type AutoscalingCycleLag struct {
+	// MetricIngestion is the time it takes the metrics of the pods to reach the metrics source.
+	MetricIngestion time.Duration
+	// MetricProbe is the time it takes the HPA to read the metrics and scale the workload.
+	MetricProbe time.Duration
+	// Scheduling is the time from a pod being created to it being scheduled on a node.
+	Scheduling time.Duration
+	// ImagePull is the time from a pod being scheduled to all its containers having started, which covers pulling
+	// the images and running the init containers.
+	ImagePull time.Duration
+	// Readiness is the time from the containers of a pod having started to the pod being ready.
+	Readiness time.Duration
+}
+
+// Total returns the sum of the parts of the AutoscalingCycleLag.
+func (a AutoscalingCycleLag) Total() time.Duration {
+	return a.MetricIngestion + a.MetricProbe + a.Scheduling + a.ImagePull + a.Readiness
+}
+
+// podStartupLags are the parts of the startup of the pods of a workload, in seconds, one per pod.
+type podStartupLags struct {
+	scheduling []float64
+	imagePull  []float64
+	readiness  []float64
+}
+
+// newPodStartupLags breaks the startup of every pod down from the timestamps of its lifecycle, keyed by pod. A pod
+// needs to have been created and to be ready to be accounted for. The time a pod is scheduled and the time its
+// containers started are optional, and a missing one leaves its part of the startup to the next part.
+func newPodStartupLags(created, scheduled, started, ready map[string]float64) podStartupLags {
+	pods := make([]string, 0, len(ready))
+	for pod := range ready {
+		pods = append(pods, pod)
+	}
+	sort.Strings(pods)
+
+	lags := podStartupLags{}
+	for _, pod := range pods {
+		createdAt, ok := created[pod]
+		readyAt := ready[pod]
+		if !ok || readyAt < createdAt {
+			continue
+		}
+		scheduledAt, ok := scheduled[pod]
+		if !ok || scheduledAt < createdAt || scheduledAt > readyAt {
+			scheduledAt = createdAt
+		}
+		startedAt, ok := started[pod]
+		if !ok || startedAt < scheduledAt || startedAt > readyAt {
+			startedAt = scheduledAt
+		}
+		lags.scheduling = append(lags.scheduling, scheduledAt-createdAt)
+		lags.imagePull = append(lags.imagePull, startedAt-scheduledAt)
+		lags.readiness = append(lags.readiness, readyAt-startedAt)
+	}
+	return lags
+}
+
+// percentile returns the percentile of the values, interpolated linearly between the closest ranks as PromQL's
+// quantile does. The 0th percentile is the smallest value.
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := math.Max(0, math.Min(p, 100)) / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// getPodTimestampsByWorkload returns the latest value of the timestamp metric of every pod of the workload seen in the
+// ACL window, keyed by pod.
+func (ps *PrometheusScraper) getPodTimestampsByWorkload(timestampMetric string,
+	namespace string,
+	workload string) (map[string]float64, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), ps.queryTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf("max(%s * on (namespace,pod) group_left(workload, workload_type)"+
+		"%s) by(namespace, pod)",
+		ps.overACLWindow(fmt.Sprintf("%s{namespace=\"%s\"}", timestampMetric, namespace)),
+		ps.overACLWindow(fmt.Sprintf("%s{namespace=\"%s\", workload=\"%s\", workload_type=\"deployment\"}",
+			ps.metricRegistry.podOwnerMetric, namespace, workload)))
+
+	result, _, err := ps.api.Query(ctx, query, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Prometheus query: %v", err)
+	}
+	if result.Type() != model.ValVector {
+		return nil, fmt.Errorf("unexpected result type: %v", result.Type())
+	}
+
+	timestamps := map[string]float64{}
+	for _, sample := range result.(model.Vector) {
+		timestamps[string(sample.Metric["pod"])] = float64(sample.Value)
+	}
+	return timestamps, nil
+}
+
+// overACLWindow selects the latest value of the series over the ACL window, so that the pods that were replaced
+// within the window are accounted for. Without a window only the current pods are.
+func (ps *PrometheusScraper) overACLWindow(selector string) string {
+	if ps.aclWindow <= 0 {
+		return selector
+	}
+	return fmt.Sprintf("max_over_time(%s[%s])", selector, model.Duration(ps.aclWindow))
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"time"
+)
+
+var _ = Describe("AutoscalingCycleLag", func() {
+	It("should sum its parts", func() {
+		acl := AutoscalingCycleLag{
+			MetricIngestion: 15 * time.Second,
+			MetricProbe:     15 * time.Second,
+			Scheduling:      time.Second,
+			ImagePull:       20 * time.Second,
+			Readiness:       9 * time.Second,
+		}
+		Expect(acl.Total()).To(Equal(time.Minute))
+	})
+
+	It("should interpolate the percentile between the closest ranks", func() {
+		values := []float64{40, 10, 30, 20}
+		Expect(percentile(values, 0)).To(Equal(10.0))
+		Expect(percentile(values, 50)).To(Equal(25.0))
+		Expect(percentile(values, 90)).To(BeNumerically("~", 37, 1e-9))
+		Expect(percentile(values, 100)).To(Equal(40.0))
+		Expect(percentile(nil, 90)).To(Equal(0.0))
+		// The values are left untouched.
+		Expect(values).To(Equal([]float64{40, 10, 30, 20}))
+	})
+
+	It("should break the startup of the pods down", func() {
+		lags := newPodStartupLags(
+			map[string]float64{"pod-1": 100, "pod-2": 100, "pod-3": 100},
+			map[string]float64{"pod-1": 102, "pod-2": 104},
+			map[string]float64{"pod-1": 110},
+			map[string]float64{"pod-1": 130, "pod-2": 120, "pod-3": 150, "pod-4": 150},
+		)
+		// pod-4 has no created time, pod-2 no started time and pod-3 neither a scheduled nor a started time.
+		Expect(lags.scheduling).To(Equal([]float64{2, 4, 0}))
+		Expect(lags.imagePull).To(Equal([]float64{8, 0, 0}))
+		Expect(lags.readiness).To(Equal([]float64{20, 16, 50}))
+	})
+
+	It("should leave out the timestamps out of the order of the startup", func() {
+		lags := newPodStartupLags(
+			map[string]float64{"pod-1": 100, "pod-2": 100},
+			map[string]float64{"pod-1": 90},
+			map[string]float64{"pod-1": 140},
+			map[string]float64{"pod-1": 130, "pod-2": 90},
+		)
+		Expect(lags.scheduling).To(Equal([]float64{0}))
+		Expect(lags.imagePull).To(Equal([]float64{0}))
+		Expect(lags.readiness).To(Equal([]float64{30}))
+	})
+})
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// NormalizeDataPoints puts the data points of a range query on a grid of the step, as the HPA simulation takes every
+// data point for one step. The data points are sorted and deduplicated by timestamp, keeping the last sample of a
+// timestamp, such as the samples repeated at the boundaries of the splits of a range query. The grid starts at the
+// first data point, and the value at every step of the grid is interpolated linearly between the samples around it.
+// When the samples around a step are further apart than maxInterpolatedGap, or than a step if that is longer, the
+// step is marked as a Gap rather than filled in.
+func NormalizeDataPoints(dataPoints []DataPoint, step time.Duration, maxInterpolatedGap time.Duration) []DataPoint {
+	if len(dataPoints) == 0 || step <= 0 {
+		return dataPoints
+	}
+	if maxInterpolatedGap < step {
+		maxInterpolatedGap = step
+	}
+
+	samples := dedupeDataPoints(dataPoints)
+	start := samples[0].Timestamp
+	end := samples[len(samples)-1].Timestamp
+
+	normalized := make([]DataPoint, 0, int(end.Sub(start)/step)+1)
+	// next is the index of the first sample after the step.
+	next := 0
+	for t := start; !t.After(end); t = t.Add(step) {
+		for next < len(samples) && !samples[next].Timestamp.After(t) {
+			next++
+		}
+		previous := samples[next-1]
+		if previous.Timestamp.Equal(t) || next == len(samples) {
+			normalized = append(normalized, DataPoint{Timestamp: t, Value: previous.Value})
+			continue
+		}
+
+		following := samples[next]
+		interval := following.Timestamp.Sub(previous.Timestamp)
+		if interval > maxInterpolatedGap {
+			normalized = append(normalized, DataPoint{Timestamp: t, Gap: true})
+			continue
+		}
+		fraction := float64(t.Sub(previous.Timestamp)) / float64(interval)
+		normalized = append(normalized, DataPoint{Timestamp: t,
+			Value: previous.Value + fraction*(following.Value-previous.Value)})
+	}
+	return normalized
+}
+
+// dedupeDataPoints returns the data points sorted by timestamp, with the last of the data points of a timestamp
+// retained.
+func dedupeDataPoints(dataPoints []DataPoint) []DataPoint {
+	sorted := make([]DataPoint, len(dataPoints))
+	copy(sorted, dataPoints)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp.Before(sorted[j].Timestamp) })
+
+	deduped := sorted[:1]
+	for _, dp := range sorted[1:] {
+		if dp.Timestamp.Equal(deduped[len(deduped)-1].Timestamp) {
+			deduped[len(deduped)-1] = dp
+			continue
+		}
+		deduped = append(deduped, dp)
+	}
+	return deduped
+}
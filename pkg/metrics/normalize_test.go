@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NormalizeDataPoints", func() {
+	start := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	at := func(offset time.Duration, value float64) DataPoint {
+		return DataPoint{Timestamp: start.Add(offset), Value: value}
+	}
+	gap := func(offset time.Duration) DataPoint {
+		return DataPoint{Timestamp: start.Add(offset), Gap: true}
+	}
+
+	It("should leave evenly spaced data points as they are", func() {
+		dataPoints := []DataPoint{
+			at(0, 1), at(time.Minute, 2), at(2*time.Minute, 3), at(3*time.Minute, 4),
+		}
+		Expect(NormalizeDataPoints(dataPoints, time.Minute, 5*time.Minute)).To(Equal(dataPoints))
+	})
+
+	It("should return no data points for no data points", func() {
+		Expect(NormalizeDataPoints(nil, time.Minute, 5*time.Minute)).To(BeEmpty())
+	})
+
+	It("should drop the samples repeated at the boundaries of the splits of a range query", func() {
+		dataPoints := []DataPoint{
+			at(0, 1), at(time.Minute, 2), at(2*time.Minute, 3),
+			// The next split starts where the previous one ended.
+			at(2*time.Minute, 3), at(3*time.Minute, 4), at(4*time.Minute, 5),
+			at(4*time.Minute, 5), at(5*time.Minute, 6),
+		}
+		Expect(NormalizeDataPoints(dataPoints, time.Minute, 5*time.Minute)).To(Equal([]DataPoint{
+			at(0, 1), at(time.Minute, 2), at(2*time.Minute, 3), at(3*time.Minute, 4), at(4*time.Minute, 5),
+			at(5*time.Minute, 6),
+		}))
+	})
+
+	It("should sort the data points and keep the last sample of a timestamp", func() {
+		dataPoints := []DataPoint{
+			at(2*time.Minute, 3), at(0, 1), at(time.Minute, 2), at(time.Minute, 2.5),
+		}
+		Expect(NormalizeDataPoints(dataPoints, time.Minute, 5*time.Minute)).To(Equal([]DataPoint{
+			at(0, 1), at(time.Minute, 2.5), at(2*time.Minute, 3),
+		}))
+		// The input is left untouched.
+		Expect(dataPoints[0]).To(Equal(at(2*time.Minute, 3)))
+	})
+
+	It("should resample jittered samples onto the grid of the step", func() {
+		dataPoints := []DataPoint{
+			at(0, 10), at(70*time.Second, 17), at(110*time.Second, 21), at(3*time.Minute, 30),
+		}
+		normalized := NormalizeDataPoints(dataPoints, time.Minute, 5*time.Minute)
+		Expect(normalized).To(HaveLen(4))
+		Expect(normalized[0]).To(Equal(at(0, 10)))
+		Expect(normalized[1].Timestamp).To(Equal(start.Add(time.Minute)))
+		Expect(normalized[1].Value).To(BeNumerically("~", 16, 1e-9))
+		Expect(normalized[2].Timestamp).To(Equal(start.Add(2 * time.Minute)))
+		Expect(normalized[2].Value).To(BeNumerically("~", 22.285714, 1e-6))
+		Expect(normalized[3]).To(Equal(at(3*time.Minute, 30)))
+	})
+
+	It("should fill the gaps up to the max interpolated gap", func() {
+		dataPoints := []DataPoint{
+			at(0, 10), at(time.Minute, 10),
+			// Three samples are missing.
+			at(5*time.Minute, 50), at(6*time.Minute, 60),
+		}
+		Expect(NormalizeDataPoints(dataPoints, time.Minute, 5*time.Minute)).To(Equal([]DataPoint{
+			at(0, 10), at(time.Minute, 10), at(2*time.Minute, 20), at(3*time.Minute, 30), at(4*time.Minute, 40),
+			at(5*time.Minute, 50), at(6*time.Minute, 60),
+		}))
+	})
+
+	It("should mark the gaps longer than the max interpolated gap", func() {
+		dataPoints := []DataPoint{
+			at(0, 10), at(time.Minute, 10),
+			// A scrape outage of an hour.
+			at(61*time.Minute, 50), at(61*time.Minute, 50), at(62*time.Minute, 60),
+		}
+		normalized := NormalizeDataPoints(dataPoints, time.Minute, 5*time.Minute)
+		Expect(normalized).To(HaveLen(63))
+		Expect(normalized[:2]).To(Equal([]DataPoint{at(0, 10), at(time.Minute, 10)}))
+		for i := 2; i < 61; i++ {
+			Expect(normalized[i]).To(Equal(gap(time.Duration(i) * time.Minute)))
+		}
+		Expect(normalized[61:]).To(Equal([]DataPoint{at(61*time.Minute, 50), at(62*time.Minute, 60)}))
+	})
+
+	It("should mark every missing sample as a gap when no gap is interpolated", func() {
+		dataPoints := []DataPoint{at(0, 10), at(time.Minute, 10), at(3*time.Minute, 30)}
+		Expect(NormalizeDataPoints(dataPoints, time.Minute, 0)).To(Equal([]DataPoint{
+			at(0, 10), at(time.Minute, 10), gap(2 * time.Minute), at(3*time.Minute, 30),
+		}))
+	})
+})
@@ -13,6 +13,9 @@ import (
 type DataPoint struct {
 	Timestamp time.Time
 	Value     float64
+	// Gap marks a step of a scrape outage too long to be interpolated across. The Value of a Gap is meaningless, and
+	// the consumers of the data points skip it.
+	Gap bool
 }
 
 // Scraper is an interface for scraping metrics data.
@@ -32,7 +35,24 @@ type Scraper interface {
 		step time.Duration) ([]DataPoint, error)
 
 	GetACLByWorkload(namespace,
-		workload string) (time.Duration, error)
+		workload string) (AutoscalingCycleLag, error)
+
+	GetMemoryWorkingSetByWorkload(namespace,
+		workload string,
+		start time.Time,
+		end time.Time,
+		step time.Duration) ([]DataPoint, error)
+
+	GetMemoryLimitsPerPodByWorkload(namespace,
+		workload string) (float64, error)
+
+	GetMemoryRequestsPerPodByWorkload(namespace,
+		workload string) (float64, error)
+
+	GetMetricByQuery(query string,
+		start time.Time,
+		end time.Time,
+		step time.Duration) ([]DataPoint, error)
 }
 
 // PrometheusScraper is a Scraper implementation that scrapes metrics data from Prometheus.
@@ -43,27 +63,61 @@ type PrometheusScraper struct {
 	rangeQuerySplitter  *RangeQuerySplitter
 	metricIngestionTime float64
 	metricProbeTime     float64
+	// maxInterpolatedGap is the longest interval between samples that NormalizeDataPoints interpolates across.
+	maxInterpolatedGap time.Duration
+	// aclPercentile is the percentile of the startup of the pods the ACL is computed from. 0 takes the fastest pod.
+	aclPercentile float64
+	// aclWindow is how far back the pods the ACL is computed from are looked for. 0 takes the current pods alone.
+	aclWindow time.Duration
 }
 
 type MetricNameRegistry struct {
-	utilizationMetric     string
-	podOwnerMetric        string
-	resourceLimitMetric   string
-	readyReplicasMetric   string
-	replicaSetOwnerMetric string
-	hpaMaxReplicasMetric  string
-	hpaOwnerInfoMetric    string
-	podCreatedTimeMetric  string
-	podReadyTimeMetric    string
+	utilizationMetric      string
+	podOwnerMetric         string
+	resourceLimitMetric    string
+	readyReplicasMetric    string
+	replicaSetOwnerMetric  string
+	hpaMaxReplicasMetric   string
+	hpaOwnerInfoMetric     string
+	podCreatedTimeMetric   string
+	podReadyTimeMetric     string
+	podScheduledTimeMetric string
+	containerStartedMetric string
+	memoryWorkingSetMetric string
+	memoryLimitMetric      string
+	memoryRequestMetric    string
 }
 
-func (ps *PrometheusScraper) GetACLByWorkload(namespace string, workload string) (time.Duration, error) {
-	podBootStrapTime, err := ps.getPodReadyLatencyByWorkload(namespace, workload)
-	if err != nil {
-		return 0.0, fmt.Errorf("error getting pod bootstrap time: %v", err)
+// GetACLByWorkload returns the AutoscalingCycleLag of the workload. Every part of the startup of the pods is taken at
+// the ACL percentile of the pods seen in the ACL window, so the total is no less than that percentile of the startup
+// of the pods.
+func (ps *PrometheusScraper) GetACLByWorkload(namespace string, workload string) (AutoscalingCycleLag, error) {
+	timestamps := make([]map[string]float64, 0, 4)
+	for _, timestampMetric := range []string{
+		ps.metricRegistry.podCreatedTimeMetric,
+		ps.metricRegistry.podScheduledTimeMetric,
+		ps.metricRegistry.containerStartedMetric,
+		ps.metricRegistry.podReadyTimeMetric,
+	} {
+		podTimestamps, err := ps.getPodTimestampsByWorkload(timestampMetric, namespace, workload)
+		if err != nil {
+			return AutoscalingCycleLag{}, fmt.Errorf("error getting pod bootstrap time: %v", err)
+		}
+		timestamps = append(timestamps, podTimestamps)
+	}
+
+	lags := newPodStartupLags(timestamps[0], timestamps[1], timestamps[2], timestamps[3])
+	if len(lags.readiness) == 0 {
+		return AutoscalingCycleLag{}, fmt.Errorf("error getting pod bootstrap time: no ready pods found for %s/%s",
+			namespace, workload)
 	}
-	totalACL := ps.metricIngestionTime + ps.metricProbeTime + podBootStrapTime
-	return time.Duration(totalACL) * time.Second, nil
+	return AutoscalingCycleLag{
+		MetricIngestion: secondsToDuration(ps.metricIngestionTime),
+		MetricProbe:     secondsToDuration(ps.metricProbeTime),
+		Scheduling:      secondsToDuration(percentile(lags.scheduling, ps.aclPercentile)),
+		ImagePull:       secondsToDuration(percentile(lags.imagePull, ps.aclPercentile)),
+		Readiness:       secondsToDuration(percentile(lags.readiness, ps.aclPercentile)),
+	}, nil
 }
 
 func NewKubePrometheusMetricNameRegistry() *MetricNameRegistry {
@@ -76,16 +130,26 @@ func NewKubePrometheusMetricNameRegistry() *MetricNameRegistry {
 	hpaOwnerInfoMetric := "kube_horizontalpodautoscaler_info"
 	podCreatedTimeMetric := "kube_pod_created"
 	podReadyTimeMetric := "alm_kube_pod_ready_time"
+	podScheduledTimeMetric := "kube_pod_status_scheduled_time"
+	containerStartedMetric := "kube_pod_container_state_started"
+	memoryWorkingSetMetric := "node_namespace_pod_container:container_memory_working_set_bytes"
+	memoryLimitMetric := "cluster:namespace:pod_memory:active:kube_pod_container_resource_limits"
+	memoryRequestMetric := "cluster:namespace:pod_memory:active:kube_pod_container_resource_requests"
 
 	return &MetricNameRegistry{utilizationMetric: cpuUtilizationMetric,
-		podOwnerMetric:        podOwnerMetric,
-		resourceLimitMetric:   resourceLimitMetric,
-		readyReplicasMetric:   readyReplicasMetric,
-		replicaSetOwnerMetric: replicaSetOwnerMetric,
-		hpaMaxReplicasMetric:  hpaMaxReplicasMetric,
-		hpaOwnerInfoMetric:    hpaOwnerInfoMetric,
-		podCreatedTimeMetric:  podCreatedTimeMetric,
-		podReadyTimeMetric:    podReadyTimeMetric,
+		podOwnerMetric:         podOwnerMetric,
+		resourceLimitMetric:    resourceLimitMetric,
+		readyReplicasMetric:    readyReplicasMetric,
+		replicaSetOwnerMetric:  replicaSetOwnerMetric,
+		hpaMaxReplicasMetric:   hpaMaxReplicasMetric,
+		hpaOwnerInfoMetric:     hpaOwnerInfoMetric,
+		podCreatedTimeMetric:   podCreatedTimeMetric,
+		podReadyTimeMetric:     podReadyTimeMetric,
+		podScheduledTimeMetric: podScheduledTimeMetric,
+		containerStartedMetric: containerStartedMetric,
+		memoryWorkingSetMetric: memoryWorkingSetMetric,
+		memoryLimitMetric:      memoryLimitMetric,
+		memoryRequestMetric:    memoryRequestMetric,
 	}
 }
 
@@ -94,8 +158,11 @@ func NewKubePrometheusMetricNameRegistry() *MetricNameRegistry {
 func NewPrometheusScraper(apiURL string,
 	timeout time.Duration,
 	splitInterval time.Duration,
+	maxInterpolatedGap time.Duration,
 	metricIngestionTime float64,
-	metricProbeTime float64) (*PrometheusScraper, error) {
+	metricProbeTime float64,
+	aclPercentile float64,
+	aclWindow time.Duration) (*PrometheusScraper, error) {
 
 	client, err := api.NewClient(api.Config{
 		Address: apiURL,
@@ -110,8 +177,11 @@ func NewPrometheusScraper(apiURL string,
 		metricRegistry:      NewKubePrometheusMetricNameRegistry(),
 		queryTimeout:        timeout,
 		rangeQuerySplitter:  NewRangeQuerySplitter(v1Api, splitInterval),
+		maxInterpolatedGap:  maxInterpolatedGap,
 		metricProbeTime:     metricProbeTime,
-		metricIngestionTime: metricIngestionTime}, nil
+		metricIngestionTime: metricIngestionTime,
+		aclPercentile:       aclPercentile,
+		aclWindow:           aclWindow}, nil
 }
 
 // GetAverageCPUUtilizationByWorkload returns the average CPU utilization for the given workload type and name in the
@@ -122,9 +192,6 @@ func (ps *PrometheusScraper) GetAverageCPUUtilizationByWorkload(namespace string
 	end time.Time,
 	step time.Duration) ([]DataPoint, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), ps.queryTimeout)
-	defer cancel()
-
 	query := fmt.Sprintf("sum(%s"+
 		"{namespace=\"%s\"} * on (namespace,pod) group_left(workload, workload_type)"+
 		"%s{namespace=\"%s\", workload=\"%s\","+
@@ -135,28 +202,7 @@ func (ps *PrometheusScraper) GetAverageCPUUtilizationByWorkload(namespace string
 		namespace,
 		workload)
 
-	result, err := ps.rangeQuerySplitter.QueryRangeByInterval(ctx, query, start, end, step)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute Prometheus query: %v", err)
-	}
-	if result.Type() != model.ValMatrix {
-		return nil, fmt.Errorf("unexpected result type: %v", result.Type())
-	}
-
-	matrix := result.(model.Matrix)
-	if len(matrix) != 1 {
-		return nil, fmt.Errorf("unexpected no of time series: %v", len(matrix))
-	}
-
-	var dataPoints []DataPoint
-	for _, sample := range matrix[0].Values {
-		datapoint := DataPoint{sample.Timestamp.Time(), float64(sample.Value)}
-		if !sample.Timestamp.Time().IsZero() {
-			dataPoints = append(dataPoints, datapoint)
-		}
-	}
-	return dataPoints, nil
+	return ps.queryRangeNormalized(query, start, end, step)
 }
 
 // GetCPUUtilizationBreachDataPoints returns the data points where avg CPU utilization for a workload goes above the
@@ -168,8 +214,6 @@ func (ps *PrometheusScraper) GetCPUUtilizationBreachDataPoints(namespace,
 	start time.Time,
 	end time.Time,
 	step time.Duration) ([]DataPoint, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), ps.queryTimeout)
-	defer cancel()
 
 	query := fmt.Sprintf("(sum(%s{"+
 		"namespace=\"%s\"} * on(namespace,pod) group_left(workload, workload_type) "+
@@ -211,6 +255,64 @@ func (ps *PrometheusScraper) GetCPUUtilizationBreachDataPoints(namespace,
 		workloadType,
 		workload)
 
+	return ps.queryRange(query, start, end, step)
+}
+
+// GetMemoryWorkingSetByWorkload returns the memory working set in bytes, summed over the pods of the given workload in
+// the specified namespace, in the given time range.
+func (ps *PrometheusScraper) GetMemoryWorkingSetByWorkload(namespace string,
+	workload string,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]DataPoint, error) {
+
+	query := fmt.Sprintf("sum(%s"+
+		"{namespace=\"%s\"} * on (namespace,pod) group_left(workload, workload_type)"+
+		"%s{namespace=\"%s\", workload=\"%s\","+
+		" workload_type=\"deployment\"}) by(namespace, workload, workload_type)",
+		ps.metricRegistry.memoryWorkingSetMetric,
+		namespace,
+		ps.metricRegistry.podOwnerMetric,
+		namespace,
+		workload)
+
+	return ps.queryRangeNormalized(query, start, end, step)
+}
+
+// GetMetricByQuery runs a PromQL range query that yields a single time series, like the requests per second of a
+// workload, and returns its data points.
+func (ps *PrometheusScraper) GetMetricByQuery(query string,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]DataPoint, error) {
+
+	return ps.queryRangeNormalized(query, start, end, step)
+}
+
+// queryRangeNormalized runs a range query that yields a single time series, split by the RangeQuerySplitter, and
+// returns its data points normalized to the step.
+func (ps *PrometheusScraper) queryRangeNormalized(query string,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]DataPoint, error) {
+
+	dataPoints, err := ps.queryRange(query, start, end, step)
+	if err != nil {
+		return nil, err
+	}
+	return NormalizeDataPoints(dataPoints, step, ps.maxInterpolatedGap), nil
+}
+
+// queryRange runs a range query that yields a single time series, split by the RangeQuerySplitter, and returns its
+// samples as data points.
+func (ps *PrometheusScraper) queryRange(query string,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]DataPoint, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), ps.queryTimeout)
+	defer cancel()
+
 	result, err := ps.rangeQuerySplitter.QueryRangeByInterval(ctx, query, start, end, step)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute Prometheus query: %v", err)
@@ -218,15 +320,15 @@ func (ps *PrometheusScraper) GetCPUUtilizationBreachDataPoints(namespace,
 	if result.Type() != model.ValMatrix {
 		return nil, fmt.Errorf("unexpected result type: %v", result.Type())
 	}
-	matrix := result.(model.Matrix)
 
+	matrix := result.(model.Matrix)
 	if len(matrix) != 1 {
 		return nil, fmt.Errorf("unexpected no of time series: %v", len(matrix))
 	}
 
 	var dataPoints []DataPoint
 	for _, sample := range matrix[0].Values {
-		datapoint := DataPoint{sample.Timestamp.Time(), float64(sample.Value)}
+		datapoint := DataPoint{Timestamp: sample.Timestamp.Time(), Value: float64(sample.Value)}
 		if !sample.Timestamp.Time().IsZero() {
 			dataPoints = append(dataPoints, datapoint)
 		}
@@ -234,6 +336,50 @@ func (ps *PrometheusScraper) GetCPUUtilizationBreachDataPoints(namespace,
 	return dataPoints, nil
 }
 
+// GetMemoryLimitsPerPodByWorkload returns the sum of the memory limits in bytes of the containers of a pod of the given
+// workload. The largest pod is taken when the pods of the workload differ, as during a rollout.
+func (ps *PrometheusScraper) GetMemoryLimitsPerPodByWorkload(namespace string, workload string) (float64, error) {
+	return ps.getPerPodResourceByWorkload(ps.metricRegistry.memoryLimitMetric, namespace, workload)
+}
+
+// GetMemoryRequestsPerPodByWorkload returns the sum of the memory requests in bytes of the containers of a pod of the
+// given workload. The largest pod is taken when the pods of the workload differ, as during a rollout.
+func (ps *PrometheusScraper) GetMemoryRequestsPerPodByWorkload(namespace string, workload string) (float64, error) {
+	return ps.getPerPodResourceByWorkload(ps.metricRegistry.memoryRequestMetric, namespace, workload)
+}
+
+func (ps *PrometheusScraper) getPerPodResourceByWorkload(resourceMetric string,
+	namespace string,
+	workload string) (float64, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), ps.queryTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf("max(sum(%s"+
+		"{namespace=\"%s\"} * on (namespace,pod) group_left(workload, workload_type)"+
+		"%s{namespace=\"%s\", workload=\"%s\","+
+		" workload_type=\"deployment\"}) by(namespace, pod))",
+		resourceMetric,
+		namespace,
+		ps.metricRegistry.podOwnerMetric,
+		namespace,
+		workload)
+
+	result, _, err := ps.api.Query(ctx, query, time.Now())
+	if err != nil {
+		return 0.0, fmt.Errorf("failed to execute Prometheus query: %v", err)
+	}
+	if result.Type() != model.ValVector {
+		return 0.0, fmt.Errorf("unexpected result type: %v", result.Type())
+	}
+	vector := result.(model.Vector)
+
+	if len(vector) != 1 {
+		return 0.0, fmt.Errorf("unexpected no of time series: %v", len(vector))
+	}
+	return float64(vector[0].Value), nil
+}
+
 // RangeQuerySplitter splits a given queryRange into multiple range queries of width splitInterval. This is done to
 // avoid loading too many samples into P8s memory.
 type RangeQuerySplitter struct {
@@ -303,38 +449,3 @@ func mergeMatrices(matrixA, matrixB model.Matrix) model.Matrix {
 
 	return resultMatrix
 }
-func (ps *PrometheusScraper) getPodReadyLatencyByWorkload(namespace string, workload string) (float64, error) {
-
-	ctx, cancel := context.WithTimeout(context.Background(), ps.queryTimeout)
-	defer cancel()
-
-	query := fmt.Sprintf("min((%s"+
-		"{namespace=\"%s\"} - on (namespace,pod) (%s{namespace=\"%s\"}))  * on (namespace,pod) group_left(workload, workload_type)"+
-		"(%s{namespace=\"%s\", workload=\"%s\","+
-		" workload_type=\"deployment\"}))",
-		ps.metricRegistry.podReadyTimeMetric,
-		namespace,
-		ps.metricRegistry.podCreatedTimeMetric,
-		namespace,
-		ps.metricRegistry.podOwnerMetric,
-		namespace,
-		workload)
-
-	result, _, err := ps.api.Query(ctx, query, time.Now())
-
-	if err != nil {
-		return 0.0, fmt.Errorf("failed to execute Prometheus query: %v", err)
-	}
-	if result.Type() != model.ValVector {
-		return 0.0, fmt.Errorf("unexpected result type: %v", result.Type())
-	}
-	matrix := result.(model.Vector)
-
-	if len(matrix) != 1 {
-		return 0.0, fmt.Errorf("unexpected no of time series: %v", len(matrix))
-	}
-
-	podBootstrapTime := float64(matrix[0].Value)
-
-	return podBootstrapTime, nil
-}
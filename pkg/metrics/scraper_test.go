@@ -107,11 +107,123 @@ var _ = Describe("PrometheusScraper", func() {
 
 			autoscalingLag1, err := scraper.GetACLByWorkload("test-ns-1", "test-workload-1")
 			Expect(err).NotTo(HaveOccurred())
-			Expect(autoscalingLag1).To(Equal(time.Duration(35.0 * time.Second)))
+			Expect(autoscalingLag1.Total()).To(Equal(time.Duration(35.0 * time.Second)))
 
 			autoscalingLag2, err := scraper.GetACLByWorkload("test-ns-2", "test-workload-3")
 			Expect(err).NotTo(HaveOccurred())
-			Expect(autoscalingLag2).To(Equal(time.Duration(55.0 * time.Second)))
+			Expect(autoscalingLag2.Total()).To(Equal(time.Duration(55.0 * time.Second)))
+		})
+
+		It("should break the ACL down at the percentile of the pods", func() {
+			for i, pod := range []string{"test-pod-1", "test-pod-2", "test-pod-3"} {
+				offset := float64(10 * i)
+				podCreatedTimeMetric.WithLabelValues("test-ns-acl", pod).Set(100)
+				podScheduledTimeMetric.WithLabelValues("test-ns-acl", pod).Set(102 + offset)
+				containerStartedMetric.WithLabelValues("test-ns-acl", pod, "app").Set(110 + 2*offset)
+				containerStartedMetric.WithLabelValues("test-ns-acl", pod, "sidecar").Set(105)
+				podReadyTimeMetric.WithLabelValues("test-ns-acl", pod).Set(130 + 3*offset)
+				kubePodOwnerMetric.WithLabelValues("test-ns-acl", pod, "test-workload-1", "deployment").Set(1)
+			}
+			// A pod that isn't ready yet isn't accounted for.
+			podCreatedTimeMetric.WithLabelValues("test-ns-acl", "test-pod-4").Set(100)
+			kubePodOwnerMetric.WithLabelValues("test-ns-acl", "test-pod-4", "test-workload-1", "deployment").Set(1)
+
+			//wait for the metric to be scraped - scraping interval is 1s
+			time.Sleep(2 * time.Second)
+
+			percentileScraper := *scraper
+			percentileScraper.aclPercentile = 50
+			percentileScraper.aclWindow = time.Hour
+
+			autoscalingLag, err := percentileScraper.GetACLByWorkload("test-ns-acl", "test-workload-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(autoscalingLag).To(Equal(AutoscalingCycleLag{
+				MetricIngestion: 15 * time.Second,
+				MetricProbe:     15 * time.Second,
+				Scheduling:      12 * time.Second,
+				ImagePull:       18 * time.Second,
+				Readiness:       30 * time.Second,
+			}))
+			Expect(autoscalingLag.Total()).To(Equal(90 * time.Second))
+
+			_, err = percentileScraper.GetACLByWorkload("test-ns-acl", "test-workload-2")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when querying GetMemoryWorkingSetByWorkload", func() {
+		It("should return the working set summed over the pods of the workload", func() {
+			kubePodOwnerMetric.WithLabelValues("test-ns-mem", "test-pod-1", "test-workload-1", "deployment").Set(1)
+			kubePodOwnerMetric.WithLabelValues("test-ns-mem", "test-pod-2", "test-workload-1", "deployment").Set(1)
+			kubePodOwnerMetric.WithLabelValues("test-ns-mem", "test-pod-3", "test-workload-2", "deployment").Set(1)
+
+			memoryWorkingSetMetric.WithLabelValues("test-ns-mem", "test-pod-1", "test-node-1", "container-1").Set(100)
+			memoryWorkingSetMetric.WithLabelValues("test-ns-mem", "test-pod-1", "test-node-1", "container-2").Set(20)
+			memoryWorkingSetMetric.WithLabelValues("test-ns-mem", "test-pod-2", "test-node-2", "container-1").Set(150)
+			memoryWorkingSetMetric.WithLabelValues("test-ns-mem", "test-pod-3", "test-node-2", "container-1").Set(500)
+
+			start := time.Now()
+			//wait for the metric to be scraped - scraping interval is 1s
+			time.Sleep(2 * time.Second)
+			end := time.Now()
+
+			dataPoints, err := scraper.GetMemoryWorkingSetByWorkload("test-ns-mem", "test-workload-1", start, end,
+				time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dataPoints).ToNot(BeEmpty())
+			Expect(dataPoints[len(dataPoints)-1].Value).To(Equal(270.0))
+		})
+	})
+
+	Context("when querying the memory resources per pod", func() {
+		It("should return the limits and requests of the largest pod of the workload", func() {
+			kubePodOwnerMetric.WithLabelValues("test-ns-mem", "test-pod-4", "test-workload-3", "deployment").Set(1)
+			kubePodOwnerMetric.WithLabelValues("test-ns-mem", "test-pod-5", "test-workload-3", "deployment").Set(1)
+
+			memoryLimitMetric.WithLabelValues("test-ns-mem", "test-pod-4", "test-node-1", "container-1").Set(1024)
+			memoryLimitMetric.WithLabelValues("test-ns-mem", "test-pod-4", "test-node-1", "container-2").Set(256)
+			memoryLimitMetric.WithLabelValues("test-ns-mem", "test-pod-5", "test-node-2", "container-1").Set(1024)
+			memoryRequestMetric.WithLabelValues("test-ns-mem", "test-pod-4", "test-node-1", "container-1").Set(512)
+			memoryRequestMetric.WithLabelValues("test-ns-mem", "test-pod-4", "test-node-1", "container-2").Set(128)
+			memoryRequestMetric.WithLabelValues("test-ns-mem", "test-pod-5", "test-node-2", "container-1").Set(512)
+
+			//wait for the metric to be scraped - scraping interval is 1s
+			time.Sleep(2 * time.Second)
+
+			limits, err := scraper.GetMemoryLimitsPerPodByWorkload("test-ns-mem", "test-workload-3")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(limits).To(Equal(1280.0))
+
+			requests, err := scraper.GetMemoryRequestsPerPodByWorkload("test-ns-mem", "test-workload-3")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requests).To(Equal(640.0))
+
+			_, err = scraper.GetMemoryLimitsPerPodByWorkload("test-ns-mem", "test-workload-missing")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when querying GetMetricByQuery", func() {
+		It("should return the data points of the single series of the query", func() {
+			memoryWorkingSetMetric.WithLabelValues("test-ns-query", "test-pod-1", "test-node-1", "container-1").Set(30)
+			memoryWorkingSetMetric.WithLabelValues("test-ns-query", "test-pod-2", "test-node-1", "container-1").Set(12)
+
+			start := time.Now()
+			//wait for the metric to be scraped - scraping interval is 1s
+			time.Sleep(2 * time.Second)
+			end := time.Now()
+
+			dataPoints, err := scraper.GetMetricByQuery(
+				"sum(node_namespace_pod_container_container_memory_working_set_bytes{namespace=\"test-ns-query\"})",
+				start, end, time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dataPoints).ToNot(BeEmpty())
+			Expect(dataPoints[len(dataPoints)-1].Value).To(Equal(42.0))
+
+			_, err = scraper.GetMetricByQuery(
+				"node_namespace_pod_container_container_memory_working_set_bytes{namespace=\"test-ns-query\"}",
+				start, end, time.Second)
+			Expect(err).To(HaveOccurred())
 		})
 	})
 
@@ -55,6 +55,13 @@ var (
 	podCreatedTimeMetric  *prometheus.GaugeVec
 	podReadyTimeMetric    *prometheus.GaugeVec
 
+	podScheduledTimeMetric *prometheus.GaugeVec
+	containerStartedMetric *prometheus.GaugeVec
+
+	memoryWorkingSetMetric *prometheus.GaugeVec
+	memoryLimitMetric      *prometheus.GaugeVec
+	memoryRequestMetric    *prometheus.GaugeVec
+
 	scraper *PrometheusScraper
 )
 
@@ -78,6 +85,12 @@ var _ = BeforeSuite(func() {
 
 	podCreatedTimeMetric := "kube_pod_created"
 	podReadyTimeMetric := "alm_kube_pod_ready_time"
+	podScheduledTimeMetric := "kube_pod_status_scheduled_time"
+	containerStartedMetric := "kube_pod_container_state_started"
+
+	memoryWorkingSetMetric := "node_namespace_pod_container_container_memory_working_set_bytes"
+	memoryLimitMetric := "cluster_namespace_pod_memory_active_kube_pod_container_resource_limits"
+	memoryRequestMetric := "cluster_namespace_pod_memory_active_kube_pod_container_resource_requests"
 
 	api := v1.NewAPI(client)
 	metricIngestionTime := 15.0
@@ -85,15 +98,20 @@ var _ = BeforeSuite(func() {
 
 	scraper = &PrometheusScraper{api: api,
 		metricRegistry: &MetricNameRegistry{
-			utilizationMetric:     utilizationMetric,
-			podOwnerMetric:        podOwnerMetric,
-			resourceLimitMetric:   resourceLimitMetric,
-			readyReplicasMetric:   readyReplicasMetric,
-			replicaSetOwnerMetric: replicaSetOwnerMetric,
-			hpaMaxReplicasMetric:  hpaMaxReplicasMetric,
-			hpaOwnerInfoMetric:    hpaOwnerInfoMetric,
-			podCreatedTimeMetric:  podCreatedTimeMetric,
-			podReadyTimeMetric:    podReadyTimeMetric,
+			utilizationMetric:      utilizationMetric,
+			podOwnerMetric:         podOwnerMetric,
+			resourceLimitMetric:    resourceLimitMetric,
+			readyReplicasMetric:    readyReplicasMetric,
+			replicaSetOwnerMetric:  replicaSetOwnerMetric,
+			hpaMaxReplicasMetric:   hpaMaxReplicasMetric,
+			hpaOwnerInfoMetric:     hpaOwnerInfoMetric,
+			podCreatedTimeMetric:   podCreatedTimeMetric,
+			podReadyTimeMetric:     podReadyTimeMetric,
+			podScheduledTimeMetric: podScheduledTimeMetric,
+			containerStartedMetric: containerStartedMetric,
+			memoryWorkingSetMetric: memoryWorkingSetMetric,
+			memoryLimitMetric:      memoryLimitMetric,
+			memoryRequestMetric:    memoryRequestMetric,
 		},
 		queryTimeout:        30 * time.Second,
 		rangeQuerySplitter:  NewRangeQuerySplitter(api, 1*time.Second),
@@ -176,6 +194,31 @@ func registerMetrics() {
 		Help: "Test metric pod ready",
 	}, []string{"namespace", "pod"})
 
+	podScheduledTimeMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kube_pod_status_scheduled_time",
+		Help: "Test metric pod scheduled",
+	}, []string{"namespace", "pod"})
+
+	containerStartedMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kube_pod_container_state_started",
+		Help: "Test metric container started",
+	}, []string{"namespace", "pod", "container"})
+
+	memoryWorkingSetMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "node_namespace_pod_container_container_memory_working_set_bytes",
+		Help: "Test metric for container memory working set",
+	}, []string{"namespace", "pod", "node", "container"})
+
+	memoryLimitMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cluster_namespace_pod_memory_active_kube_pod_container_resource_limits",
+		Help: "Test metric for container memory limits",
+	}, []string{"namespace", "pod", "node", "container"})
+
+	memoryRequestMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cluster_namespace_pod_memory_active_kube_pod_container_resource_requests",
+		Help: "Test metric for container memory requests",
+	}, []string{"namespace", "pod", "node", "container"})
+
 	registry.MustRegister(cpuUsageMetric)
 	registry.MustRegister(kubePodOwnerMetric)
 	registry.MustRegister(resourceLimitMetric)
@@ -185,4 +228,9 @@ func registerMetrics() {
 	registry.MustRegister(hpaOwnerInfoMetric)
 	registry.MustRegister(podCreatedTimeMetric)
 	registry.MustRegister(podReadyTimeMetric)
+	registry.MustRegister(podScheduledTimeMetric)
+	registry.MustRegister(containerStartedMetric)
+	registry.MustRegister(memoryWorkingSetMetric)
+	registry.MustRegister(memoryLimitMetric)
+	registry.MustRegister(memoryRequestMetric)
 }
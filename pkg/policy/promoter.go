@@ -0,0 +1,107 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	"github.com/go-logr/logr"
+	"time"
+)
+
+// Promoter decides when a workload can be moved up the policy ladder to the next riskier policy.
+type Promoter interface {
+	// GetPromotedPolicy returns the policy the workload should be promoted to, or nil if the workload should stay on
+	// its current policy. optimal is the data driven HPAConfiguration generated by the Recommender for the workload.
+	GetPromotedPolicy(policyRecommendation *v1alpha1.PolicyRecommendation,
+		optimal *v1alpha1.HPAConfiguration) (*v1alpha1.Policy, error)
+}
+
+// TimeBasedPromoter promotes a workload to the next riskier policy once it has spent soakPeriod on its current policy
+// without any breaches. Promotions stop once the current policy reaches the optimal target utilization generated by
+// the Recommender. The target utilization of a policy and the breaches of the soak period are those of the cpu, so
+// the workloads whose HPA doesn't scale on the cpu, like the memory and the custom metric ones, stay on their policy.
+type TimeBasedPromoter struct {
+	store      Store
+	scraper    metrics.Scraper
+	soakPeriod time.Duration
+	cpuRedLine float64
+	metricStep time.Duration
+	logger     logr.Logger
+}
+
+func NewTimeBasedPromoter(store Store,
+	scraper metrics.Scraper,
+	soakPeriod time.Duration,
+	cpuRedLine float64,
+	metricStep time.Duration,
+	logger logr.Logger) *TimeBasedPromoter {
+	return &TimeBasedPromoter{
+		store:      store,
+		scraper:    scraper,
+		soakPeriod: soakPeriod,
+		cpuRedLine: cpuRedLine,
+		metricStep: metricStep,
+		logger:     logger,
+	}
+}
+
+func (p *TimeBasedPromoter) GetPromotedPolicy(policyRecommendation *v1alpha1.PolicyRecommendation,
+	optimal *v1alpha1.HPAConfiguration) (*v1alpha1.Policy, error) {
+
+	currentPolicy := policyRecommendation.Spec.Policy
+	if optimal == nil || optimal.TargetMetricValue == 0 {
+		// The HPA doesn't scale on the cpu utilization, which the policy ladder is made of.
+		return nil, nil
+	}
+	if currentPolicy.Spec.TargetUtilization >= optimal.TargetMetricValue {
+		// The current policy has already reached the data driven optimum.
+		return nil, nil
+	}
+
+	soakStart := soakStartTime(policyRecommendation)
+	end := time.Now()
+	if end.Sub(soakStart) < p.soakPeriod {
+		return nil, nil
+	}
+
+	workloadSpec := policyRecommendation.Spec.WorkloadSpec
+	breaches, err := p.scraper.GetCPUUtilizationBreachDataPoints(workloadSpec.Namespace,
+		workloadSpec.Kind,
+		workloadSpec.Name,
+		p.cpuRedLine,
+		soakStart,
+		end,
+		p.metricStep)
+	if err != nil {
+		return nil, fmt.Errorf("error while checking breaches during the soak period: %v", err)
+	}
+	if len(breaches) > 0 {
+		p.logger.Info("Breaches detected during the soak period. Skipping promotion.",
+			"workload", workloadSpec.Name, "namespace", workloadSpec.Namespace, "breaches", len(breaches))
+		return nil, nil
+	}
+
+	nextPolicy, err := p.store.GetNextPolicy(&currentPolicy)
+	if errors.Is(err, ErrNoNextPolicy) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nextPolicy, nil
+}
+
+// soakStartTime returns the time from which the workload has been soaking on its current policy. It is the later of
+// the time the current policy was assigned and the time the last breach was detected.
+func soakStartTime(policyRecommendation *v1alpha1.PolicyRecommendation) time.Time {
+	soakStart := policyRecommendation.CreationTimestamp.Time
+	transitions := policyRecommendation.Status.PolicyTransitions
+	if len(transitions) > 0 {
+		soakStart = transitions[len(transitions)-1].TransitionTime.Time
+	}
+	if lastBreach := policyRecommendation.Status.LastBreach; lastBreach != nil && lastBreach.DetectedAt.After(soakStart) {
+		soakStart = lastBreach.DetectedAt.Time
+	}
+	return soakStart
+}
@@ -0,0 +1,178 @@
+package policy
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"time"
+)
+
+type FakeStore struct {
+	policies []v1alpha1.Policy
+}
+
+func (fs *FakeStore) GetSafestPolicy() (*v1alpha1.Policy, error) {
+	return &fs.policies[0], nil
+}
+
+func (fs *FakeStore) GetNextPolicy(currentPolicy *v1alpha1.Policy) (*v1alpha1.Policy, error) {
+	for i, policy := range fs.policies {
+		if policy.Spec.RiskIndex == currentPolicy.Spec.RiskIndex && i+1 < len(fs.policies) {
+			return &fs.policies[i+1], nil
+		}
+	}
+	return nil, ErrNoNextPolicy
+}
+
+func (fs *FakeStore) GetPreviousPolicy(currentPolicy *v1alpha1.Policy) (*v1alpha1.Policy, error) {
+	for i, policy := range fs.policies {
+		if policy.Spec.RiskIndex == currentPolicy.Spec.RiskIndex && i > 0 {
+			return &fs.policies[i-1], nil
+		}
+	}
+	return nil, ErrNoPreviousPolicy
+}
+
+type FakeScraper struct {
+	breaches []metrics.DataPoint
+}
+
+func (fs *FakeScraper) GetAverageCPUUtilizationByWorkload(namespace,
+	workload string,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]metrics.DataPoint, error) {
+	return []metrics.DataPoint{}, nil
+}
+
+func (fs *FakeScraper) GetCPUUtilizationBreachDataPoints(namespace,
+	workloadType,
+	workload string,
+	redLineUtilization float64,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]metrics.DataPoint, error) {
+	return fs.breaches, nil
+}
+
+func (fs *FakeScraper) GetACLByWorkload(namespace,
+	workload string) (metrics.AutoscalingCycleLag, error) {
+	return metrics.AutoscalingCycleLag{Readiness: 5 * time.Minute}, nil
+}
+
+func (fs *FakeScraper) GetMemoryWorkingSetByWorkload(namespace,
+	workload string,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]metrics.DataPoint, error) {
+	return []metrics.DataPoint{}, nil
+}
+
+func (fs *FakeScraper) GetMemoryLimitsPerPodByWorkload(namespace,
+	workload string) (float64, error) {
+	return 0.0, nil
+}
+
+func (fs *FakeScraper) GetMemoryRequestsPerPodByWorkload(namespace,
+	workload string) (float64, error) {
+	return 0.0, nil
+}
+
+func (fs *FakeScraper) GetMetricByQuery(query string,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]metrics.DataPoint, error) {
+	return []metrics.DataPoint{}, nil
+}
+
+var _ = Describe("TimeBasedPromoter", func() {
+	var (
+		fakeStore            *FakeStore
+		fakeScraper          *FakeScraper
+		promoter             *TimeBasedPromoter
+		policyRecommendation *v1alpha1.PolicyRecommendation
+		optimal              *v1alpha1.HPAConfiguration
+	)
+
+	BeforeEach(func() {
+		fakeStore = &FakeStore{policies: []v1alpha1.Policy{
+			{Spec: v1alpha1.PolicySpec{ID: "safe", RiskIndex: "1", Min: 4, TargetUtilization: 30}},
+			{Spec: v1alpha1.PolicySpec{ID: "moderate", RiskIndex: "2", Min: 2, TargetUtilization: 50}},
+			{Spec: v1alpha1.PolicySpec{ID: "risky", RiskIndex: "3", Min: 1, TargetUtilization: 70}},
+		}}
+		fakeScraper = &FakeScraper{}
+		promoter = NewTimeBasedPromoter(fakeStore, fakeScraper, 24*time.Hour, 0.85, 30*time.Second,
+			zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
+
+		policyRecommendation = &v1alpha1.PolicyRecommendation{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "test-workload",
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-48 * time.Hour)),
+			},
+			Spec: v1alpha1.PolicyRecommendationSpec{
+				Policy: fakeStore.policies[0],
+			},
+		}
+		optimal = &v1alpha1.HPAConfiguration{Min: 3, Max: 20, TargetMetricValue: 60}
+	})
+
+	It("should promote to the next policy after a breach free soak period", func() {
+		nextPolicy, err := promoter.GetPromotedPolicy(policyRecommendation, optimal)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nextPolicy).NotTo(BeNil())
+		Expect(nextPolicy.Spec.ID).To(Equal("moderate"))
+	})
+
+	It("should not promote before the soak period has elapsed since the last transition", func() {
+		policyRecommendation.Status.PolicyTransitions = []v1alpha1.PolicyTransition{
+			{Type: v1alpha1.PolicyPromoted, ToPolicy: "safe", TransitionTime: metav1.NewTime(time.Now().Add(-time.Hour))},
+		}
+		nextPolicy, err := promoter.GetPromotedPolicy(policyRecommendation, optimal)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nextPolicy).To(BeNil())
+	})
+
+	It("should not promote before the soak period has elapsed since the last breach", func() {
+		policyRecommendation.Status.LastBreach = &v1alpha1.BreachEvidence{
+			DetectedAt: metav1.NewTime(time.Now().Add(-time.Hour)), PeakUtilizationPercent: 92}
+		nextPolicy, err := promoter.GetPromotedPolicy(policyRecommendation, optimal)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nextPolicy).To(BeNil())
+	})
+
+	It("should not promote when breaches occurred during the soak period", func() {
+		fakeScraper.breaches = []metrics.DataPoint{{Timestamp: time.Now(), Value: 1.2}}
+		nextPolicy, err := promoter.GetPromotedPolicy(policyRecommendation, optimal)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nextPolicy).To(BeNil())
+	})
+
+	It("should stop once the current policy reaches the optimal target", func() {
+		policyRecommendation.Spec.Policy = fakeStore.policies[2]
+		nextPolicy, err := promoter.GetPromotedPolicy(policyRecommendation, optimal)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nextPolicy).To(BeNil())
+	})
+
+	It("should not promote a workload whose HPA doesn't scale on the cpu", func() {
+		optimal = &v1alpha1.HPAConfiguration{Min: 3, Max: 20, Metrics: []v1alpha1.MetricTarget{
+			{Name: "memory", Type: autoscalingv2.ResourceMetricSourceType, TargetValue: 104},
+		}}
+		nextPolicy, err := promoter.GetPromotedPolicy(policyRecommendation, optimal)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nextPolicy).To(BeNil())
+	})
+
+	It("should not promote beyond the riskiest policy", func() {
+		policyRecommendation.Spec.Policy = fakeStore.policies[2]
+		optimal.TargetMetricValue = 90
+		nextPolicy, err := promoter.GetPromotedPolicy(policyRecommendation, optimal)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nextPolicy).To(BeNil())
+	})
+})
@@ -2,6 +2,7 @@ package policy
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
 	"sort"
@@ -9,9 +10,17 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+var (
+	// ErrNoNextPolicy is returned by GetNextPolicy when the current policy is already the riskiest one.
+	ErrNoNextPolicy = errors.New("no next policy found")
+	// ErrNoPreviousPolicy is returned by GetPreviousPolicy when the current policy is already the safest one.
+	ErrNoPreviousPolicy = errors.New("no previous policy found")
+)
+
 type Store interface {
 	GetSafestPolicy() (*v1alpha1.Policy, error)
 	GetNextPolicy(currentPolicy *v1alpha1.Policy) (*v1alpha1.Policy, error)
+	GetPreviousPolicy(currentPolicy *v1alpha1.Policy) (*v1alpha1.Policy, error)
 }
 type PolicyStore struct {
 	k8sClient client.Client
@@ -61,5 +70,28 @@ func (ps *PolicyStore) GetNextPolicy(currentPolicy *v1alpha1.Policy) (*v1alpha1.
 		}
 	}
 
-	return nil, fmt.Errorf("no next policy found")
+	return nil, ErrNoNextPolicy
+}
+
+func (ps *PolicyStore) GetPreviousPolicy(currentPolicy *v1alpha1.Policy) (*v1alpha1.Policy, error) {
+	policies := &v1alpha1.PolicyList{}
+	err := ps.k8sClient.List(context.Background(), policies)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(policies.Items, func(i, j int) bool {
+		return policies.Items[i].Spec.RiskIndex < policies.Items[j].Spec.RiskIndex
+	})
+
+	for i, policy := range policies.Items {
+		if policy.Spec.RiskIndex == currentPolicy.Spec.RiskIndex {
+			if i > 0 {
+				return &policies.Items[i-1], nil
+			}
+			break
+		}
+	}
+
+	return nil, ErrNoPreviousPolicy
 }
@@ -56,5 +56,16 @@ var _ = Describe("PolicyStore", func() {
 		nextPolicy, err = store.GetNextPolicy(&policies[1])
 		Expect(err).To(HaveOccurred())
 		Expect(nextPolicy).To(BeNil())
+
+		By("getting the previous policy")
+		previousPolicy, err := store.GetPreviousPolicy(&policies[1])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(previousPolicy).NotTo(BeNil())
+		Expect(previousPolicy.Name).To(Equal("policy1"))
+
+		By("getting the previous policy when there is no previous policy")
+		previousPolicy, err = store.GetPreviousPolicy(&policies[0])
+		Expect(err).To(Equal(ErrNoPreviousPolicy))
+		Expect(previousPolicy).To(BeNil())
 	})
 })
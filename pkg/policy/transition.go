@@ -0,0 +1,18 @@
+package policy
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+)
+
+// maxPolicyTransitions is the number of policy transitions retained in the PolicyRecommendation status.
+const maxPolicyTransitions = 10
+
+// AppendTransition appends a transition to the list while retaining only the latest maxPolicyTransitions.
+func AppendTransition(transitions []v1alpha1.PolicyTransition,
+	transition v1alpha1.PolicyTransition) []v1alpha1.PolicyTransition {
+	transitions = append(transitions, transition)
+	if len(transitions) > maxPolicyTransitions {
+		transitions = transitions[len(transitions)-maxPolicyTransitions:]
+	}
+	return transitions
+}
@@ -0,0 +1,75 @@
+package reco
+
+import (
+	"context"
+	rolloutv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+// AutoscalingCycleLagAnnotation on a workload overrides the autoscaling cycle lag measured from its pods with a
+// duration, e.g. "90s", for teams that know the startup profile of their workload better.
+const AutoscalingCycleLagAnnotation = "ottoscalr.io/autoscaling-cycle-lag"
+
+// getAutoscalingCycleLag returns the autoscaling cycle lag to simulate the workload with. It is the lag of the
+// autoscaling cycle lag annotation of the workload if there is one, and the lag measured by the scraper otherwise.
+func getAutoscalingCycleLag(k8sClient client.Client,
+	scraper metrics.Scraper,
+	workloadSpec v1alpha1.WorkloadSpec) (*v1alpha1.AutoscalingCycleLag, error) {
+
+	workload, err := GetWorkload(k8sClient, workloadSpec)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if workload != nil {
+		if annotation := workload.GetAnnotations()[AutoscalingCycleLagAnnotation]; annotation != "" {
+			acl, err := time.ParseDuration(annotation)
+			if err != nil || acl <= 0 {
+				return nil, newRecommendationError(InvalidAnnotation,
+					"invalid %s annotation %q, expected a positive duration", AutoscalingCycleLagAnnotation, annotation)
+			}
+			return &v1alpha1.AutoscalingCycleLag{Total: metav1.Duration{Duration: acl}, Overridden: true}, nil
+		}
+	}
+
+	acl, err := scraper.GetACLByWorkload(workloadSpec.Namespace, workloadSpec.Name)
+	if err != nil {
+		return nil, &RecommendationError{Kind: MetricsUnavailable, Err: err}
+	}
+	return &v1alpha1.AutoscalingCycleLag{
+		Total:           metav1.Duration{Duration: acl.Total()},
+		MetricIngestion: &metav1.Duration{Duration: acl.MetricIngestion},
+		MetricProbe:     &metav1.Duration{Duration: acl.MetricProbe},
+		Scheduling:      &metav1.Duration{Duration: acl.Scheduling},
+		ImagePull:       &metav1.Duration{Duration: acl.ImagePull},
+		Readiness:       &metav1.Duration{Duration: acl.Readiness},
+	}, nil
+}
+
+// GetWorkload returns the Deployment or the Rollout of the workload spec. A workload that doesn't exist is reported
+// as a WorkloadNotFound RecommendationError, and a workload of any other kind as an UnsupportedWorkloadKind one.
+func GetWorkload(k8sClient client.Client, workloadSpec v1alpha1.WorkloadSpec) (client.Object, error) {
+	var workload client.Object
+	switch workloadSpec.Kind {
+	case "Deployment":
+		workload = &appsv1.Deployment{}
+	case "Rollout":
+		workload = &rolloutv1alpha1.Rollout{}
+	default:
+		return nil, newRecommendationError(UnsupportedWorkloadKind, "unsupported objectKind: %s", workloadSpec.Kind)
+	}
+	key := types.NamespacedName{Namespace: workloadSpec.Namespace, Name: workloadSpec.Name}
+	if err := k8sClient.Get(context.Background(), key, workload); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, &RecommendationError{Kind: WorkloadNotFound, Err: err}
+		}
+		return nil, err
+	}
+	return workload, nil
+}
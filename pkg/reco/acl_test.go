@@ -0,0 +1,88 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+var _ = Describe("AutoscalingCycleLag", func() {
+	const namespace = "default"
+
+	var deployment *appsv1.Deployment
+
+	createDeployment := func(name string, annotations map[string]string) v1alpha1.WorkloadSpec {
+		deployment = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Annotations: annotations},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "container-1", Image: "container-image"}},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+		return v1alpha1.WorkloadSpec{
+			Name:      name,
+			Namespace: namespace,
+			TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+		}
+	}
+
+	AfterEach(func() {
+		if deployment != nil {
+			Expect(k8sClient.Delete(ctx, deployment)).To(Succeed())
+			deployment = nil
+		}
+	})
+
+	It("should report the breakdown measured by the scraper", func() {
+		workloadSpec := createDeployment("test-acl-measured", nil)
+
+		acl, err := getAutoscalingCycleLag(k8sClient, fakeScraper, workloadSpec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acl.Overridden).To(BeFalse())
+		Expect(acl.Total.Duration).To(Equal(5 * time.Minute))
+		Expect(acl.Readiness.Duration).To(Equal(5 * time.Minute))
+		Expect(acl.Scheduling.Duration).To(BeZero())
+	})
+
+	It("should take the lag of the annotation of the workload", func() {
+		workloadSpec := createDeployment("test-acl-overridden",
+			map[string]string{AutoscalingCycleLagAnnotation: "90s"})
+
+		acl, err := getAutoscalingCycleLag(k8sClient, fakeScraper, workloadSpec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acl).To(Equal(&v1alpha1.AutoscalingCycleLag{
+			Total:      metav1.Duration{Duration: 90 * time.Second},
+			Overridden: true,
+		}))
+	})
+
+	It("should fail on an annotation that isn't a duration", func() {
+		workloadSpec := createDeployment("test-acl-invalid",
+			map[string]string{AutoscalingCycleLagAnnotation: "two minutes"})
+
+		_, err := getAutoscalingCycleLag(k8sClient, fakeScraper, workloadSpec)
+		Expect(err).To(HaveOccurred())
+		Expect(KindOf(err)).To(Equal(InvalidAnnotation))
+		Expect(IsRetryable(err)).To(BeFalse())
+	})
+
+	It("should simulate the workload with the lag of the annotation", func() {
+		workloadSpec := createDeployment("test-acl-recommendation",
+			map[string]string{AutoscalingCycleLagAnnotation: "2m"})
+
+		recommendation, err := newMemoryRecommender(recommenderOptions{}).Recommend(workloadSpec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recommendation.ACL.Total.Duration).To(Equal(2 * time.Minute))
+		Expect(recommendation.ACL.Overridden).To(BeTrue())
+	})
+})
@@ -0,0 +1,92 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+const week = 7 * 24 * time.Hour
+
+// BreachBudget is the under-provisioning the simulated HPA is allowed before a target utilization is rejected. The
+// zero value tolerates no breach at all.
+type BreachBudget struct {
+	// MaxBreachDurationPerWeek is the total duration of breaches tolerated per week of metrics. It is scaled to the
+	// length of the metric window.
+	MaxBreachDurationPerWeek time.Duration
+	// MaxConsecutiveBreachDuration is the longest a single breach is allowed to last. Zero puts no limit on a single
+	// breach other than MaxBreachDurationPerWeek.
+	MaxConsecutiveBreachDuration time.Duration
+	// MagnitudeWeighted makes a breach consume the budget in proportion to how far the demand exceeded the simulated
+	// capacity, so that a deep breach uses up the budget faster than a shallow one.
+	MagnitudeWeighted bool
+}
+
+// newBreachBudgetUsage returns the usage of the breach budget over the data points before any breach. Each data point
+// stands for one metric step, and the gaps in the metrics don't count towards the window.
+func (s *simulator) newBreachBudgetUsage(dataPoints []metrics.DataPoint) v1alpha1.BreachBudgetUsage {
+	window := time.Duration(0)
+	for _, dp := range dataPoints {
+		if !dp.Gap {
+			window += s.metricStep
+		}
+	}
+	return v1alpha1.BreachBudgetUsage{
+		Allowed: metav1.Duration{Duration: time.Duration(
+			float64(s.breachBudget.MaxBreachDurationPerWeek) * float64(window) / float64(week))},
+		MaxConsecutiveBreachDuration: metav1.Duration{Duration: s.breachBudget.MaxConsecutiveBreachDuration},
+	}
+}
+
+// breachMeter measures the breaches of the simulated capacity into a usage of the breach budget, one data point at a
+// time. Each data point stands for one metric step. A data point without a breach, or a gap in the metrics, ends the
+// breach in progress.
+type breachMeter struct {
+	usage             *v1alpha1.BreachBudgetUsage
+	metricStep        time.Duration
+	magnitudeWeighted bool
+	current           time.Duration
+}
+
+// newBreachMeter returns a breachMeter that measures the breaches into the usage.
+func (s *simulator) newBreachMeter(usage *v1alpha1.BreachBudgetUsage) breachMeter {
+	return breachMeter{usage: usage, metricStep: s.metricStep, magnitudeWeighted: s.breachBudget.MagnitudeWeighted}
+}
+
+// measure accounts for the breach, if any, of the capacity by the demand, and tells whether there was one.
+func (m *breachMeter) measure(demand, capacity float64) bool {
+	if demand <= capacity {
+		m.end()
+		return false
+	}
+
+	weight := 1.0
+	if m.magnitudeWeighted && capacity > 0 {
+		weight = demand / capacity
+	}
+	m.usage.Consumed.Duration += time.Duration(float64(m.metricStep) * weight)
+	m.current += m.metricStep
+	if m.current > m.usage.LongestBreach.Duration {
+		m.usage.LongestBreach.Duration = m.current
+	}
+	return true
+}
+
+// end ends the breach in progress.
+func (m *breachMeter) end() {
+	m.current = 0
+}
+
+// isBreachBudgetUsageWithinBudget tells whether the breaches measured by the usage fit in the budget it was measured
+// against.
+func isBreachBudgetUsageWithinBudget(usage v1alpha1.BreachBudgetUsage) bool {
+	if usage.Consumed.Duration > usage.Allowed.Duration {
+		return false
+	}
+	if usage.MaxConsecutiveBreachDuration.Duration > 0 &&
+		usage.LongestBreach.Duration > usage.MaxConsecutiveBreachDuration.Duration {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,159 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"time"
+)
+
+var _ = Describe("BreachBudget", func() {
+	const step = time.Minute
+
+	newRecommender := func(breachBudget BreachBudget) *CpuUtilizationBasedRecommender {
+		return newCpuRecommender(recommenderOptions{step: step, breachBudget: breachBudget})
+	}
+
+	// series returns an hour of data points at one minute step with the given value, and the given breaches
+	// of the simulated capacity, keyed by the index of the data point and valued by the demand.
+	series := func(value float64, breaches map[int]float64) ([]metrics.DataPoint, []metrics.DataPoint) {
+		start := time.Now().Add(-time.Hour)
+		original := make([]metrics.DataPoint, 60)
+		simulated := make([]metrics.DataPoint, 60)
+		for i := range original {
+			timestamp := start.Add(time.Duration(i) * step)
+			original[i] = metrics.DataPoint{Timestamp: timestamp, Value: value}
+			if demand, ok := breaches[i]; ok {
+				original[i].Value = demand
+			}
+			simulated[i] = metrics.DataPoint{Timestamp: timestamp, Value: value}
+		}
+		return original, simulated
+	}
+
+	Context("with a weekly budget", func() {
+		// 168 minutes a week is a minute an hour.
+		budget := BreachBudget{MaxBreachDurationPerWeek: 168 * time.Minute}
+
+		It("should scale the budget to the metric window and tolerate breaches within it", func() {
+			original, simulated := series(10, map[int]float64{30: 11})
+			withinBudget, usage := measureBreaches(&newRecommender(budget).simulator, original, simulated)
+			Expect(withinBudget).To(BeTrue())
+			Expect(usage.Allowed.Duration).To(Equal(time.Minute))
+			Expect(usage.Consumed.Duration).To(Equal(time.Minute))
+			Expect(usage.LongestBreach.Duration).To(Equal(time.Minute))
+		})
+
+		It("should reject breaches beyond the budget", func() {
+			original, simulated := series(10, map[int]float64{10: 11, 40: 11})
+			withinBudget, usage := measureBreaches(&newRecommender(budget).simulator, original, simulated)
+			Expect(withinBudget).To(BeFalse())
+			Expect(usage.Consumed.Duration).To(Equal(2 * time.Minute))
+		})
+
+		It("should consume the budget in proportion to the magnitude of the breach", func() {
+			weighted := budget
+			weighted.MagnitudeWeighted = true
+			original, simulated := series(10, map[int]float64{30: 20})
+			withinBudget, usage := measureBreaches(&newRecommender(weighted).simulator, original, simulated)
+			Expect(withinBudget).To(BeFalse())
+			Expect(usage.Consumed.Duration).To(Equal(2 * time.Minute))
+		})
+	})
+
+	Context("with a limit on consecutive breaches", func() {
+		budget := BreachBudget{
+			MaxBreachDurationPerWeek:     7 * 24 * time.Hour,
+			MaxConsecutiveBreachDuration: 2 * time.Minute,
+		}
+
+		It("should tolerate separate short breaches", func() {
+			original, simulated := series(10, map[int]float64{10: 11, 20: 11, 30: 11})
+			withinBudget, usage := measureBreaches(&newRecommender(budget).simulator, original, simulated)
+			Expect(withinBudget).To(BeTrue())
+			Expect(usage.LongestBreach.Duration).To(Equal(time.Minute))
+		})
+
+		It("should reject a breach lasting longer than the limit", func() {
+			original, simulated := series(10, map[int]float64{10: 11, 11: 11, 12: 11})
+			withinBudget, usage := measureBreaches(&newRecommender(budget).simulator, original, simulated)
+			Expect(withinBudget).To(BeFalse())
+			Expect(usage.LongestBreach.Duration).To(Equal(3 * time.Minute))
+		})
+
+		It("should end a breach at a gap in the metrics", func() {
+			original, simulated := series(10, map[int]float64{10: 11, 11: 11, 13: 11, 14: 11})
+			original[12].Gap = true
+			withinBudget, usage := measureBreaches(&newRecommender(budget).simulator, original, simulated)
+			Expect(withinBudget).To(BeTrue())
+			Expect(usage.LongestBreach.Duration).To(Equal(2 * time.Minute))
+		})
+	})
+
+	It("should leave the gaps in the metrics out of the window and the breaches", func() {
+		budget := BreachBudget{MaxBreachDurationPerWeek: 168 * time.Minute}
+		original, simulated := series(10, map[int]float64{})
+		for i := 0; i < 30; i++ {
+			original[i] = metrics.DataPoint{Timestamp: original[i].Timestamp, Value: 1000, Gap: true}
+		}
+		withinBudget, usage := measureBreaches(&newRecommender(budget).simulator, original, simulated)
+		Expect(withinBudget).To(BeTrue())
+		Expect(usage.Allowed.Duration).To(Equal(30 * time.Second))
+		Expect(usage.Consumed.Duration).To(BeZero())
+	})
+
+	It("should hold the replicas through a gap in the metrics", func() {
+		start := time.Now().Add(-time.Hour)
+		dataPoints := make([]metrics.DataPoint, 10)
+		for i := range dataPoints {
+			dataPoints[i] = metrics.DataPoint{Timestamp: start.Add(time.Duration(i) * step), Value: 4}
+		}
+		for i := 3; i < 6; i++ {
+			dataPoints[i] = metrics.DataPoint{Timestamp: dataPoints[i].Timestamp, Gap: true}
+		}
+
+		simulation, err := newRecommender(BreachBudget{}).simulateHPA(dataPoints, 0, 50, 1, ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.minReplicas).To(Equal(8))
+		Expect(simulation.maxReplicas).To(Equal(8))
+		for i := 3; i < 6; i++ {
+			Expect(simulation.dataPoints[i].Gap).To(BeTrue())
+		}
+	})
+
+	It("should not let a single glitch drag the target down", func() {
+		start := time.Now().Add(-time.Hour)
+		dataPoints := make([]metrics.DataPoint, 60)
+		for i := range dataPoints {
+			dataPoints[i] = metrics.DataPoint{Timestamp: start.Add(time.Duration(i) * step), Value: 40}
+		}
+		dataPoints[30].Value = 80
+
+		zeroTolerance, err := newRecommender(BreachBudget{}).findOptimalTargetUtilization(dataPoints, 5*time.Minute,
+			minTarget, maxTarget, 1, ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+
+		budgeted, err := newRecommender(BreachBudget{
+			MaxBreachDurationPerWeek: 168 * time.Minute,
+		}).findOptimalTargetUtilization(dataPoints, 5*time.Minute, minTarget, maxTarget, 1, ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(budgeted.target).To(BeNumerically(">", zeroTolerance.target))
+		Expect(budgeted.usage.Consumed.Duration).To(Equal(time.Minute))
+	})
+})
+
+// measureBreaches measures the breaches of the simulated capacity by the original demand with the breachMeter the
+// simulation uses, and tells whether they fit in the breach budget.
+func measureBreaches(s *simulator, original, simulated []metrics.DataPoint) (bool, v1alpha1.BreachBudgetUsage) {
+	usage := s.newBreachBudgetUsage(original)
+	meter := s.newBreachMeter(&usage)
+	for i := range original {
+		if original[i].Gap {
+			meter.end()
+			continue
+		}
+		meter.measure(original[i].Value, simulated[i].Value)
+	}
+	return isBreachBudgetUsageWithinBudget(usage), usage
+}
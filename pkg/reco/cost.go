@@ -0,0 +1,229 @@
+package reco
+
+import (
+	"context"
+	rolloutv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"math"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"time"
+)
+
+// costProjectionPeriod is the period the savings of a recommendation are projected over.
+const costProjectionPeriod = 30 * 24 * time.Hour
+
+// currentScaling is how the replicas of a workload are set today, which the cost of the recommended HPA is compared
+// against.
+type currentScaling struct {
+	mode v1alpha1.ScalingMode
+	// replicas is the replicas set on a workload with StaticReplicas.
+	replicas float64
+	// target is the cpu target utilization of the requests of the HPA of a workload with HPAScaling, and bounds are
+	// its min and max replicas.
+	target int
+	bounds ReplicaBounds
+}
+
+// getCurrentScaling returns how the workload is scaled today. The workload is scaled by an HPA if one other than the
+// HPA applied by ottoscalr scales it on its cpu utilization, and runs the replicas set on it otherwise.
+func getCurrentScaling(k8sClient client.Client, workloadSpec v1alpha1.WorkloadSpec) (*currentScaling, error) {
+	hpaList := autoscalingv2.HorizontalPodAutoscalerList{}
+	if err := k8sClient.List(context.Background(), &hpaList, client.InNamespace(workloadSpec.Namespace)); err != nil {
+		return nil, err
+	}
+	for _, hpa := range hpaList.Items {
+		if hpa.Spec.ScaleTargetRef.Kind != workloadSpec.Kind || hpa.Spec.ScaleTargetRef.Name != workloadSpec.Name {
+			continue
+		}
+		// The HPA applied by ottoscalr is the recommended one, not the scaling it is compared against.
+		if owner := metav1.GetControllerOf(&hpa); owner != nil && owner.Kind == "PolicyRecommendation" &&
+			strings.HasPrefix(owner.APIVersion, v1alpha1.GroupVersion.Group+"/") {
+			continue
+		}
+		for _, metric := range hpa.Spec.Metrics {
+			if metric.Type != autoscalingv2.ResourceMetricSourceType || metric.Resource == nil ||
+				metric.Resource.Name != corev1.ResourceCPU || metric.Resource.Target.AverageUtilization == nil {
+				continue
+			}
+			scaling := &currentScaling{
+				mode:   v1alpha1.HPAScaling,
+				target: int(*metric.Resource.Target.AverageUtilization),
+				bounds: ReplicaBounds{Min: 1, Max: int(hpa.Spec.MaxReplicas)},
+			}
+			if hpa.Spec.MinReplicas != nil {
+				scaling.bounds.Min = int(*hpa.Spec.MinReplicas)
+			}
+			return scaling, nil
+		}
+	}
+
+	workload, err := GetWorkload(k8sClient, workloadSpec)
+	if err != nil {
+		return nil, err
+	}
+	var replicas *int32
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		replicas = w.Spec.Replicas
+	case *rolloutv1alpha1.Rollout:
+		replicas = w.Spec.Replicas
+	}
+	// The replicas of a workload default to one.
+	scaling := &currentScaling{mode: v1alpha1.StaticReplicas, replicas: 1}
+	if replicas != nil {
+		scaling.replicas = float64(*replicas)
+	}
+	return scaling, nil
+}
+
+// costModel estimates the cost of the HPAConfigurations of a workload over the demand its HPA was simulated against,
+// compared with the core hours requested under its current scaling.
+type costModel struct {
+	current          v1alpha1.ScalingMode
+	currentCoreHours float64
+	// dataPoints are the steps of the simulation, and coresPerPod the cpu requests of a pod as the scheduler accounts
+	// for them, init containers and overhead included.
+	dataPoints  []metrics.DataPoint
+	step        time.Duration
+	coresPerPod float64
+	// replicas are the replicas the HPA scaled the workload to at every data point at the recommended
+	// HPAConfiguration, when scaling between the bounds of the Recommender.
+	recommended v1alpha1.HPAConfiguration
+	replicas    []float64
+	// simulate returns the replicas of the HPA at another HPAConfiguration over the data points.
+	simulate func(hpaConfiguration v1alpha1.HPAConfiguration) ([]float64, error)
+}
+
+// newCostModel returns the costModel of the workload, whose HPA scaled it to the replicas at the recommended
+// HPAConfiguration over the data points of the cpu metric.
+func (s *simulator) newCostModel(k8sClient client.Client,
+	workloadSpec v1alpha1.WorkloadSpec,
+	cpu *metricRecommendation,
+	recommended v1alpha1.HPAConfiguration,
+	replicas []float64,
+	simulate func(hpaConfiguration v1alpha1.HPAConfiguration) ([]float64, error)) (*costModel, error) {
+
+	current, err := getCurrentScaling(k8sClient, workloadSpec)
+	if err != nil {
+		return nil, err
+	}
+	currentCoreHours, err := s.currentCoreHours(cpu.series.dataPoints, cpu.acl.Total.Duration,
+		cpu.podResources, current)
+	if err != nil {
+		return nil, err
+	}
+	return &costModel{
+		current:          current.mode,
+		currentCoreHours: currentCoreHours,
+		dataPoints:       cpu.series.dataPoints,
+		step:             cpu.series.step,
+		coresPerPod:      cpu.podResources.scheduledRequests,
+		recommended:      recommended,
+		replicas:         replicas,
+		simulate:         simulate,
+	}, nil
+}
+
+// estimate returns the CostEstimate of the HPAConfiguration. The replicas of the recommended HPAConfiguration are
+// reused when the HPAConfiguration has the same targets and its bounds don't hold them back, and the HPA is simulated
+// at the HPAConfiguration otherwise.
+func (m *costModel) estimate(hpaConfiguration v1alpha1.HPAConfiguration) (*v1alpha1.CostEstimate, error) {
+	replicas := m.replicas
+	if !m.scalesLikeRecommended(hpaConfiguration) {
+		var err error
+		if replicas, err = m.simulate(hpaConfiguration); err != nil {
+			return nil, err
+		}
+	}
+	return newCostEstimate(m.current,
+		m.currentCoreHours,
+		coreHours(replicas, m.dataPoints, m.coresPerPod, m.step),
+		time.Duration(len(observedDataPoints(m.dataPoints)))*m.step), nil
+}
+
+// scalesLikeRecommended tells whether an HPA at the HPAConfiguration scales the workload to the same replicas as
+// the one at the recommended HPAConfiguration.
+func (m *costModel) scalesLikeRecommended(hpaConfiguration v1alpha1.HPAConfiguration) bool {
+	if hpaConfiguration.TargetMetricValue != m.recommended.TargetMetricValue ||
+		!equality.Semantic.DeepEqual(hpaConfiguration.Metrics, m.recommended.Metrics) {
+		return false
+	}
+	for _, replicas := range m.replicas {
+		if replicas < float64(hpaConfiguration.Min) || replicas > float64(hpaConfiguration.Max) {
+			return false
+		}
+	}
+	return true
+}
+
+// simulateAtRequestsTarget simulates the HPA at a cpu target utilization of the requests of the containers of a pod.
+// The target of an HPA may be over 100% of the requests, so the HPA is simulated at the full utilization of the cores
+// a pod is scaled at instead, which asks for the same replicas.
+func (s *simulator) simulateAtRequestsTarget(dataPoints []metrics.DataPoint,
+	acl time.Duration,
+	target int,
+	resources podResources,
+	bounds ReplicaBounds) (*hpaSimulation, error) {
+
+	return s.simulateHPA(dataPoints, acl, 100, resources.requests*float64(target)/100, bounds)
+}
+
+// currentCoreHours returns the core hours requested under the current scaling of the workload over the data points.
+func (s *simulator) currentCoreHours(dataPoints []metrics.DataPoint,
+	acl time.Duration,
+	resources podResources,
+	current *currentScaling) (float64, error) {
+
+	if current.mode != v1alpha1.HPAScaling {
+		return float64(len(observedDataPoints(dataPoints))) * current.replicas * resources.scheduledRequests *
+			s.metricStep.Hours(), nil
+	}
+	simulation, err := s.simulateAtRequestsTarget(dataPoints, acl, current.target, resources, current.bounds)
+	if err != nil {
+		return 0, err
+	}
+	return coreHours(simulation.replicas, dataPoints, resources.scheduledRequests, s.metricStep), nil
+}
+
+// coreHours returns the core hours requested by the replicas at the data points that aren't a Gap, each of which
+// stands for a step.
+func coreHours(replicas []float64, dataPoints []metrics.DataPoint, coresPerPod float64, step time.Duration) float64 {
+	total := 0.0
+	for i, dp := range dataPoints {
+		if !dp.Gap {
+			total += replicas[i]
+		}
+	}
+	return total * coresPerPod * step.Hours()
+}
+
+// newCostEstimate returns the CostEstimate of the core hours under the current scaling and under the recommended HPA
+// over the window, and projects the savings over the costProjectionPeriod.
+func newCostEstimate(currentScaling v1alpha1.ScalingMode,
+	currentCoreHours float64,
+	recommendedCoreHours float64,
+	window time.Duration) *v1alpha1.CostEstimate {
+
+	saved := currentCoreHours - recommendedCoreHours
+	estimate := &v1alpha1.CostEstimate{
+		CurrentScaling:       currentScaling,
+		Window:               metav1.Duration{Duration: window},
+		CurrentCoreHours:     *milliQuantity(currentCoreHours),
+		RecommendedCoreHours: *milliQuantity(recommendedCoreHours),
+		SavedCoreHours:       *milliQuantity(saved),
+	}
+	if currentCoreHours > 0 {
+		estimate.SavingsPercentage = int(math.Round(100 * saved / currentCoreHours))
+	}
+	if window > 0 {
+		estimate.ProjectedMonthlySavedCoreHours = *milliQuantity(saved * costProjectionPeriod.Hours() / window.Hours())
+	}
+	return estimate
+}
@@ -0,0 +1,299 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+var _ = Describe("Cost", func() {
+	const step = time.Minute
+
+	newSimulator := func() *simulator {
+		return &simulator{redLineUtil: redLineUtil, metricStep: step, logger: logger}
+	}
+
+	// series returns an hour of data points at one minute step with a demand of two cores.
+	series := func() []metrics.DataPoint {
+		start := time.Now().Add(-time.Hour)
+		dataPoints := make([]metrics.DataPoint, 60)
+		for i := range dataPoints {
+			dataPoints[i] = metrics.DataPoint{Timestamp: start.Add(time.Duration(i) * step), Value: 2}
+		}
+		return dataPoints
+	}
+
+	// newCostModel returns the costModel of an HPA at a target of 50% of a core per pod, with the replicas of its
+	// simulation, and counts the simulations of other HPAConfigurations in simulations.
+	simulations := 0
+	newCostModel := func(dataPoints []metrics.DataPoint, current *currentScaling) *costModel {
+		s := newSimulator()
+		simulation, err := s.simulateHPA(dataPoints, 0, 50, 1, ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+		resources := podResources{requests: 1, scheduledRequests: 1}
+		currentCoreHours, err := s.currentCoreHours(dataPoints, 0, resources, current)
+		Expect(err).NotTo(HaveOccurred())
+		simulations = 0
+		return &costModel{
+			current:          current.mode,
+			currentCoreHours: currentCoreHours,
+			dataPoints:       dataPoints,
+			step:             step,
+			coresPerPod:      1,
+			recommended:      v1alpha1.HPAConfiguration{Min: 4, Max: 4, TargetMetricValue: 50},
+			replicas:         simulation.replicas,
+			simulate: func(hpaConfiguration v1alpha1.HPAConfiguration) ([]float64, error) {
+				simulations++
+				simulation, err := s.simulateAtRequestsTarget(dataPoints, 0, hpaConfiguration.TargetMetricValue,
+					podResources{requests: 1},
+					ReplicaBounds{Min: hpaConfiguration.Min, Max: hpaConfiguration.Max})
+				if err != nil {
+					return nil, err
+				}
+				return simulation.replicas, nil
+			},
+		}
+	}
+
+	Context("estimate", func() {
+		It("should compare the recommended HPA with the static replicas", func() {
+			estimate, err := newCostModel(series(), &currentScaling{mode: v1alpha1.StaticReplicas, replicas: 10}).
+				estimate(v1alpha1.HPAConfiguration{Min: 4, Max: 4, TargetMetricValue: 50})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(simulations).To(BeZero())
+			Expect(estimate.CurrentScaling).To(Equal(v1alpha1.StaticReplicas))
+			Expect(estimate.Window.Duration).To(Equal(time.Hour))
+			// 10 pods of a core for an hour, against the 4 pods that keep 2 cores at 50%.
+			Expect(estimate.CurrentCoreHours.AsApproximateFloat64()).To(BeNumerically("~", 10, 0.001))
+			Expect(estimate.RecommendedCoreHours.AsApproximateFloat64()).To(BeNumerically("~", 4, 0.001))
+			Expect(estimate.SavedCoreHours.AsApproximateFloat64()).To(BeNumerically("~", 6, 0.001))
+			Expect(estimate.SavingsPercentage).To(Equal(60))
+			Expect(estimate.ProjectedMonthlySavedCoreHours.AsApproximateFloat64()).To(BeNumerically("~", 4320, 0.001))
+		})
+
+		It("should simulate the current HPA at a target over the requests", func() {
+			estimate, err := newCostModel(series(),
+				&currentScaling{mode: v1alpha1.HPAScaling, target: 200, bounds: ReplicaBounds{Min: 1, Max: 10}}).
+				estimate(v1alpha1.HPAConfiguration{Min: 4, Max: 4, TargetMetricValue: 50})
+			Expect(err).NotTo(HaveOccurred())
+			// A pod at 200% of its core keeps 2 cores.
+			Expect(estimate.CurrentCoreHours.AsApproximateFloat64()).To(BeNumerically("~", 1, 0.001))
+			Expect(estimate.SavedCoreHours.AsApproximateFloat64()).To(BeNumerically("~", -3, 0.001))
+			Expect(estimate.SavingsPercentage).To(Equal(-300))
+		})
+
+		It("should cost the HPA bounded by the policy rather than the recommended one", func() {
+			model := newCostModel(series(), &currentScaling{mode: v1alpha1.StaticReplicas, replicas: 10})
+
+			// The max headroom leaves the replicas as is.
+			estimate, err := model.estimate(v1alpha1.HPAConfiguration{Min: 4, Max: 6, TargetMetricValue: 50})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(simulations).To(BeZero())
+			Expect(estimate.RecommendedCoreHours.AsApproximateFloat64()).To(BeNumerically("~", 4, 0.001))
+
+			// A min replica floor of the policy holds the replicas up.
+			estimate, err = model.estimate(v1alpha1.HPAConfiguration{Min: 6, Max: 6, TargetMetricValue: 50})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(simulations).To(Equal(1))
+			Expect(estimate.RecommendedCoreHours.AsApproximateFloat64()).To(BeNumerically("~", 6, 0.001))
+
+			// A lower target of the policy asks for more replicas.
+			estimate, err = model.estimate(v1alpha1.HPAConfiguration{Min: 4, Max: 10, TargetMetricValue: 25})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(simulations).To(Equal(2))
+			Expect(estimate.RecommendedCoreHours.AsApproximateFloat64()).To(BeNumerically("~", 8, 0.001))
+		})
+
+		It("should cost the pods at the requests the scheduler accounts for", func() {
+			// The init containers of a pod request half a core more than its containers.
+			resources := podResources{requests: 1, scheduledRequests: 1.5}
+			coreHours, err := newSimulator().currentCoreHours(series(), 0, resources,
+				&currentScaling{mode: v1alpha1.StaticReplicas, replicas: 10})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(coreHours).To(BeNumerically("~", 15, 0.001))
+
+			// The HPA is scaled on the requests of the containers alone.
+			coreHours, err = newSimulator().currentCoreHours(series(), 0, resources,
+				&currentScaling{mode: v1alpha1.HPAScaling, target: 200, bounds: ReplicaBounds{Min: 1, Max: 10}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(coreHours).To(BeNumerically("~", 1.5, 0.001))
+		})
+
+		It("should leave the gaps in the metrics out", func() {
+			dataPoints := series()
+			for i := 0; i < 30; i++ {
+				dataPoints[i].Gap = true
+			}
+			estimate, err := newCostModel(dataPoints, &currentScaling{mode: v1alpha1.StaticReplicas, replicas: 10}).
+				estimate(v1alpha1.HPAConfiguration{Min: 4, Max: 4, TargetMetricValue: 50})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(estimate.Window.Duration).To(Equal(30 * time.Minute))
+			Expect(estimate.CurrentCoreHours.AsApproximateFloat64()).To(BeNumerically("~", 5, 0.001))
+			Expect(estimate.RecommendedCoreHours.AsApproximateFloat64()).To(BeNumerically("~", 2, 0.001))
+		})
+	})
+
+	Context("getCurrentScaling", func() {
+		const namespace = "default"
+
+		var (
+			deployment *appsv1.Deployment
+			hpa        *autoscalingv2.HorizontalPodAutoscaler
+		)
+
+		createDeployment := func(name string, replicas int32) v1alpha1.WorkloadSpec {
+			deployment = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: &replicas,
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "container-1", Image: "container-image"}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+			return v1alpha1.WorkloadSpec{
+				Name:      name,
+				Namespace: namespace,
+				TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			}
+		}
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, deployment)).To(Succeed())
+			if hpa != nil {
+				Expect(k8sClient.Delete(ctx, hpa)).To(Succeed())
+				hpa = nil
+			}
+		})
+
+		It("should take the replicas of a workload without an HPA", func() {
+			workloadSpec := createDeployment("test-cost-static", 6)
+
+			scaling, err := getCurrentScaling(k8sClient, workloadSpec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scaling).To(Equal(&currentScaling{mode: v1alpha1.StaticReplicas, replicas: 6}))
+		})
+
+		It("should leave out the HPA applied by ottoscalr", func() {
+			workloadSpec := createDeployment("test-cost-applied-hpa", 6)
+			averageUtilization := int32(50)
+			controller := true
+			hpa = &autoscalingv2.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-cost-applied-hpa", Namespace: namespace,
+					OwnerReferences: []metav1.OwnerReference{{APIVersion: v1alpha1.GroupVersion.String(),
+						Kind: "PolicyRecommendation", Name: "test-cost-applied-hpa", UID: "test-cost-applied-hpa",
+						Controller: &controller}},
+				},
+				Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment",
+						Name: "test-cost-applied-hpa", APIVersion: "apps/v1"},
+					MaxReplicas: 12,
+					Metrics: []autoscalingv2.MetricSpec{{
+						Type: autoscalingv2.ResourceMetricSourceType,
+						Resource: &autoscalingv2.ResourceMetricSource{
+							Name: corev1.ResourceCPU,
+							Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType,
+								AverageUtilization: &averageUtilization},
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, hpa)).To(Succeed())
+
+			scaling, err := getCurrentScaling(k8sClient, workloadSpec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scaling).To(Equal(&currentScaling{mode: v1alpha1.StaticReplicas, replicas: 6}))
+		})
+
+		It("should take the cpu target and the bounds of the HPA of the workload", func() {
+			workloadSpec := createDeployment("test-cost-hpa", 6)
+			minReplicas := int32(2)
+			averageUtilization := int32(70)
+			hpa = &autoscalingv2.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-cost-hpa", Namespace: namespace},
+				Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment",
+						Name: "test-cost-hpa", APIVersion: "apps/v1"},
+					MinReplicas: &minReplicas,
+					MaxReplicas: 12,
+					Metrics: []autoscalingv2.MetricSpec{{
+						Type: autoscalingv2.ResourceMetricSourceType,
+						Resource: &autoscalingv2.ResourceMetricSource{
+							Name: corev1.ResourceCPU,
+							Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType,
+								AverageUtilization: &averageUtilization},
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, hpa)).To(Succeed())
+
+			Eventually(func() v1alpha1.ScalingMode {
+				scaling, err := getCurrentScaling(k8sClient, workloadSpec)
+				if err != nil {
+					return ""
+				}
+				return scaling.mode
+			}).Should(Equal(v1alpha1.HPAScaling))
+			scaling, err := getCurrentScaling(k8sClient, workloadSpec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scaling).To(Equal(&currentScaling{mode: v1alpha1.HPAScaling, target: 70,
+				bounds: ReplicaBounds{Min: 2, Max: 12}}))
+		})
+	})
+
+	It("should estimate the cost of the recommendation of the cpu recommender", func() {
+		const namespace = "default"
+		replicas := int32(40)
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cost-recommendation", Namespace: namespace},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test-cost-recommendation"}},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test-cost-recommendation"}},
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "container-1",
+						Image: "container-image", Resources: corev1.ResourceRequirements{
+							Limits: corev1.ResourceList{corev1.ResourceCPU: *milliQuantity(8.2)}}}}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+		defer func() {
+			Expect(k8sClient.Delete(ctx, deployment)).To(Succeed())
+		}()
+
+		recommendation, err := recommender.Recommend(v1alpha1.WorkloadSpec{
+			Name:      deployment.Name,
+			Namespace: namespace,
+			TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		cost, err := recommendation.EstimateCost(recommendation.HPAConfiguration)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cost).NotTo(BeNil())
+		Expect(cost.CurrentScaling).To(Equal(v1alpha1.StaticReplicas))
+		Expect(cost.SavedCoreHours.AsApproximateFloat64()).To(BeNumerically(">", 0))
+		Expect(cost.RecommendedCoreHours.AsApproximateFloat64()).To(BeNumerically("<=",
+			float64(recommendation.HPAConfiguration.Max)*8.2*cost.Window.Hours()))
+
+		By("Costing the HPA bounded by the policy")
+		bounded := recommendation.HPAConfiguration
+		bounded.Min = bounded.Max
+		boundedCost, err := recommendation.EstimateCost(bounded)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(boundedCost.RecommendedCoreHours.AsApproximateFloat64()).To(BeNumerically(">",
+			cost.RecommendedCoreHours.AsApproximateFloat64()))
+	})
+})
@@ -0,0 +1,277 @@
+package reco
+
+import (
+	"bytes"
+	"context"
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	"github.com/go-logr/logr"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"text/template"
+	"time"
+)
+
+// The custom metric annotations on a workload override the CustomMetricSpec of its Policy, field by field.
+const (
+	CustomMetricNameAnnotation           = "ottoscalr.io/custom-metric-name"
+	CustomMetricQueryAnnotation          = "ottoscalr.io/custom-metric-query"
+	CustomMetricPerPodCapacityAnnotation = "ottoscalr.io/custom-metric-per-pod-capacity"
+	CustomMetricTypeAnnotation           = "ottoscalr.io/custom-metric-type"
+)
+
+// CustomMetricRecommender generates the HPAConfiguration of workloads that scale on a metric other than the
+// utilization of a resource, like their requests per second or the depth of the queue they consume. The metric is
+// read with the PromQL query template of the CustomMetricSpec, and the HPA is simulated with the pods sized on the
+// per pod capacity of the spec.
+type CustomMetricRecommender struct {
+	simulator
+	k8sClient         client.Client
+	metricWindow      time.Duration
+	scraper           metrics.Scraper
+	minTarget         int
+	maxTarget         int
+	maxHeadroomFactor float64
+	replicaBounds     ReplicaBounds
+}
+
+func NewCustomMetricRecommender(k8sClient client.Client,
+	redLineUtil float64,
+	metricWindow time.Duration,
+	scraper metrics.Scraper,
+	metricStep time.Duration,
+	minTarget int,
+	maxTarget int,
+	maxHeadroomFactor float64,
+	breachBudget BreachBudget,
+	hpaBehavior HPABehavior,
+	replicaBounds ReplicaBounds,
+	forecast ForecastConfig,
+	logger logr.Logger) *CustomMetricRecommender {
+	return &CustomMetricRecommender{
+		simulator: simulator{
+			redLineUtil:  redLineUtil,
+			metricStep:   metricStep,
+			breachBudget: breachBudget,
+			hpaBehavior:  hpaBehavior,
+			forecast:     forecast,
+			logger:       logger,
+		},
+		k8sClient:         k8sClient,
+		metricWindow:      metricWindow,
+		scraper:           scraper,
+		minTarget:         minTarget,
+		maxTarget:         maxTarget,
+		maxHeadroomFactor: maxHeadroomFactor,
+		replicaBounds:     replicaBounds,
+	}
+}
+
+func (c *CustomMetricRecommender) Recommend(workloadSpec v1alpha1.WorkloadSpec) (*Recommendation, error) {
+	customRecommendation, err := c.recommendMetric(workloadSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendation := &Recommendation{
+		Warnings:     customRecommendation.warnings,
+		BreachBudget: &customRecommendation.breachBudget,
+		Forecast:     customRecommendation.forecast,
+		ACL:          customRecommendation.acl,
+		Explanation:  newExplanation(customRecommendation.acl, customRecommendation.explanation),
+		// The time ranges of the metric window left out of the simulation.
+		ExcludedMetricRanges: customRecommendation.excludedRanges,
+	}
+	maxReplicas, warning, err := capMaxReplicasOfWorkloadByQuota(c.k8sClient, workloadSpec,
+		customRecommendation.minReplicas, applyMaxHeadroom(customRecommendation.maxReplicas, c.maxHeadroomFactor))
+	if err != nil {
+		c.logger.Error(err, "Error while capping the max replicas by the namespace quota")
+		return nil, err
+	}
+	if warning != nil {
+		recommendation.Warnings = append(recommendation.Warnings, *warning)
+	}
+
+	recommendation.HPAConfiguration = v1alpha1.HPAConfiguration{
+		Min:     customRecommendation.minReplicas,
+		Max:     maxReplicas,
+		Metrics: []v1alpha1.MetricTarget{customRecommendation.metricTarget()},
+	}
+	return recommendation, nil
+}
+
+// recommendMetric finds the optimal target utilization of the per pod capacity for the workload, and the replicas
+// the simulated HPA scaled between at that target.
+func (c *CustomMetricRecommender) recommendMetric(workloadSpec v1alpha1.WorkloadSpec) (*metricRecommendation,
+	error) {
+
+	spec, err := c.getCustomMetricSpec(workloadSpec)
+	if err != nil {
+		c.logger.Error(err, "Error while getting the custom metric of the workload")
+		return nil, err
+	}
+
+	query, err := renderQuery(spec.QueryTemplate, workloadSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	end := time.Now()
+	start := end.Add(-c.metricWindow)
+
+	dataPoints, err := c.scraper.GetMetricByQuery(query, start, end, c.metricStep)
+	if err != nil {
+		c.logger.Error(err, "Error while scraping GetMetricByQuery.", "query", query)
+		return nil, &RecommendationError{Kind: MetricsUnavailable, Err: err}
+	}
+	if len(dataPoints) == 0 {
+		return nil, newRecommendationError(InsufficientData, "no %s data points found between %s and %s",
+			spec.Name, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+
+	dataPoints, excludedRanges, err := excludeMetricRanges(c.k8sClient, workloadSpec, dataPoints)
+	if err != nil {
+		c.logger.Error(err, "Error while getting the metric exclusions")
+		return nil, err
+	}
+	if len(observedDataPoints(dataPoints)) == 0 {
+		return nil, newRecommendationError(InsufficientData, "all the %s data points between %s and %s are excluded",
+			spec.Name, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+
+	acl, err := getAutoscalingCycleLag(c.k8sClient, c.scraper, workloadSpec)
+	if err != nil {
+		c.logger.Error(err, "Error while getting GetACL.")
+		return nil, err
+	}
+
+	perPodCapacity := spec.PerPodCapacity.AsApproximateFloat64()
+	// The HPA is simulated against the forecast of the demand, if one is configured.
+	demand, forecast := c.forecastDemand(dataPoints)
+
+	search, err := c.findOptimalTargetUtilization(
+		demand,
+		acl.Total.Duration,
+		c.minTarget,
+		c.maxTarget,
+		perPodCapacity,
+		c.replicaBounds)
+	if err != nil {
+		c.logger.Error(err, "Error while executing findOptimalTargetUtilization")
+		return nil, err
+	}
+
+	recommendation := &metricRecommendation{
+		series: metricSeries{
+			name:            spec.Name,
+			step:            c.metricStep,
+			dataPoints:      demand,
+			perPodResources: perPodCapacity,
+			target:          search.target,
+		},
+		metricType:     spec.Type,
+		targetValue:    search.target,
+		averageValue:   averageValueOfTarget(*spec.PerPodCapacity, search.target),
+		minReplicas:    search.minReplicas,
+		maxReplicas:    search.maxReplicas,
+		breachBudget:   search.usage,
+		forecast:       forecast,
+		acl:            acl,
+		excludedRanges: excludedRanges,
+		explanation: newMetricExplanation(spec.Name, c.metricStep, demand, perPodCapacity, search.target,
+			search.candidates, forecast),
+	}
+	if search.warning != nil {
+		recommendation.warnings = append(recommendation.warnings, *search.warning)
+	}
+	return recommendation, nil
+}
+
+// getCustomMetricSpec returns the CustomMetricSpec of the Policy the workload is on, overridden by the custom metric
+// annotations on the workload.
+func (c *CustomMetricRecommender) getCustomMetricSpec(workloadSpec v1alpha1.WorkloadSpec) (*v1alpha1.CustomMetricSpec,
+	error) {
+
+	workload, err := GetWorkload(c.k8sClient, workloadSpec)
+	if err != nil {
+		return nil, err
+	}
+	key := types.NamespacedName{Namespace: workloadSpec.Namespace, Name: workloadSpec.Name}
+
+	spec := v1alpha1.CustomMetricSpec{}
+	// The PolicyRecommendation of a workload shares its name, and holds the Policy it is on.
+	policyRecommendation := &v1alpha1.PolicyRecommendation{}
+	if err := c.k8sClient.Get(context.Background(), key, policyRecommendation); err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	} else if policyRecommendation.Spec.Policy.Spec.CustomMetric != nil {
+		spec = *policyRecommendation.Spec.Policy.Spec.CustomMetric.DeepCopy()
+	}
+
+	annotations := workload.GetAnnotations()
+	if name := annotations[CustomMetricNameAnnotation]; name != "" {
+		spec.Name = name
+	}
+	if query := annotations[CustomMetricQueryAnnotation]; query != "" {
+		spec.QueryTemplate = query
+	}
+	if capacity := annotations[CustomMetricPerPodCapacityAnnotation]; capacity != "" {
+		quantity, err := resource.ParseQuantity(capacity)
+		if err != nil {
+			return nil, newRecommendationError(InvalidMetricConfiguration, "invalid %s annotation %q: %v",
+				CustomMetricPerPodCapacityAnnotation, capacity, err)
+		}
+		spec.PerPodCapacity = &quantity
+	}
+	if metricType := annotations[CustomMetricTypeAnnotation]; metricType != "" {
+		spec.Type = autoscalingv2.MetricSourceType(metricType)
+	}
+	if spec.Type == "" {
+		spec.Type = autoscalingv2.PodsMetricSourceType
+	}
+
+	switch {
+	case spec.Name == "":
+		return nil, newRecommendationError(InvalidMetricConfiguration, "no custom metric name set for %s %s/%s",
+			workloadSpec.Kind, workloadSpec.Namespace, workloadSpec.Name)
+	case spec.QueryTemplate == "":
+		return nil, newRecommendationError(InvalidMetricConfiguration, "no custom metric query set for %s %s/%s",
+			workloadSpec.Kind, workloadSpec.Namespace, workloadSpec.Name)
+	case spec.PerPodCapacity == nil || spec.PerPodCapacity.Sign() <= 0:
+		return nil, newRecommendationError(InvalidMetricConfiguration,
+			"no positive custom metric per pod capacity set for %s %s/%s",
+			workloadSpec.Kind, workloadSpec.Namespace, workloadSpec.Name)
+	case spec.Type != autoscalingv2.PodsMetricSourceType && spec.Type != autoscalingv2.ExternalMetricSourceType:
+		return nil, newRecommendationError(InvalidMetricConfiguration,
+			"unsupported custom metric type %s for %s %s/%s, expected Pods or External",
+			spec.Type, workloadSpec.Kind, workloadSpec.Namespace, workloadSpec.Name)
+	}
+	return &spec, nil
+}
+
+// renderQuery fills the namespace and the name of the workload in the PromQL query template.
+func renderQuery(queryTemplate string, workloadSpec v1alpha1.WorkloadSpec) (string, error) {
+	tmpl, err := template.New("query").Option("missingkey=error").Parse(queryTemplate)
+	if err != nil {
+		return "", newRecommendationError(InvalidMetricConfiguration, "invalid custom metric query template: %v", err)
+	}
+
+	var query bytes.Buffer
+	err = tmpl.Execute(&query, struct {
+		Namespace string
+		Workload  string
+	}{Namespace: workloadSpec.Namespace, Workload: workloadSpec.Name})
+	if err != nil {
+		return "", newRecommendationError(InvalidMetricConfiguration, "invalid custom metric query template: %v", err)
+	}
+	return query.String(), nil
+}
+
+// averageValueOfTarget returns the value of the metric per pod at the target utilization of the per pod capacity.
+func averageValueOfTarget(perPodCapacity resource.Quantity, target int) *resource.Quantity {
+	return resource.NewMilliQuantity(perPodCapacity.MilliValue()*int64(target)/100, perPodCapacity.Format)
+}
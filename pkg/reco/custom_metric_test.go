@@ -0,0 +1,157 @@
+package reco
+
+import (
+	"context"
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+// QueryRecordingScraper records the last query it was asked to run.
+type QueryRecordingScraper struct {
+	FakeScraper
+	query string
+}
+
+func (qs *QueryRecordingScraper) GetMetricByQuery(query string,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]metrics.DataPoint, error) {
+	qs.query = query
+	return qs.FakeScraper.GetMetricByQuery(query, start, end, step)
+}
+
+var _ = Describe("CustomMetricRecommender", func() {
+	const namespace = "default"
+
+	newDeployment := func(name string, annotations map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   namespace,
+				Annotations: annotations,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "container-1", Image: "container-image"}},
+					},
+				},
+			},
+		}
+	}
+
+	newWorkloadSpec := func(name string) v1alpha1.WorkloadSpec {
+		return v1alpha1.WorkloadSpec{
+			Name:      name,
+			Namespace: namespace,
+			TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+		}
+	}
+
+	newRecommender := func(scraper metrics.Scraper) *CustomMetricRecommender {
+		return newCustomMetricRecommender(recommenderOptions{scraper: scraper})
+	}
+
+	It("should recommend the average value of the metric from the annotations on the workload", func() {
+		ctx := context.TODO()
+		deployment := newDeployment("test-custom-metric-annotated", map[string]string{
+			CustomMetricNameAnnotation:           "http_requests_per_second",
+			CustomMetricQueryAnnotation:          `sum(rate(http_requests_total{namespace="{{.Namespace}}", workload="{{.Workload}}"}[1m]))`,
+			CustomMetricPerPodCapacityAnnotation: "8200m",
+		})
+		Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, deployment)).To(Succeed()) }()
+
+		scraper := &QueryRecordingScraper{}
+		recommendation, err := newRecommender(scraper).Recommend(newWorkloadSpec("test-custom-metric-annotated"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scraper.query).To(Equal(
+			`sum(rate(http_requests_total{namespace="default", workload="test-custom-metric-annotated"}[1m]))`))
+
+		// The fake metric is the same as the cpu utilization and the capacity matches the cpu limits of the cpu
+		// recommender tests, so the target and the replicas match.
+		Expect(recommendation.HPAConfiguration.Min).To(Equal(8))
+		Expect(recommendation.HPAConfiguration.Max).To(Equal(24))
+		Expect(recommendation.HPAConfiguration.Metrics).To(HaveLen(1))
+		metricTarget := recommendation.HPAConfiguration.Metrics[0]
+		Expect(metricTarget.Name).To(Equal("http_requests_per_second"))
+		Expect(metricTarget.Type).To(Equal(autoscalingv2.PodsMetricSourceType))
+		Expect(metricTarget.TargetValue).To(Equal(52))
+		Expect(metricTarget.AverageValue.MilliValue()).To(Equal(int64(4264)))
+		Expect(recommendation.BreachBudget).NotTo(BeNil())
+	})
+
+	It("should let the annotations on the workload override the custom metric of its policy", func() {
+		ctx := context.TODO()
+		deployment := newDeployment("test-custom-metric-policy", map[string]string{
+			CustomMetricPerPodCapacityAnnotation: "8200m",
+		})
+		Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, deployment)).To(Succeed()) }()
+
+		capacity := resource.MustParse("100")
+		policyRecommendation := &v1alpha1.PolicyRecommendation{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-custom-metric-policy", Namespace: namespace},
+			Spec: v1alpha1.PolicyRecommendationSpec{
+				WorkloadSpec: newWorkloadSpec("test-custom-metric-policy"),
+				Policy: v1alpha1.Policy{
+					ObjectMeta: metav1.ObjectMeta{Name: "queue-policy"},
+					Spec: v1alpha1.PolicySpec{
+						ID: "queue-policy",
+						CustomMetric: &v1alpha1.CustomMetricSpec{
+							Name:           "queue_depth",
+							QueryTemplate:  `sum(queue_depth{consumer="{{.Workload}}"})`,
+							PerPodCapacity: &capacity,
+							Type:           autoscalingv2.ExternalMetricSourceType,
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, policyRecommendation)).To(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, policyRecommendation)).To(Succeed()) }()
+
+		scraper := &QueryRecordingScraper{}
+		recommendation, err := newRecommender(scraper).Recommend(newWorkloadSpec("test-custom-metric-policy"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scraper.query).To(Equal(`sum(queue_depth{consumer="test-custom-metric-policy"})`))
+
+		metricTarget := recommendation.HPAConfiguration.Metrics[0]
+		Expect(metricTarget.Name).To(Equal("queue_depth"))
+		Expect(metricTarget.Type).To(Equal(autoscalingv2.ExternalMetricSourceType))
+		Expect(metricTarget.AverageValue.MilliValue()).To(Equal(int64(4264)))
+	})
+
+	It("should return InvalidMetricConfiguration when the workload has no per pod capacity", func() {
+		ctx := context.TODO()
+		deployment := newDeployment("test-custom-metric-no-capacity", map[string]string{
+			CustomMetricNameAnnotation:  "http_requests_per_second",
+			CustomMetricQueryAnnotation: `sum(rate(http_requests_total[1m]))`,
+		})
+		Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, deployment)).To(Succeed()) }()
+
+		_, err := newRecommender(fakeScraper).Recommend(newWorkloadSpec("test-custom-metric-no-capacity"))
+		Expect(KindOf(err)).To(Equal(InvalidMetricConfiguration))
+		Expect(IsRetryable(err)).To(BeFalse())
+		Expect(err.Error()).To(ContainSubstring("per pod capacity"))
+	})
+
+	It("should reject a query template that doesn't render", func() {
+		_, err := renderQuery(`sum(up{namespace="{{.Cluster}}"})`, newWorkloadSpec("test-custom-metric-template"))
+		Expect(KindOf(err)).To(Equal(InvalidMetricConfiguration))
+
+		_, err = renderQuery(`sum(up{namespace="{{.Namespace"})`, newWorkloadSpec("test-custom-metric-template"))
+		Expect(KindOf(err)).To(Equal(InvalidMetricConfiguration))
+	})
+})
@@ -0,0 +1,79 @@
+package reco
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorKind classifies the errors returned by a Recommender.
+type ErrorKind string
+
+const (
+	// MetricsUnavailable means the metrics for the workload couldn't be fetched from the metrics source.
+	MetricsUnavailable ErrorKind = "MetricsUnavailable"
+	// InsufficientData means the metrics source returned too little data to generate a recommendation.
+	InsufficientData ErrorKind = "InsufficientData"
+	// WorkloadNotFound means the workload doesn't exist, e.g. it was deleted after it was queued for a recommendation.
+	WorkloadNotFound ErrorKind = "WorkloadNotFound"
+	// UnsupportedWorkloadKind means the workload is of a kind the Recommender doesn't know how to handle.
+	UnsupportedWorkloadKind ErrorKind = "UnsupportedWorkloadKind"
+	// InvalidResources means the resources declared on the pod template of the workload can't be used to generate
+	// a recommendation, e.g. no CPU requests or limits are set.
+	InvalidResources ErrorKind = "InvalidResources"
+	// InvalidMetricConfiguration means the metric the workload asked to be scaled on is missing or malformed, e.g. a
+	// custom metric without a per pod capacity.
+	InvalidMetricConfiguration ErrorKind = "InvalidMetricConfiguration"
+	// InvalidAnnotation means an annotation on the workload that tunes its recommendation is malformed, e.g. an
+	// autoscaling cycle lag that isn't a duration.
+	InvalidAnnotation ErrorKind = "InvalidAnnotation"
+	// UnknownRecommender means the workload asked for a Recommender that isn't registered with the Registry.
+	UnknownRecommender ErrorKind = "UnknownRecommender"
+)
+
+// RecommendationError is returned by a Recommender when it fails to generate a recommendation for a workload.
+type RecommendationError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func newRecommendationError(kind ErrorKind, format string, args ...interface{}) *RecommendationError {
+	return &RecommendationError{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+func (e *RecommendationError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+}
+
+func (e *RecommendationError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable tells whether the same request could succeed later without any change to the workload.
+func (e *RecommendationError) IsRetryable() bool {
+	switch e.Kind {
+	case MetricsUnavailable, InsufficientData:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRetryable tells whether the error returned by a Recommender is worth retrying. Errors that aren't a
+// RecommendationError, such as failures to reach the api server, are considered retryable.
+func IsRetryable(err error) bool {
+	var recommendationError *RecommendationError
+	if errors.As(err, &recommendationError) {
+		return recommendationError.IsRetryable()
+	}
+	return true
+}
+
+// KindOf returns the ErrorKind of the error returned by a Recommender, and an empty ErrorKind if the error isn't a
+// RecommendationError.
+func KindOf(err error) ErrorKind {
+	var recommendationError *RecommendationError
+	if errors.As(err, &recommendationError) {
+		return recommendationError.Kind
+	}
+	return ""
+}
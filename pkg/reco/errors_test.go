@@ -0,0 +1,120 @@
+package reco
+
+import (
+	"errors"
+	"fmt"
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+// FailingScraper returns the configured error and data points for the cpu utilization of all workloads.
+type FailingScraper struct {
+	FakeScraper
+	dataPoints []metrics.DataPoint
+	err        error
+}
+
+func (fs *FailingScraper) GetAverageCPUUtilizationByWorkload(namespace,
+	workload string,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]metrics.DataPoint, error) {
+	return fs.dataPoints, fs.err
+}
+
+var _ = Describe("RecommendationError", func() {
+
+	It("should tell retryable errors from the non retryable ones", func() {
+		Expect(IsRetryable(&RecommendationError{Kind: MetricsUnavailable, Err: errors.New("down")})).To(BeTrue())
+		Expect(IsRetryable(&RecommendationError{Kind: InsufficientData, Err: errors.New("empty")})).To(BeTrue())
+		Expect(IsRetryable(&RecommendationError{Kind: UnsupportedWorkloadKind, Err: errors.New("job")})).To(BeFalse())
+		Expect(IsRetryable(&RecommendationError{Kind: InvalidResources, Err: errors.New("limits")})).To(BeFalse())
+		Expect(IsRetryable(&RecommendationError{Kind: WorkloadNotFound, Err: errors.New("deleted")})).To(BeFalse())
+		Expect(IsRetryable(errors.New("connection refused"))).To(BeTrue())
+	})
+
+	It("should find the kind of a wrapped error", func() {
+		err := fmt.Errorf("recommending: %w", &RecommendationError{Kind: InvalidResources, Err: errors.New("limits")})
+		Expect(KindOf(err)).To(Equal(InvalidResources))
+		Expect(KindOf(errors.New("connection refused"))).To(BeEmpty())
+		Expect(err.Error()).To(ContainSubstring("InvalidResources: limits"))
+	})
+
+	Context("When recommending for a workload", func() {
+		var (
+			workloadName = "test-error-deployment"
+			deployment   *appsv1.Deployment
+			workloadSpec = v1alpha1.WorkloadSpec{
+				Name:      workloadName,
+				Namespace: "default",
+				TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			}
+		)
+
+		BeforeEach(func() {
+			// The containers have no cpu limits.
+			deployment = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: workloadName, Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": workloadName}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": workloadName}},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "container-1", Image: "container-image"}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, deployment)).To(Succeed())
+		})
+
+		newRecommender := func(scraper metrics.Scraper) *CpuUtilizationBasedRecommender {
+			return newCpuRecommender(recommenderOptions{scraper: scraper})
+		}
+
+		It("should return MetricsUnavailable when the scraper fails", func() {
+			hpaConfig, err := newRecommender(&FailingScraper{err: errors.New("prometheus is down")}).
+				Recommend(workloadSpec)
+			Expect(hpaConfig).To(BeNil())
+			Expect(KindOf(err)).To(Equal(MetricsUnavailable))
+			Expect(errors.Unwrap(err)).To(MatchError("prometheus is down"))
+		})
+
+		It("should return InsufficientData when there are no data points", func() {
+			_, err := newRecommender(&FailingScraper{dataPoints: []metrics.DataPoint{}}).Recommend(workloadSpec)
+			Expect(KindOf(err)).To(Equal(InsufficientData))
+		})
+
+		It("should return UnsupportedWorkloadKind for a workload that isn't a Deployment or a Rollout", func() {
+			statefulSetSpec := workloadSpec
+			statefulSetSpec.Kind = "StatefulSet"
+			_, err := newRecommender(fakeScraper).Recommend(statefulSetSpec)
+			Expect(KindOf(err)).To(Equal(UnsupportedWorkloadKind))
+			Expect(IsRetryable(err)).To(BeFalse())
+		})
+
+		It("should return WorkloadNotFound for a workload that was deleted", func() {
+			deletedSpec := workloadSpec
+			deletedSpec.Name = "test-deleted-deployment"
+			_, err := newRecommender(fakeScraper).Recommend(deletedSpec)
+			Expect(KindOf(err)).To(Equal(WorkloadNotFound))
+			Expect(IsRetryable(err)).To(BeFalse())
+		})
+
+		It("should return InvalidResources when the containers have no cpu limits", func() {
+			_, err := newRecommender(fakeScraper).Recommend(workloadSpec)
+			Expect(KindOf(err)).To(Equal(InvalidResources))
+			Expect(IsRetryable(err)).To(BeFalse())
+		})
+	})
+})
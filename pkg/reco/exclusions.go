@@ -0,0 +1,61 @@
+package reco
+
+import (
+	"context"
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+)
+
+// excludeMetricRanges marks the data points in the time ranges of the MetricExclusions that cover the workload as
+// gaps, so that the simulation skips them as it does the gaps in the metrics. It returns the marked data points and
+// the ranges that overlap them, in the order of their start.
+func excludeMetricRanges(k8sClient client.Client,
+	workloadSpec v1alpha1.WorkloadSpec,
+	dataPoints []metrics.DataPoint) ([]metrics.DataPoint, []v1alpha1.ExcludedMetricRange, error) {
+
+	if len(dataPoints) == 0 {
+		return dataPoints, nil, nil
+	}
+
+	exclusions := &v1alpha1.MetricExclusionList{}
+	if err := k8sClient.List(context.Background(), exclusions); err != nil {
+		return nil, nil, err
+	}
+
+	first := dataPoints[0].Timestamp
+	last := dataPoints[len(dataPoints)-1].Timestamp
+	var excludedRanges []v1alpha1.ExcludedMetricRange
+	for _, exclusion := range exclusions.Items {
+		spec := exclusion.Spec
+		if !spec.Covers(workloadSpec) || !spec.End.After(spec.Start.Time) ||
+			!spec.End.After(first) || spec.Start.After(last) {
+			continue
+		}
+		excludedRanges = append(excludedRanges, v1alpha1.ExcludedMetricRange{
+			Exclusion: exclusion.Name,
+			Start:     spec.Start,
+			End:       spec.End,
+			Reason:    spec.Reason,
+		})
+	}
+	if len(excludedRanges) == 0 {
+		return dataPoints, nil, nil
+	}
+	sort.SliceStable(excludedRanges, func(i, j int) bool {
+		return excludedRanges[i].Start.Before(&excludedRanges[j].Start)
+	})
+
+	marked := make([]metrics.DataPoint, len(dataPoints))
+	copy(marked, dataPoints)
+	for i, dp := range marked {
+		for _, excludedRange := range excludedRanges {
+			if !dp.Timestamp.Before(excludedRange.Start.Time) && dp.Timestamp.Before(excludedRange.End.Time) {
+				marked[i] = metrics.DataPoint{Timestamp: dp.Timestamp, Gap: true}
+				break
+			}
+		}
+	}
+	return marked, excludedRanges, nil
+}
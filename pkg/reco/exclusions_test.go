@@ -0,0 +1,158 @@
+package reco
+
+import (
+	"context"
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+var _ = Describe("MetricExclusions", func() {
+	// The data points are far enough in the past for the exclusions not to overlap those of the other tests.
+	start := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	workloadSpec := v1alpha1.WorkloadSpec{
+		Name:      "test-exclusion-workload",
+		Namespace: "test-exclusion-ns",
+		TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+	}
+
+	var exclusions []*v1alpha1.MetricExclusion
+	createExclusion := func(name string, from, to int, namespace, workload string) {
+		exclusion := &v1alpha1.MetricExclusion{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1alpha1.MetricExclusionSpec{
+				Start:     metav1.NewTime(start.Add(time.Duration(from) * time.Minute)),
+				End:       metav1.NewTime(start.Add(time.Duration(to) * time.Minute)),
+				Reason:    "load test",
+				Namespace: namespace,
+				Workload:  workload,
+			},
+		}
+		Expect(k8sClient.Create(context.Background(), exclusion)).To(Succeed())
+		exclusions = append(exclusions, exclusion)
+	}
+
+	dataPoints := func() []metrics.DataPoint {
+		dataPoints := make([]metrics.DataPoint, 60)
+		for i := range dataPoints {
+			dataPoints[i] = metrics.DataPoint{Timestamp: start.Add(time.Duration(i) * time.Minute), Value: 40}
+		}
+		return dataPoints
+	}
+
+	AfterEach(func() {
+		for _, exclusion := range exclusions {
+			Expect(k8sClient.Delete(context.Background(), exclusion)).To(Succeed())
+		}
+		exclusions = nil
+	})
+
+	It("should mark the data points of the exclusions covering the workload as gaps", func() {
+		createExclusion("test-exclusion-workload-scoped", 40, 45, "test-exclusion-ns", "test-exclusion-workload")
+		createExclusion("test-exclusion-cluster-scoped", 10, 20, "", "")
+		createExclusion("test-exclusion-other-namespace", 0, 60, "other-ns", "")
+		createExclusion("test-exclusion-other-workload", 0, 60, "test-exclusion-ns", "other-workload")
+		createExclusion("test-exclusion-outside-window", 120, 180, "", "")
+
+		original := dataPoints()
+		marked, excludedRanges, err := excludeMetricRanges(k8sClient, workloadSpec, original)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(excludedRanges).To(HaveLen(2))
+		Expect(excludedRanges[0].Exclusion).To(Equal("test-exclusion-cluster-scoped"))
+		Expect(excludedRanges[0].Reason).To(Equal("load test"))
+		Expect(excludedRanges[1].Exclusion).To(Equal("test-exclusion-workload-scoped"))
+
+		for i, dp := range marked {
+			excluded := (i >= 10 && i < 20) || (i >= 40 && i < 45)
+			Expect(dp.Gap).To(Equal(excluded), "data point %d", i)
+			Expect(dp.Timestamp).To(Equal(original[i].Timestamp))
+		}
+		// The data points passed in are left untouched.
+		Expect(observedDataPoints(original)).To(HaveLen(60))
+	})
+
+	It("should return the data points as they are without exclusions", func() {
+		original := dataPoints()
+		marked, excludedRanges, err := excludeMetricRanges(k8sClient, workloadSpec, original)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(excludedRanges).To(BeEmpty())
+		Expect(marked).To(Equal(original))
+	})
+
+	It("should start the simulation after the excluded data points at the start of the window", func() {
+		createExclusion("test-exclusion-leading", -10, 5, "test-exclusion-ns", "")
+
+		marked, _, err := excludeMetricRanges(k8sClient, workloadSpec, dataPoints())
+		Expect(err).NotTo(HaveOccurred())
+		simulation, err := newCpuRecommender(recommenderOptions{step: time.Minute}).simulateHPA(marked, 0, 50, 1,
+			ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.minReplicas).To(Equal(80))
+		Expect(simulation.maxReplicas).To(Equal(80))
+		for i := 0; i < 5; i++ {
+			Expect(simulation.dataPoints[i].Gap).To(BeTrue())
+		}
+		Expect(simulation.dataPoints[5].Gap).To(BeFalse())
+	})
+
+	It("should leave the excluded data points out of the memory and custom metric recommendations", func() {
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-exclusion-metrics-workload",
+				Namespace: "default",
+				Annotations: map[string]string{
+					CustomMetricNameAnnotation:           "http_requests_per_second",
+					CustomMetricQueryAnnotation:          "sum(rate(http_requests_total[1m]))",
+					CustomMetricPerPodCapacityAnnotation: "8200m",
+				},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test-exclusion"}},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test-exclusion"}},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "container-1", Image: "container-image"}},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(context.Background(), deployment)).To(Succeed())
+		defer func() { Expect(k8sClient.Delete(context.Background(), deployment)).To(Succeed()) }()
+		metricsWorkloadSpec := v1alpha1.WorkloadSpec{
+			Name:      deployment.Name,
+			Namespace: deployment.Namespace,
+			TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+		}
+
+		// The exclusion covers the peak of the series of the fake scraper, which starts 10 minutes ago.
+		exclusion := &v1alpha1.MetricExclusion{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-exclusion-metrics-peak"},
+			Spec: v1alpha1.MetricExclusionSpec{
+				Start:     metav1.NewTime(time.Now().Add(-11 * time.Minute)),
+				End:       metav1.NewTime(time.Now().Add(-7*time.Minute - 30*time.Second)),
+				Reason:    "load test",
+				Namespace: deployment.Namespace,
+				Workload:  deployment.Name,
+			},
+		}
+		Expect(k8sClient.Create(context.Background(), exclusion)).To(Succeed())
+		exclusions = append(exclusions, exclusion)
+
+		for _, recommender := range []Recommender{
+			newMemoryRecommender(recommenderOptions{}),
+			newCustomMetricRecommender(recommenderOptions{}),
+		} {
+			recommendation, err := recommender.Recommend(metricsWorkloadSpec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recommendation.ExcludedMetricRanges).To(HaveLen(1))
+			Expect(recommendation.ExcludedMetricRanges[0].Exclusion).To(Equal(exclusion.Name))
+			// Without the peak, the replicas are well below the 24 of the whole series.
+			Expect(recommendation.HPAConfiguration.Max).To(BeNumerically("<", 24))
+		}
+	})
+})
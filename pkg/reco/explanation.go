@@ -0,0 +1,111 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+// Explanation records how a Recommendation was reached, so that the owners of a workload can tell why its target
+// moved.
+type Explanation struct {
+	GeneratedAt metav1.Time `json:"generatedAt"`
+	// ACL is the autoscaling cycle lag the HPA was simulated with.
+	ACL *v1alpha1.AutoscalingCycleLag `json:"acl,omitempty"`
+	// Metrics explains the search for the target of every metric of the recommendation.
+	Metrics []MetricExplanation `json:"metrics"`
+}
+
+// MetricExplanation is the trace of the search for the optimal target of a metric.
+type MetricExplanation struct {
+	Metric string `json:"metric"`
+	// WindowStart and WindowEnd are the bounds of the metric window the HPA was simulated over.
+	WindowStart metav1.Time     `json:"windowStart"`
+	WindowEnd   metav1.Time     `json:"windowEnd"`
+	Step        metav1.Duration `json:"step"`
+	// DataPoints is the number of data points simulated, of which SkippedDataPoints were gaps in the metrics or
+	// excluded from the window.
+	DataPoints        int `json:"dataPoints"`
+	SkippedDataPoints int `json:"skippedDataPoints"`
+	// PerPodResources is the capacity of a pod the utilization is measured against, in the units of the metric.
+	PerPodResources float64 `json:"perPodResources"`
+	// Target is the target utilization of PerPodResources the search settled on.
+	Target int `json:"target"`
+	// Candidates lists the targets tried by the search, in the order they were tried.
+	Candidates []CandidateTarget `json:"candidates"`
+	// Forecast is the forecast series the HPA was simulated against, if the metric is forecast.
+	Forecast *ForecastSeries `json:"forecast,omitempty"`
+}
+
+// CandidateTarget is a target utilization tried by the search for the optimal target, and how the simulated HPA fared
+// at it.
+type CandidateTarget struct {
+	Target int `json:"target"`
+	// Accepted is true when the breaches at the target fit in the breach budget.
+	Accepted    bool `json:"accepted"`
+	MinReplicas int  `json:"minReplicas"`
+	MaxReplicas int  `json:"maxReplicas"`
+	// Breaches is the number of data points the demand exceeded the simulated capacity at. The simulation of a target
+	// that isn't accepted stops at the breach that rejects it, so its Breaches, replicas and BreachBudgetConsumed
+	// only cover the data points up to that breach.
+	Breaches int `json:"breaches"`
+	// FirstBreach is the time of the first of the Breaches.
+	FirstBreach          *metav1.Time    `json:"firstBreach,omitempty"`
+	BreachBudgetConsumed metav1.Duration `json:"breachBudgetConsumed"`
+}
+
+// newCandidateTarget returns the CandidateTarget of the simulation of the HPA at the target.
+func newCandidateTarget(target int, accepted bool, simulation *hpaSimulation) CandidateTarget {
+	candidate := CandidateTarget{
+		Target:               target,
+		Accepted:             accepted,
+		MinReplicas:          simulation.minReplicas,
+		MaxReplicas:          simulation.maxReplicas,
+		Breaches:             simulation.breaches,
+		BreachBudgetConsumed: simulation.usage.Consumed,
+	}
+	if simulation.breaches > 0 {
+		firstBreach := metav1.NewTime(simulation.firstBreach)
+		candidate.FirstBreach = &firstBreach
+	}
+	return candidate
+}
+
+// newMetricExplanation returns the MetricExplanation of the search for the target of the metric over the data points
+// the HPA was simulated against, which are those of the forecast if one is configured.
+func newMetricExplanation(metric string,
+	step time.Duration,
+	dataPoints []metrics.DataPoint,
+	perPodResources float64,
+	target int,
+	candidates []CandidateTarget,
+	forecast *Forecast) *MetricExplanation {
+
+	explanation := &MetricExplanation{
+		Metric:            metric,
+		Step:              metav1.Duration{Duration: step},
+		DataPoints:        len(dataPoints),
+		SkippedDataPoints: len(dataPoints) - len(observedDataPoints(dataPoints)),
+		PerPodResources:   perPodResources,
+		Target:            target,
+		Candidates:        candidates,
+		Forecast:          forecast.Series(),
+	}
+	if len(dataPoints) > 0 {
+		explanation.WindowStart = metav1.NewTime(dataPoints[0].Timestamp)
+		explanation.WindowEnd = metav1.NewTime(dataPoints[len(dataPoints)-1].Timestamp)
+	}
+	return explanation
+}
+
+// newExplanation returns the Explanation of a recommendation on the metrics.
+func newExplanation(acl *v1alpha1.AutoscalingCycleLag, metricExplanations ...*MetricExplanation) *Explanation {
+	explanation := &Explanation{GeneratedAt: metav1.NewTime(time.Now()), ACL: acl}
+	for _, metricExplanation := range metricExplanations {
+		if metricExplanation != nil {
+			explanation.Metrics = append(explanation.Metrics, *metricExplanation)
+		}
+	}
+	return explanation
+}
@@ -0,0 +1,61 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"time"
+)
+
+var _ = Describe("Explanation", func() {
+	const step = time.Minute
+
+	// series returns an hour of data points at one minute step at 40 with a spike to 80 at the 30th minute.
+	series := func() []metrics.DataPoint {
+		start := time.Now().Add(-time.Hour)
+		dataPoints := make([]metrics.DataPoint, 60)
+		for i := range dataPoints {
+			dataPoints[i] = metrics.DataPoint{Timestamp: start.Add(time.Duration(i) * step), Value: 40}
+		}
+		dataPoints[30].Value = 80
+		return dataPoints
+	}
+
+	It("should trace every target tried by the search", func() {
+		dataPoints := series()
+		recommender := newCpuRecommender(recommenderOptions{step: step})
+
+		search, err := recommender.findOptimalTargetUtilization(dataPoints, 5*time.Minute, minTarget, maxTarget, 1,
+			ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+		target, candidates := search.target, search.candidates
+		Expect(candidates).NotTo(BeEmpty())
+		Expect(candidates[0].Target).To(Equal(minTarget + (maxTarget-minTarget)/2))
+
+		for _, candidate := range candidates {
+			if candidate.Accepted {
+				Expect(candidate.Target).To(BeNumerically("<=", target))
+				Expect(candidate.Breaches).To(BeZero())
+				Expect(candidate.FirstBreach).To(BeNil())
+			} else {
+				Expect(candidate.Target).To(BeNumerically(">", target))
+				Expect(candidate.Breaches).To(BeNumerically(">", 0))
+				Expect(candidate.FirstBreach.Time).To(Equal(dataPoints[30].Timestamp))
+			}
+		}
+		Expect(candidates).To(ContainElement(And(HaveField("Target", target), HaveField("Accepted", true))))
+	})
+
+	It("should report the window and the skipped data points", func() {
+		dataPoints := series()
+		dataPoints[10].Gap = true
+		dataPoints[11].Gap = true
+
+		explanation := newMetricExplanation("cpu", step, dataPoints, 2, 50, nil, nil)
+		Expect(explanation.WindowStart.Time).To(Equal(dataPoints[0].Timestamp))
+		Expect(explanation.WindowEnd.Time).To(Equal(dataPoints[59].Timestamp))
+		Expect(explanation.DataPoints).To(Equal(60))
+		Expect(explanation.SkippedDataPoints).To(Equal(2))
+		Expect(explanation.Step.Duration).To(Equal(step))
+	})
+})
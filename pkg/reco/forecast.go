@@ -0,0 +1,298 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// decompositionRounds is the number of times the trend and the seasonal profiles are fit in turn.
+	decompositionRounds = 10
+	// maxForecastSeriesPoints bounds the data points of the forecast kept in the explanation of a recommendation, so
+	// that a long horizon at a short step still fits in a ConfigMap.
+	maxForecastSeriesPoints = 2000
+)
+
+// ForecastMode tells what the HPA is simulated against.
+type ForecastMode string
+
+const (
+	// HistoryOnly simulates the HPA against the history of the metric alone.
+	HistoryOnly ForecastMode = ""
+	// ForecastOnly simulates the HPA against the forecast of the metric over the horizon.
+	ForecastOnly ForecastMode = "Forecast"
+	// WorstOfForecastAndHistory simulates the HPA against the larger of the forecast and the history one horizon
+	// earlier, at every step of the horizon. Only the last horizon of the history is compared against, and the HPA is
+	// simulated over the horizon alone, so the history older than one horizon is left out of the simulation.
+	WorstOfForecastAndHistory ForecastMode = "WorstOfForecastAndHistory"
+)
+
+// ForecastConfig configures the forecast of the metric the HPA is simulated against. The history is decomposed into
+// a linear trend and the seasonal profile of each of the SeasonalPeriods, and the sum of the trend and the profiles
+// is projected over the Horizon. The zero value simulates the history alone.
+type ForecastConfig struct {
+	Mode ForecastMode
+	// Horizon is how far ahead the metric is forecast. It should be a multiple of the longest seasonal period, so that
+	// the history one horizon earlier is in the same phase of every season as the forecast.
+	Horizon time.Duration
+	// SeasonalPeriods are the seasons to fit, typically a day and a week. A season is skipped when the history
+	// doesn't cover two of its periods.
+	SeasonalPeriods []time.Duration
+}
+
+// Forecast is the forecast of a metric over the horizon, exposed for inspection.
+type Forecast struct {
+	Mode    ForecastMode
+	Horizon time.Duration
+	Step    time.Duration
+	// SeasonalPeriods are the seasons the history was long enough to fit.
+	SeasonalPeriods []time.Duration
+	// TrendPerDay is the slope of the linear trend of the history.
+	TrendPerDay  float64
+	HistoryPeak  float64
+	ForecastPeak float64
+	DataPoints   []metrics.DataPoint
+}
+
+// Summary condenses the Forecast for the status of a PolicyRecommendation.
+func (f *Forecast) Summary() *v1alpha1.ForecastSummary {
+	summary := &v1alpha1.ForecastSummary{
+		Mode:         string(f.Mode),
+		Horizon:      metav1.Duration{Duration: f.Horizon},
+		TrendPerDay:  *milliQuantity(f.TrendPerDay),
+		HistoryPeak:  *milliQuantity(f.HistoryPeak),
+		ForecastPeak: *milliQuantity(f.ForecastPeak),
+	}
+	for _, period := range f.SeasonalPeriods {
+		summary.SeasonalPeriods = append(summary.SeasonalPeriods, metav1.Duration{Duration: period})
+	}
+	return summary
+}
+
+// ForecastSeries is the series the HPA was simulated against when the metric is forecast, for inspection. A series
+// longer than maxForecastSeriesPoints is downsampled to the peak of every Step, so that no spike is hidden.
+type ForecastSeries struct {
+	Mode   ForecastMode    `json:"mode"`
+	Step   metav1.Duration `json:"step"`
+	Points []ForecastPoint `json:"points"`
+}
+
+// ForecastPoint is a data point of a ForecastSeries.
+type ForecastPoint struct {
+	Timestamp metav1.Time `json:"timestamp"`
+	Value     float64     `json:"value"`
+}
+
+// Series returns the data points of the Forecast as a ForecastSeries, and nil when there is no Forecast.
+func (f *Forecast) Series() *ForecastSeries {
+	if f == nil {
+		return nil
+	}
+	stride := (len(f.DataPoints) + maxForecastSeriesPoints - 1) / maxForecastSeriesPoints
+	if stride < 1 {
+		stride = 1
+	}
+	series := &ForecastSeries{Mode: f.Mode, Step: metav1.Duration{Duration: f.Step * time.Duration(stride)}}
+	for start := 0; start < len(f.DataPoints); start += stride {
+		end := start + stride
+		if end > len(f.DataPoints) {
+			end = len(f.DataPoints)
+		}
+		peak := f.DataPoints[start].Value
+		for _, dp := range f.DataPoints[start+1 : end] {
+			peak = math.Max(peak, dp.Value)
+		}
+		series.Points = append(series.Points, ForecastPoint{
+			Timestamp: metav1.NewTime(f.DataPoints[start].Timestamp),
+			Value:     peak,
+		})
+	}
+	return series
+}
+
+// forecastDemand returns the data points to simulate the HPA against, and the forecast if one is configured. The
+// history is returned as is when the mode is HistoryOnly or the history is too short to fit a trend.
+func (s *simulator) forecastDemand(history []metrics.DataPoint) ([]metrics.DataPoint, *Forecast) {
+	if s.forecast.Mode == HistoryOnly || s.forecast.Horizon <= 0 || s.metricStep <= 0 {
+		return history, nil
+	}
+	// The forecast is fit on the data points outside the gaps in the metrics.
+	observed := observedDataPoints(history)
+	if len(observed) < 2 {
+		return history, nil
+	}
+
+	forecast := forecastDataPoints(observed, s.metricStep, s.forecast.Horizon, s.forecast.SeasonalPeriods)
+	forecast.Mode = s.forecast.Mode
+	forecast.Step = s.metricStep
+	if s.forecast.Mode == WorstOfForecastAndHistory {
+		forecast.DataPoints = worstOfForecastAndHistory(forecast.DataPoints, observed, s.forecast.Horizon)
+	}
+	for _, dp := range forecast.DataPoints {
+		forecast.ForecastPeak = math.Max(forecast.ForecastPeak, dp.Value)
+	}
+	return forecast.DataPoints, forecast
+}
+
+// forecastDataPoints decomposes the history into a linear trend and the seasonal profiles of the periods, and
+// projects their sum over the horizon at every step. The profile of a period is the mean, per step of the period, of
+// what the trend and the shorter periods leave unexplained. The trend and the profiles are fit in turn for a few
+// rounds, so that a season that isn't symmetric over the window doesn't skew the trend.
+func forecastDataPoints(history []metrics.DataPoint,
+	step time.Duration,
+	horizon time.Duration,
+	seasonalPeriods []time.Duration) *Forecast {
+
+	origin := history[0].Timestamp
+	end := history[len(history)-1].Timestamp
+	forecast := &Forecast{Horizon: horizon}
+	for _, dp := range history {
+		forecast.HistoryPeak = math.Max(forecast.HistoryPeak, dp.Value)
+	}
+
+	periods := append([]time.Duration(nil), seasonalPeriods...)
+	sort.Slice(periods, func(i, j int) bool { return periods[i] < periods[j] })
+	for _, period := range periods {
+		if period >= step && end.Sub(origin) >= 2*period {
+			forecast.SeasonalPeriods = append(forecast.SeasonalPeriods, period)
+		}
+	}
+
+	var slope, intercept float64
+	var profiles []seasonalProfile
+	seasonal := make([]float64, len(history))
+	deseasonalized := make([]metrics.DataPoint, len(history))
+	residuals := make([]float64, len(history))
+	for round := 0; round < decompositionRounds; round++ {
+		for i, dp := range history {
+			deseasonalized[i] = metrics.DataPoint{Timestamp: dp.Timestamp, Value: dp.Value - seasonal[i]}
+		}
+		slope, intercept = linearTrend(deseasonalized, origin)
+
+		for i, dp := range history {
+			residuals[i] = dp.Value - (intercept + slope*dp.Timestamp.Sub(origin).Seconds())
+			seasonal[i] = 0
+		}
+		profiles = profiles[:0]
+		for _, period := range forecast.SeasonalPeriods {
+			profile := newSeasonalProfile(history, residuals, period, step)
+			for i, dp := range history {
+				residuals[i] -= profile.at(dp.Timestamp)
+				seasonal[i] += profile.at(dp.Timestamp)
+			}
+			profiles = append(profiles, profile)
+		}
+		if len(profiles) == 0 {
+			break
+		}
+	}
+
+	forecast.TrendPerDay = slope * (24 * time.Hour).Seconds()
+	for t := end.Add(step); !t.After(end.Add(horizon)); t = t.Add(step) {
+		value := intercept + slope*t.Sub(origin).Seconds()
+		for _, profile := range profiles {
+			value += profile.at(t)
+		}
+		forecast.DataPoints = append(forecast.DataPoints, metrics.DataPoint{Timestamp: t, Value: math.Max(value, 0)})
+	}
+	return forecast
+}
+
+// linearTrend fits a line to the data points by least squares, with the time in seconds since the origin.
+func linearTrend(dataPoints []metrics.DataPoint, origin time.Time) (float64, float64) {
+	var sumX, sumY, sumXX, sumXY float64
+	n := float64(len(dataPoints))
+	for _, dp := range dataPoints {
+		x := dp.Timestamp.Sub(origin).Seconds()
+		sumX += x
+		sumY += dp.Value
+		sumXX += x * x
+		sumXY += x * dp.Value
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, sumY / n
+	}
+	slope := (n*sumXY - sumX*sumY) / denominator
+	return slope, (sumY - slope*sumX) / n
+}
+
+// seasonalProfile is the mean value at every step of a period. The steps are counted from the Unix epoch, so that
+// a daily period starts at midnight UTC, whatever the window of the history.
+type seasonalProfile struct {
+	period time.Duration
+	step   time.Duration
+	means  []float64
+}
+
+func newSeasonalProfile(history []metrics.DataPoint,
+	values []float64,
+	period time.Duration,
+	step time.Duration) seasonalProfile {
+
+	profile := seasonalProfile{period: period, step: step, means: make([]float64, int(period/step))}
+	counts := make([]int, len(profile.means))
+	for i, dp := range history {
+		slot := profile.slot(dp.Timestamp)
+		profile.means[slot] += values[i]
+		counts[slot]++
+	}
+	for slot := range profile.means {
+		if counts[slot] > 0 {
+			profile.means[slot] /= float64(counts[slot])
+		}
+	}
+	return profile
+}
+
+func (p seasonalProfile) slot(t time.Time) int {
+	offset := time.Duration(t.UnixNano()) % p.period
+	if offset < 0 {
+		offset += p.period
+	}
+	return int(offset/p.step) % len(p.means)
+}
+
+func (p seasonalProfile) at(t time.Time) float64 {
+	return p.means[p.slot(t)]
+}
+
+// worstOfForecastAndHistory returns the larger of the forecast and the history one horizon earlier at every step of
+// the forecast. The history is sampled at its latest data point no later than the step. Only the steps of the forecast
+// are returned, so the history older than one horizon isn't part of the result.
+func worstOfForecastAndHistory(forecast []metrics.DataPoint,
+	history []metrics.DataPoint,
+	horizon time.Duration) []metrics.DataPoint {
+
+	worst := make([]metrics.DataPoint, len(forecast))
+	next := 0
+	historyValue := 0.0
+	for i, dp := range forecast {
+		for next < len(history) && !history[next].Timestamp.After(dp.Timestamp.Add(-horizon)) {
+			historyValue = history[next].Value
+			next++
+		}
+		worst[i] = metrics.DataPoint{Timestamp: dp.Timestamp, Value: math.Max(dp.Value, historyValue)}
+	}
+	return worst
+}
+
+// observedDataPoints returns the data points that aren't in a gap of the metrics.
+func observedDataPoints(dataPoints []metrics.DataPoint) []metrics.DataPoint {
+	observed := make([]metrics.DataPoint, 0, len(dataPoints))
+	for _, dp := range dataPoints {
+		if !dp.Gap {
+			observed = append(observed, dp)
+		}
+	}
+	return observed
+}
+
+func milliQuantity(value float64) *resource.Quantity {
+	return resource.NewMilliQuantity(int64(math.Round(value*1000)), resource.DecimalSI)
+}
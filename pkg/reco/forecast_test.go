@@ -0,0 +1,207 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+// GrowingWorkingSetScraper returns a memory working set that grows steadily over the two days before now.
+type GrowingWorkingSetScraper struct {
+	FakeScraper
+}
+
+func (gs *GrowingWorkingSetScraper) GetMemoryWorkingSetByWorkload(namespace,
+	workload string,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]metrics.DataPoint, error) {
+	var dataPoints []metrics.DataPoint
+	now := time.Now()
+	for t := now.Add(-48 * time.Hour); !t.After(now); t = t.Add(metricStep) {
+		elapsed := t.Sub(now.Add(-48 * time.Hour))
+		dataPoints = append(dataPoints, metrics.DataPoint{Timestamp: t, Value: 10 + elapsed.Hours()/4})
+	}
+	return dataPoints, nil
+}
+
+var _ = Describe("Forecast", func() {
+	start := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	// hourly returns a data point every hour for the given days, valued by the function of the hour since start.
+	hourly := func(days int, value func(hour int) float64) []metrics.DataPoint {
+		var dataPoints []metrics.DataPoint
+		for hour := 0; hour < days*24; hour++ {
+			dataPoints = append(dataPoints, metrics.DataPoint{
+				Timestamp: start.Add(time.Duration(hour) * time.Hour),
+				Value:     value(hour),
+			})
+		}
+		return dataPoints
+	}
+
+	It("should project the trend of the history", func() {
+		history := hourly(3, func(hour int) float64 { return 10 + float64(hour) })
+
+		forecast := forecastDataPoints(history, time.Hour, 24*time.Hour, nil)
+		Expect(forecast.TrendPerDay).To(BeNumerically("~", 24, 1e-6))
+		Expect(forecast.HistoryPeak).To(Equal(81.0))
+		Expect(forecast.DataPoints).To(HaveLen(24))
+		Expect(forecast.DataPoints[0].Timestamp).To(Equal(start.Add(72 * time.Hour)))
+		Expect(forecast.DataPoints[0].Value).To(BeNumerically("~", 82, 1e-6))
+		Expect(forecast.DataPoints[23].Value).To(BeNumerically("~", 105, 1e-6))
+	})
+
+	It("should project the daily season of the history", func() {
+		// Busy from 8am to 8pm every day.
+		history := hourly(14, func(hour int) float64 {
+			if hour%24 >= 8 && hour%24 < 20 {
+				return 70
+			}
+			return 50
+		})
+
+		forecast := forecastDataPoints(history, time.Hour, 24*time.Hour, []time.Duration{24 * time.Hour})
+		Expect(forecast.SeasonalPeriods).To(Equal([]time.Duration{24 * time.Hour}))
+		Expect(forecast.DataPoints).To(HaveLen(24))
+		// The forecast starts at midnight of the fifteenth day.
+		Expect(forecast.DataPoints[1].Value).To(BeNumerically("~", 50, 0.5))
+		Expect(forecast.DataPoints[9].Value).To(BeNumerically("~", 70, 0.5))
+		Expect(forecast.DataPoints[21].Value).To(BeNumerically("~", 50, 0.5))
+	})
+
+	It("should fit the weekly season on what the daily season leaves unexplained", func() {
+		// Busy from 8am to 8pm, and twice as busy on the first day of every week.
+		history := hourly(21, func(hour int) float64 {
+			value := 50.0
+			if hour%24 >= 8 && hour%24 < 20 {
+				value = 70
+			}
+			if (hour/24)%7 == 0 {
+				value *= 2
+			}
+			return value
+		})
+
+		forecast := forecastDataPoints(history, time.Hour, 7*24*time.Hour,
+			[]time.Duration{7 * 24 * time.Hour, 24 * time.Hour})
+		Expect(forecast.SeasonalPeriods).To(Equal([]time.Duration{24 * time.Hour, 7 * 24 * time.Hour}))
+		Expect(forecast.DataPoints).To(HaveLen(7 * 24))
+		// The forecast starts at midnight of the first day of the fourth week.
+		Expect(forecast.DataPoints[9].Value).To(BeNumerically("~", 140, 1))
+		Expect(forecast.DataPoints[24+9].Value).To(BeNumerically("~", 70, 1))
+		Expect(forecast.DataPoints[24+1].Value).To(BeNumerically("~", 50, 1))
+	})
+
+	It("should skip the seasons the history doesn't cover twice", func() {
+		history := hourly(3, func(hour int) float64 { return 50 })
+
+		forecast := forecastDataPoints(history, time.Hour, 24*time.Hour,
+			[]time.Duration{24 * time.Hour, 7 * 24 * time.Hour})
+		Expect(forecast.SeasonalPeriods).To(Equal([]time.Duration{24 * time.Hour}))
+	})
+
+	It("should take the worse of the forecast and the history one horizon earlier", func() {
+		history := hourly(2, func(hour int) float64 {
+			if hour == 30 {
+				return 90
+			}
+			return 40
+		})
+		forecast := []metrics.DataPoint{
+			{Timestamp: start.Add(53 * time.Hour), Value: 60},
+			{Timestamp: start.Add(54 * time.Hour), Value: 60},
+			{Timestamp: start.Add(55 * time.Hour), Value: 30},
+		}
+
+		worst := worstOfForecastAndHistory(forecast, history, 24*time.Hour)
+		Expect(worst).To(Equal([]metrics.DataPoint{
+			{Timestamp: start.Add(53 * time.Hour), Value: 60},
+			{Timestamp: start.Add(54 * time.Hour), Value: 90},
+			{Timestamp: start.Add(55 * time.Hour), Value: 40},
+		}))
+	})
+
+	It("should simulate the history alone when no forecast is configured", func() {
+		history := hourly(3, func(hour int) float64 { return float64(hour) })
+		s := &simulator{metricStep: time.Hour}
+
+		demand, forecast := s.forecastDemand(history)
+		Expect(demand).To(Equal(history))
+		Expect(forecast).To(BeNil())
+	})
+
+	It("should summarize the forecast for the status", func() {
+		forecast := &Forecast{
+			Mode:            WorstOfForecastAndHistory,
+			Horizon:         7 * 24 * time.Hour,
+			SeasonalPeriods: []time.Duration{24 * time.Hour},
+			TrendPerDay:     -0.25,
+			HistoryPeak:     12.5,
+			ForecastPeak:    14,
+		}
+
+		summary := forecast.Summary()
+		Expect(summary.Mode).To(Equal("WorstOfForecastAndHistory"))
+		Expect(summary.Horizon).To(Equal(metav1.Duration{Duration: 7 * 24 * time.Hour}))
+		Expect(summary.SeasonalPeriods).To(Equal([]metav1.Duration{{Duration: 24 * time.Hour}}))
+		Expect(summary.TrendPerDay.String()).To(Equal("-250m"))
+		Expect(summary.HistoryPeak.String()).To(Equal("12500m"))
+		Expect(summary.ForecastPeak.String()).To(Equal("14"))
+	})
+
+	It("should downsample a long forecast series to its peaks", func() {
+		start := time.Now()
+		forecast := &Forecast{Mode: ForecastOnly, Step: time.Minute}
+		for i := 0; i < 2*maxForecastSeriesPoints+1; i++ {
+			forecast.DataPoints = append(forecast.DataPoints, metrics.DataPoint{
+				Timestamp: start.Add(time.Duration(i) * time.Minute),
+				Value:     float64(i % 3),
+			})
+		}
+
+		series := forecast.Series()
+		Expect(series.Mode).To(Equal(ForecastOnly))
+		Expect(series.Step.Duration).To(Equal(3 * time.Minute))
+		// 4001 data points in steps of 3, the last of them alone.
+		Expect(series.Points).To(HaveLen(1334))
+		Expect(series.Points[0].Timestamp.Time).To(Equal(start))
+		for _, point := range series.Points[:len(series.Points)-1] {
+			Expect(point.Value).To(Equal(2.0))
+		}
+
+		Expect((&Forecast{Step: time.Minute, DataPoints: forecast.DataPoints[:10]}).Series().Points).To(HaveLen(10))
+		Expect((*Forecast)(nil).Series()).To(BeNil())
+	})
+
+	It("should size the workload on the forecast growth", func() {
+		workloadSpec := v1alpha1.WorkloadSpec{
+			Name:      "test-forecast-workload",
+			Namespace: "default",
+			TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+		}
+		newRecommender := func(forecast ForecastConfig) *MemoryUtilizationBasedRecommender {
+			return newMemoryRecommender(recommenderOptions{scraper: &GrowingWorkingSetScraper{}, forecast: forecast})
+		}
+
+		historyRecommendation, err := newRecommender(ForecastConfig{}).Recommend(workloadSpec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(historyRecommendation.Forecast).To(BeNil())
+
+		forecastRecommendation, err := newRecommender(ForecastConfig{Mode: ForecastOnly, Horizon: 24 * time.Hour}).
+			Recommend(workloadSpec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(forecastRecommendation.Forecast).NotTo(BeNil())
+		Expect(forecastRecommendation.Forecast.ForecastPeak).To(
+			BeNumerically(">", forecastRecommendation.Forecast.HistoryPeak))
+		Expect(forecastRecommendation.HPAConfiguration.Max).To(
+			BeNumerically(">", historyRecommendation.HPAConfiguration.Max))
+		Expect(forecastRecommendation.Explanation.Metrics[0].Forecast).NotTo(BeNil())
+		Expect(forecastRecommendation.Explanation.Metrics[0].Forecast.Points).To(
+			HaveLen(len(forecastRecommendation.Forecast.DataPoints)))
+		Expect(historyRecommendation.Explanation.Metrics[0].Forecast).To(BeNil())
+	})
+})
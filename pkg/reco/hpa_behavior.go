@@ -0,0 +1,272 @@
+package reco
+
+import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"math"
+	"time"
+)
+
+// HPABehavior is the behavior of the HPA modeled by simulateHPA. The zero value models an HPA that scales to the
+// desired replicas instantly, in both directions.
+type HPABehavior struct {
+	// Tolerance is the band around the target utilization within which the HPA doesn't scale. The HPA controller
+	// defaults to 0.1.
+	Tolerance float64
+	// Behavior holds the stabilization windows and the rate policies for scaling up and down, as on an autoscaling/v2
+	// HPA. Rules that aren't set don't stabilize or limit scaling in that direction.
+	Behavior *autoscalingv2.HorizontalPodAutoscalerBehavior
+}
+
+type replicasRecommendation struct {
+	timestamp time.Time
+	replicas  float64
+}
+
+type scaleEvent struct {
+	timestamp time.Time
+	// delta is positive for scale ups and negative for scale downs.
+	delta float64
+}
+
+// hpaScaler decides the replicas of the simulated HPA the way the HPA controller does, by applying the tolerance,
+// the stabilization windows and the rate policies to the desired replicas.
+type hpaScaler struct {
+	behavior        HPABehavior
+	recommendations []replicasRecommendation
+	scaleEvents     []scaleEvent
+}
+
+func newHPAScaler(behavior HPABehavior) *hpaScaler {
+	return &hpaScaler{behavior: behavior}
+}
+
+// reset forgets the recommendations and the scale events of a previous simulation, and keeps their storage.
+func (s *hpaScaler) reset() {
+	s.recommendations = s.recommendations[:0]
+	s.scaleEvents = s.scaleEvents[:0]
+}
+
+// scale returns the replicas the HPA scales to at the timestamp, given the current replicas, the desired replicas
+// for the target utilization and the ratio of the current utilization to the target utilization.
+func (s *hpaScaler) scale(timestamp time.Time,
+	currentReplicas float64,
+	desiredReplicas float64,
+	utilizationRatio float64) float64 {
+
+	return s.scaleToDesired(timestamp, currentReplicas, s.applyTolerance(currentReplicas, desiredReplicas,
+		utilizationRatio))
+}
+
+// applyTolerance returns the current replicas when the utilization is within the tolerance band of the target, and
+// the desired replicas otherwise.
+func (s *hpaScaler) applyTolerance(currentReplicas float64, desiredReplicas float64, utilizationRatio float64) float64 {
+	if s.behavior.Tolerance > 0 && math.Abs(utilizationRatio-1) <= s.behavior.Tolerance {
+		return currentReplicas
+	}
+	return desiredReplicas
+}
+
+// scaleToDesired returns the replicas the HPA scales to from the current replicas, given the desired replicas after
+// the tolerance is applied.
+func (s *hpaScaler) scaleToDesired(timestamp time.Time, currentReplicas float64, desiredReplicas float64) float64 {
+	if s.behavior.Behavior == nil {
+		return desiredReplicas
+	}
+
+	newReplicas := s.stabilize(timestamp, currentReplicas, desiredReplicas)
+	newReplicas = s.limitRate(timestamp, currentReplicas, newReplicas)
+	if newReplicas != currentReplicas {
+		s.scaleEvents = append(s.scaleEvents, scaleEvent{timestamp: timestamp, delta: newReplicas - currentReplicas})
+	}
+	return newReplicas
+}
+
+// stabilize holds the replicas at the lowest recommendation of the scale up window and the highest recommendation of
+// the scale down window.
+func (s *hpaScaler) stabilize(timestamp time.Time, currentReplicas float64, desiredReplicas float64) float64 {
+	upWindow := stabilizationWindow(s.behavior.Behavior.ScaleUp)
+	downWindow := stabilizationWindow(s.behavior.Behavior.ScaleDown)
+
+	upWindowStart := timestamp.Add(-upWindow)
+	downWindowStart := timestamp.Add(-downWindow)
+	retainedStart := timestamp.Add(-maxDuration(upWindow, downWindow))
+
+	upRecommendation := desiredReplicas
+	downRecommendation := desiredReplicas
+	retained := s.recommendations[:0]
+	for _, recommendation := range s.recommendations {
+		if recommendation.timestamp.After(upWindowStart) && recommendation.replicas < upRecommendation {
+			upRecommendation = recommendation.replicas
+		}
+		if recommendation.timestamp.After(downWindowStart) && recommendation.replicas > downRecommendation {
+			downRecommendation = recommendation.replicas
+		}
+		if recommendation.timestamp.After(retainedStart) {
+			retained = append(retained, recommendation)
+		}
+	}
+	s.recommendations = append(retained, replicasRecommendation{timestamp: timestamp, replicas: desiredReplicas})
+
+	newReplicas := currentReplicas
+	if newReplicas < upRecommendation {
+		newReplicas = upRecommendation
+	}
+	if newReplicas > downRecommendation {
+		newReplicas = downRecommendation
+	}
+	return newReplicas
+}
+
+// limitRate limits the change in replicas to what the scaling policies allow within their periods.
+func (s *hpaScaler) limitRate(timestamp time.Time, currentReplicas float64, desiredReplicas float64) float64 {
+	s.pruneScaleEvents(timestamp)
+
+	if desiredReplicas > currentReplicas {
+		limit, ok := s.scaleUpLimit(timestamp, currentReplicas, s.behavior.Behavior.ScaleUp)
+		if ok {
+			return math.Min(desiredReplicas, math.Max(limit, currentReplicas))
+		}
+	} else if desiredReplicas < currentReplicas {
+		limit, ok := s.scaleDownLimit(timestamp, currentReplicas, s.behavior.Behavior.ScaleDown)
+		if ok {
+			return math.Max(desiredReplicas, math.Min(limit, currentReplicas))
+		}
+	}
+	return desiredReplicas
+}
+
+// scaleUpLimit returns the most replicas the scale up policies allow, and false if scaling up isn't limited.
+func (s *hpaScaler) scaleUpLimit(timestamp time.Time,
+	currentReplicas float64,
+	rules *autoscalingv2.HPAScalingRules) (float64, bool) {
+
+	if rules == nil {
+		return 0, false
+	}
+	if isScalingDisabled(rules) {
+		return currentReplicas, true
+	}
+	if len(rules.Policies) == 0 {
+		return 0, false
+	}
+
+	selectMin := rules.SelectPolicy != nil && *rules.SelectPolicy == autoscalingv2.MinChangePolicySelect
+	limit := math.Inf(-1)
+	if selectMin {
+		limit = math.Inf(1)
+	}
+	for _, policy := range rules.Policies {
+		periodStartReplicas := currentReplicas - s.replicasChangedWithin(timestamp, policy.PeriodSeconds, true)
+		var proposed float64
+		switch policy.Type {
+		case autoscalingv2.PodsScalingPolicy:
+			proposed = periodStartReplicas + float64(policy.Value)
+		case autoscalingv2.PercentScalingPolicy:
+			proposed = math.Ceil(periodStartReplicas * (1 + float64(policy.Value)/100))
+		}
+		if selectMin {
+			limit = math.Min(limit, proposed)
+		} else {
+			limit = math.Max(limit, proposed)
+		}
+	}
+	return limit, true
+}
+
+// scaleDownLimit returns the fewest replicas the scale down policies allow, and false if scaling down isn't limited.
+func (s *hpaScaler) scaleDownLimit(timestamp time.Time,
+	currentReplicas float64,
+	rules *autoscalingv2.HPAScalingRules) (float64, bool) {
+
+	if rules == nil {
+		return 0, false
+	}
+	if isScalingDisabled(rules) {
+		return currentReplicas, true
+	}
+	if len(rules.Policies) == 0 {
+		return 0, false
+	}
+
+	selectMin := rules.SelectPolicy != nil && *rules.SelectPolicy == autoscalingv2.MinChangePolicySelect
+	limit := math.Inf(1)
+	if selectMin {
+		limit = math.Inf(-1)
+	}
+	for _, policy := range rules.Policies {
+		periodStartReplicas := currentReplicas + s.replicasChangedWithin(timestamp, policy.PeriodSeconds, false)
+		var proposed float64
+		switch policy.Type {
+		case autoscalingv2.PodsScalingPolicy:
+			proposed = periodStartReplicas - float64(policy.Value)
+		case autoscalingv2.PercentScalingPolicy:
+			proposed = math.Floor(periodStartReplicas * (1 - float64(policy.Value)/100))
+		}
+		if selectMin {
+			limit = math.Max(limit, proposed)
+		} else {
+			limit = math.Min(limit, proposed)
+		}
+	}
+	return limit, true
+}
+
+// replicasChangedWithin returns the replicas added, or removed, by the scale events within the period before the
+// timestamp.
+func (s *hpaScaler) replicasChangedWithin(timestamp time.Time, periodSeconds int32, scaleUp bool) float64 {
+	periodStart := timestamp.Add(-time.Duration(periodSeconds) * time.Second)
+	changed := 0.0
+	for _, event := range s.scaleEvents {
+		if !event.timestamp.After(periodStart) {
+			continue
+		}
+		if scaleUp && event.delta > 0 {
+			changed += event.delta
+		} else if !scaleUp && event.delta < 0 {
+			changed -= event.delta
+		}
+	}
+	return changed
+}
+
+// pruneScaleEvents drops the scale events older than the longest policy period.
+func (s *hpaScaler) pruneScaleEvents(timestamp time.Time) {
+	longestPeriod := maxDuration(longestPolicyPeriod(s.behavior.Behavior.ScaleUp),
+		longestPolicyPeriod(s.behavior.Behavior.ScaleDown))
+	retained := s.scaleEvents[:0]
+	for _, event := range s.scaleEvents {
+		if event.timestamp.After(timestamp.Add(-longestPeriod)) {
+			retained = append(retained, event)
+		}
+	}
+	s.scaleEvents = retained
+}
+
+func stabilizationWindow(rules *autoscalingv2.HPAScalingRules) time.Duration {
+	if rules == nil || rules.StabilizationWindowSeconds == nil {
+		return 0
+	}
+	return time.Duration(*rules.StabilizationWindowSeconds) * time.Second
+}
+
+func longestPolicyPeriod(rules *autoscalingv2.HPAScalingRules) time.Duration {
+	longestPeriod := time.Duration(0)
+	if rules == nil {
+		return longestPeriod
+	}
+	for _, policy := range rules.Policies {
+		longestPeriod = maxDuration(longestPeriod, time.Duration(policy.PeriodSeconds)*time.Second)
+	}
+	return longestPeriod
+}
+
+func isScalingDisabled(rules *autoscalingv2.HPAScalingRules) bool {
+	return rules.SelectPolicy != nil && *rules.SelectPolicy == autoscalingv2.DisabledPolicySelect
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,128 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"time"
+)
+
+var _ = Describe("HPABehavior", func() {
+	const (
+		step            = time.Minute
+		target          = 50
+		perPodResources = 1.0
+	)
+
+	newRecommender := func(hpaBehavior HPABehavior) *CpuUtilizationBasedRecommender {
+		return newCpuRecommender(recommenderOptions{step: step, hpaBehavior: hpaBehavior})
+	}
+
+	// series returns data points at one minute step with the given values. With a target of 50 and a single core per
+	// pod, the desired replicas are twice the value.
+	series := func(values ...float64) []metrics.DataPoint {
+		return dataPointsOf(step, values...)
+	}
+
+	int32Ptr := func(value int32) *int32 { return &value }
+	selectPolicy := func(policy autoscalingv2.ScalingPolicySelect) *autoscalingv2.ScalingPolicySelect {
+		return &policy
+	}
+
+	It("should scale down instantly when no behavior is set", func() {
+		simulation, err := newRecommender(HPABehavior{}).simulateHPA(
+			series(10, 10, 10, 5, 5, 5), 0, target, perPodResources, ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.minReplicas).To(Equal(10))
+		Expect(simulation.dataPoints[3].Value).To(BeNumerically("~", 10*redLineUtil))
+	})
+
+	It("should hold the replicas for the scale down stabilization window", func() {
+		hpaBehavior := HPABehavior{Behavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
+			ScaleDown: &autoscalingv2.HPAScalingRules{StabilizationWindowSeconds: int32Ptr(300)},
+		}}
+		simulation, err := newRecommender(hpaBehavior).simulateHPA(
+			series(10, 10, 10, 5, 5, 5, 5, 5, 5), 0, target, perPodResources, ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.minReplicas).To(Equal(10))
+		for i := 3; i < 7; i++ {
+			Expect(simulation.dataPoints[i].Value).To(BeNumerically("~", 20*redLineUtil))
+		}
+		Expect(simulation.dataPoints[7].Value).To(BeNumerically("~", 10*redLineUtil))
+	})
+
+	It("should not scale within the tolerance band", func() {
+		simulation, err := newRecommender(HPABehavior{}).simulateHPA(
+			series(10, 10.5), 0, target, perPodResources, ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.maxReplicas).To(Equal(21))
+
+		simulation, err = newRecommender(HPABehavior{Tolerance: 0.1}).simulateHPA(
+			series(10, 10.5), 0, target, perPodResources, ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.maxReplicas).To(Equal(20))
+	})
+
+	It("should limit scale ups by the scale up policies", func() {
+		hpaBehavior := HPABehavior{Behavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
+			ScaleUp: &autoscalingv2.HPAScalingRules{
+				Policies: []autoscalingv2.HPAScalingPolicy{
+					{Type: autoscalingv2.PodsScalingPolicy, Value: 2, PeriodSeconds: 60},
+				},
+			},
+		}}
+		simulation, err := newRecommender(hpaBehavior).simulateHPA(
+			series(10, 20, 20, 20, 20), 0, target, perPodResources, ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.maxReplicas).To(Equal(28))
+	})
+
+	It("should pick the policy allowing the most change unless told otherwise", func() {
+		policies := []autoscalingv2.HPAScalingPolicy{
+			{Type: autoscalingv2.PodsScalingPolicy, Value: 2, PeriodSeconds: 60},
+			{Type: autoscalingv2.PercentScalingPolicy, Value: 50, PeriodSeconds: 60},
+		}
+		hpaBehavior := HPABehavior{Behavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
+			ScaleUp: &autoscalingv2.HPAScalingRules{Policies: policies},
+		}}
+		simulation, err := newRecommender(hpaBehavior).simulateHPA(
+			series(10, 20), 0, target, perPodResources, ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.maxReplicas).To(Equal(30))
+
+		hpaBehavior.Behavior.ScaleUp.SelectPolicy = selectPolicy(autoscalingv2.MinChangePolicySelect)
+		simulation, err = newRecommender(hpaBehavior).simulateHPA(
+			series(10, 20), 0, target, perPodResources, ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.maxReplicas).To(Equal(22))
+	})
+
+	It("should limit scale downs by the scale down policies", func() {
+		hpaBehavior := HPABehavior{Behavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
+			ScaleDown: &autoscalingv2.HPAScalingRules{
+				Policies: []autoscalingv2.HPAScalingPolicy{
+					{Type: autoscalingv2.PercentScalingPolicy, Value: 50, PeriodSeconds: 60},
+				},
+			},
+		}}
+		simulation, err := newRecommender(hpaBehavior).simulateHPA(
+			series(20, 1, 1, 1), 0, target, perPodResources, ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.minReplicas).To(Equal(5))
+		Expect(simulation.dataPoints[1].Value).To(BeNumerically("~", 20*redLineUtil))
+		Expect(simulation.dataPoints[2].Value).To(BeNumerically("~", 10*redLineUtil))
+	})
+
+	It("should not scale down when scale down is disabled", func() {
+		hpaBehavior := HPABehavior{Behavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
+			ScaleDown: &autoscalingv2.HPAScalingRules{
+				SelectPolicy: selectPolicy(autoscalingv2.DisabledPolicySelect),
+			},
+		}}
+		simulation, err := newRecommender(hpaBehavior).simulateHPA(
+			series(20, 1, 1, 1), 0, target, perPodResources, ReplicaBounds{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.minReplicas).To(Equal(40))
+	})
+})
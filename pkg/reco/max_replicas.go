@@ -0,0 +1,96 @@
+package reco
+
+import (
+	"context"
+	"fmt"
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"math"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// applyMaxHeadroom scales the max replicas seen in the simulation by the headroom factor, to leave room for traffic
+// growing beyond what was seen in the metric window. A factor below 1 leaves the max replicas as is. Every recommender
+// applies the maxHeadroomFactor it is built with through it.
+func applyMaxHeadroom(maxReplicas int, headroomFactor float64) int {
+	if headroomFactor <= 1 {
+		return maxReplicas
+	}
+	return int(math.Ceil(float64(maxReplicas) * headroomFactor))
+}
+
+// getQuotaMaxReplicas returns the number of pods of the workload that fit in the tightest CPU quota of the
+// namespace, and false if the namespace has no such quota. The limits.cpu quota is checked against the scheduled CPU
+// limits of a pod, and the requests.cpu and cpu quotas against its scheduled CPU requests. The pods of the other
+// workloads in the namespace aren't accounted for, so this is an upper bound of what the quota allows.
+func getQuotaMaxReplicas(k8sClient client.Client, namespace string, perPod podResources) (int, bool, error) {
+	resourceQuotas := corev1.ResourceQuotaList{}
+	if err := k8sClient.List(context.Background(), &resourceQuotas, client.InNamespace(namespace)); err != nil {
+		return 0, false, err
+	}
+
+	quotaMaxReplicas := 0
+	found := false
+	for _, resourceQuota := range resourceQuotas.Items {
+		for resourceName, perPodResources := range map[corev1.ResourceName]float64{
+			corev1.ResourceLimitsCPU:   perPod.scheduledLimits,
+			corev1.ResourceRequestsCPU: perPod.scheduledRequests,
+			corev1.ResourceCPU:         perPod.scheduledRequests,
+		} {
+			hard, ok := resourceQuota.Spec.Hard[resourceName]
+			if !ok || perPodResources <= 0 {
+				continue
+			}
+			replicas := int(math.Floor(float64(hard.MilliValue()) / 1000 / perPodResources))
+			if !found || replicas < quotaMaxReplicas {
+				quotaMaxReplicas = replicas
+				found = true
+			}
+		}
+	}
+	return quotaMaxReplicas, found, nil
+}
+
+// capMaxReplicasByQuota caps the max replicas to the number of pods that fit in the CPU quota of the namespace. The
+// max replicas are never capped below the min replicas, as an HPA can't scale below them anyway. A Warning is
+// returned when the cap wins.
+func capMaxReplicasByQuota(k8sClient client.Client,
+	namespace string,
+	perPod podResources,
+	minReplicas int,
+	maxReplicas int) (int, *Warning, error) {
+
+	quotaMaxReplicas, found, err := getQuotaMaxReplicas(k8sClient, namespace, perPod)
+	if err != nil || !found || maxReplicas <= quotaMaxReplicas {
+		return maxReplicas, nil, err
+	}
+
+	cappedMaxReplicas := quotaMaxReplicas
+	if cappedMaxReplicas < minReplicas {
+		cappedMaxReplicas = minReplicas
+	}
+	return cappedMaxReplicas, &Warning{
+		Reason: MaxCappedByQuota,
+		Message: fmt.Sprintf("max replicas capped from %d to %d by the cpu quota of namespace %s, which fits %d pods",
+			maxReplicas, cappedMaxReplicas, namespace, quotaMaxReplicas),
+	}, nil
+}
+
+// capMaxReplicasOfWorkloadByQuota caps the max replicas like capMaxReplicasByQuota, for the Recommenders that don't
+// size the pods of the workload on their CPU. The CPU resources of a pod are read off the workload, and the max
+// replicas of a workload that can't be found are left as is.
+func capMaxReplicasOfWorkloadByQuota(k8sClient client.Client,
+	workloadSpec v1alpha1.WorkloadSpec,
+	minReplicas int,
+	maxReplicas int) (int, *Warning, error) {
+
+	perPod, err := getPodResources(k8sClient, workloadSpec.Namespace, workloadSpec.Kind, workloadSpec.Name)
+	if errors.IsNotFound(err) {
+		return maxReplicas, nil, nil
+	}
+	if err != nil {
+		return maxReplicas, nil, err
+	}
+	return capMaxReplicasByQuota(k8sClient, workloadSpec.Namespace, perPod, minReplicas, maxReplicas)
+}
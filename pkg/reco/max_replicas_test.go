@@ -0,0 +1,163 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Max replicas", func() {
+
+	Context("applyMaxHeadroom", func() {
+		It("should scale the max replicas up by the headroom factor", func() {
+			Expect(applyMaxHeadroom(10, 1.2)).To(Equal(12))
+			Expect(applyMaxHeadroom(7, 1.25)).To(Equal(9))
+		})
+
+		It("should leave the max replicas as is for a factor of 1 or less", func() {
+			Expect(applyMaxHeadroom(10, 1)).To(Equal(10))
+			Expect(applyMaxHeadroom(10, 0)).To(Equal(10))
+			Expect(applyMaxHeadroom(10, 0.5)).To(Equal(10))
+		})
+	})
+
+	Context("with a cpu quota on the namespace", func() {
+		const quotaNamespace = "test-quota-namespace"
+		perPod := podResources{scheduledRequests: 8.2, scheduledLimits: 8.2}
+
+		var (
+			namespace     *corev1.Namespace
+			resourceQuota *corev1.ResourceQuota
+			deployment    *appsv1.Deployment
+		)
+
+		BeforeEach(func() {
+			namespace = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: quotaNamespace}}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(namespace), namespace); err != nil {
+				Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+			}
+
+			resourceQuota = &corev1.ResourceQuota{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-quota", Namespace: quotaNamespace},
+				Spec: corev1.ResourceQuotaSpec{
+					Hard: corev1.ResourceList{
+						corev1.ResourceLimitsCPU: resource.MustParse("100"),
+						corev1.ResourcePods:      resource.MustParse("500"),
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resourceQuota)).To(Succeed())
+
+			deployment = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-quota-deployment", Namespace: quotaNamespace},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test-app"}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test-app"}},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "container-1",
+									Image: "container-image",
+									Resources: corev1.ResourceRequirements{
+										Limits: corev1.ResourceList{
+											corev1.ResourceCPU: resource.MustParse("8.2"),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, resourceQuota)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, deployment)).To(Succeed())
+		})
+
+		It("should return the number of pods that fit in the quota", func() {
+			quotaMaxReplicas, found, err := getQuotaMaxReplicas(k8sClient, quotaNamespace, perPod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(quotaMaxReplicas).To(Equal(12))
+		})
+
+		It("should not find a quota in a namespace without one", func() {
+			_, found, err := getQuotaMaxReplicas(k8sClient, "default", perPod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("should check the limits.cpu quota against the cpu limits of a pod alone", func() {
+			_, found, err := getQuotaMaxReplicas(k8sClient, quotaNamespace, podResources{scheduledRequests: 8.2})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("should leave the replicas that fit in the quota as is", func() {
+			maxReplicas, warning, err := capMaxReplicasByQuota(k8sClient, quotaNamespace, perPod, 3, 12)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warning).To(BeNil())
+			Expect(maxReplicas).To(Equal(12))
+		})
+
+		It("should cap the recommendation by the quota and raise a warning", func() {
+			recommendation, err := recommender.Recommend(v1alpha1.WorkloadSpec{
+				Name:      deployment.Name,
+				Namespace: quotaNamespace,
+				TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recommendation.HPAConfiguration).To(Equal(v1alpha1.HPAConfiguration{Min: 8, Max: 12,
+				TargetMetricValue: 52}))
+			Expect(recommendation.Warnings).To(HaveLen(1))
+			Expect(recommendation.Warnings[0].Reason).To(Equal(MaxCappedByQuota))
+			Expect(recommendation.Warnings[0].Message).To(ContainSubstring("from 24 to 12"))
+		})
+
+		It("should cap the recommendations on other metrics than the cpu by the quota", func() {
+			workloadSpec := v1alpha1.WorkloadSpec{
+				Name:      deployment.Name,
+				Namespace: quotaNamespace,
+				TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			}
+			memoryRecommender := newMemoryRecommender(recommenderOptions{})
+			for _, recommender := range []Recommender{
+				memoryRecommender,
+				NewMultiMetricRecommender(k8sClient, maxHeadroomFactor, HPABehavior{}, ReplicaBounds{}, logger,
+					memoryRecommender),
+			} {
+				recommendation, err := recommender.Recommend(workloadSpec)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(recommendation.HPAConfiguration.Max).To(Equal(12))
+				Expect(recommendation.Warnings).To(HaveLen(1))
+				Expect(recommendation.Warnings[0].Reason).To(Equal(MaxCappedByQuota))
+			}
+		})
+
+		It("should not cap the max replicas below the min replicas", func() {
+			maxReplicas, warning, err := capMaxReplicasByQuota(k8sClient, quotaNamespace, perPod, 15, 20)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warning).NotTo(BeNil())
+			Expect(warning.Message).To(ContainSubstring("from 20 to 15"))
+			Expect(maxReplicas).To(Equal(15))
+		})
+
+		It("should keep the max replicas of a pod larger than the quota at the min replicas", func() {
+			maxReplicas, warning, err := capMaxReplicasByQuota(k8sClient, quotaNamespace,
+				podResources{scheduledRequests: 200, scheduledLimits: 200}, 1, 4)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warning).NotTo(BeNil())
+			Expect(warning.Message).To(ContainSubstring("which fits 0 pods"))
+			Expect(maxReplicas).To(Equal(1))
+		})
+	})
+})
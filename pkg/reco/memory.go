@@ -0,0 +1,194 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	"github.com/go-logr/logr"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"math"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+// MemoryUtilizationBasedRecommender generates the HPAConfiguration of memory bound workloads. It simulates the HPA
+// over the memory working set of the workload, with the pods sized on their memory limits as that is where they are
+// killed. The target utilization is reported against the memory requests, which is what the HPA measures memory
+// utilization against.
+type MemoryUtilizationBasedRecommender struct {
+	simulator
+	k8sClient         client.Client
+	metricWindow      time.Duration
+	scraper           metrics.Scraper
+	minTarget         int
+	maxTarget         int
+	maxHeadroomFactor float64
+	replicaBounds     ReplicaBounds
+}
+
+func NewMemoryUtilizationBasedRecommender(k8sClient client.Client,
+	redLineUtil float64,
+	metricWindow time.Duration,
+	scraper metrics.Scraper,
+	metricStep time.Duration,
+	minTarget int,
+	maxTarget int,
+	maxHeadroomFactor float64,
+	breachBudget BreachBudget,
+	hpaBehavior HPABehavior,
+	replicaBounds ReplicaBounds,
+	forecast ForecastConfig,
+	logger logr.Logger) *MemoryUtilizationBasedRecommender {
+	return &MemoryUtilizationBasedRecommender{
+		simulator: simulator{
+			redLineUtil:  redLineUtil,
+			metricStep:   metricStep,
+			breachBudget: breachBudget,
+			hpaBehavior:  hpaBehavior,
+			forecast:     forecast,
+			logger:       logger,
+		},
+		k8sClient:         k8sClient,
+		metricWindow:      metricWindow,
+		scraper:           scraper,
+		minTarget:         minTarget,
+		maxTarget:         maxTarget,
+		maxHeadroomFactor: maxHeadroomFactor,
+		replicaBounds:     replicaBounds,
+	}
+}
+
+func (m *MemoryUtilizationBasedRecommender) Recommend(workloadSpec v1alpha1.WorkloadSpec) (*Recommendation, error) {
+	memoryRecommendation, err := m.recommendMetric(workloadSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendation := &Recommendation{
+		Warnings:     memoryRecommendation.warnings,
+		BreachBudget: &memoryRecommendation.breachBudget,
+		Forecast:     memoryRecommendation.forecast,
+		ACL:          memoryRecommendation.acl,
+		Explanation:  newExplanation(memoryRecommendation.acl, memoryRecommendation.explanation),
+		// The time ranges of the metric window left out of the simulation.
+		ExcludedMetricRanges: memoryRecommendation.excludedRanges,
+	}
+	maxReplicas, warning, err := capMaxReplicasOfWorkloadByQuota(m.k8sClient, workloadSpec,
+		memoryRecommendation.minReplicas, applyMaxHeadroom(memoryRecommendation.maxReplicas, m.maxHeadroomFactor))
+	if err != nil {
+		m.logger.Error(err, "Error while capping the max replicas by the namespace quota")
+		return nil, err
+	}
+	if warning != nil {
+		recommendation.Warnings = append(recommendation.Warnings, *warning)
+	}
+
+	// The HPA scales on the memory utilization alone, so the target goes in the Metrics rather than in the
+	// TargetMetricValue, which stands for the cpu utilization.
+	metricTarget := memoryRecommendation.metricTarget()
+	metricTarget.Type = autoscalingv2.ResourceMetricSourceType
+	recommendation.HPAConfiguration = v1alpha1.HPAConfiguration{
+		Min:     memoryRecommendation.minReplicas,
+		Max:     maxReplicas,
+		Metrics: []v1alpha1.MetricTarget{metricTarget},
+	}
+	return recommendation, nil
+}
+
+// recommendMetric finds the optimal target of the memory utilization for the workload, and the replicas the
+// simulated HPA scaled between at that target.
+func (m *MemoryUtilizationBasedRecommender) recommendMetric(workloadSpec v1alpha1.WorkloadSpec) (*metricRecommendation,
+	error) {
+
+	end := time.Now()
+	start := end.Add(-m.metricWindow)
+
+	dataPoints, err := m.scraper.GetMemoryWorkingSetByWorkload(workloadSpec.Namespace,
+		workloadSpec.Name,
+		start,
+		end,
+		m.metricStep)
+	if err != nil {
+		m.logger.Error(err, "Error while scraping GetMemoryWorkingSetByWorkload.")
+		return nil, &RecommendationError{Kind: MetricsUnavailable, Err: err}
+	}
+	if len(dataPoints) == 0 {
+		return nil, newRecommendationError(InsufficientData, "no memory working set data points found between %s and %s",
+			start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+
+	dataPoints, excludedRanges, err := excludeMetricRanges(m.k8sClient, workloadSpec, dataPoints)
+	if err != nil {
+		m.logger.Error(err, "Error while getting the metric exclusions")
+		return nil, err
+	}
+	if len(observedDataPoints(dataPoints)) == 0 {
+		return nil, newRecommendationError(InsufficientData,
+			"all the memory working set data points between %s and %s are excluded",
+			start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+
+	acl, err := getAutoscalingCycleLag(m.k8sClient, m.scraper, workloadSpec)
+	if err != nil {
+		m.logger.Error(err, "Error while getting GetACL.")
+		return nil, err
+	}
+
+	perPodLimits, err := m.scraper.GetMemoryLimitsPerPodByWorkload(workloadSpec.Namespace, workloadSpec.Name)
+	if err != nil {
+		m.logger.Error(err, "Error while getting GetMemoryLimitsPerPodByWorkload.")
+		return nil, &RecommendationError{Kind: MetricsUnavailable, Err: err}
+	}
+	if perPodLimits <= 0 {
+		return nil, newRecommendationError(InvalidResources, "no memory limits set on the containers of %s %s/%s",
+			workloadSpec.Kind, workloadSpec.Namespace, workloadSpec.Name)
+	}
+
+	perPodRequests, err := m.scraper.GetMemoryRequestsPerPodByWorkload(workloadSpec.Namespace, workloadSpec.Name)
+	if err != nil {
+		m.logger.Error(err, "Error while getting GetMemoryRequestsPerPodByWorkload.")
+		return nil, &RecommendationError{Kind: MetricsUnavailable, Err: err}
+	}
+	if perPodRequests <= 0 {
+		return nil, newRecommendationError(InvalidResources, "no memory requests set on the containers of %s %s/%s",
+			workloadSpec.Kind, workloadSpec.Namespace, workloadSpec.Name)
+	}
+
+	// The HPA is simulated against the forecast of the demand, if one is configured.
+	demand, forecast := m.forecastDemand(dataPoints)
+
+	search, err := m.findOptimalTargetUtilization(demand, acl.Total.Duration, m.minTarget, m.maxTarget, perPodLimits,
+		m.replicaBounds)
+	if err != nil {
+		m.logger.Error(err, "Error while executing findOptimalTargetUtilization")
+		return nil, err
+	}
+
+	recommendation := &metricRecommendation{
+		series: metricSeries{
+			name:            MemoryMetric,
+			step:            m.metricStep,
+			dataPoints:      demand,
+			perPodResources: perPodLimits,
+			target:          search.target,
+		},
+		targetValue:    targetOfRequests(search.target, perPodLimits, perPodRequests),
+		minReplicas:    search.minReplicas,
+		maxReplicas:    search.maxReplicas,
+		breachBudget:   search.usage,
+		forecast:       forecast,
+		acl:            acl,
+		excludedRanges: excludedRanges,
+		explanation: newMetricExplanation(MemoryMetric, m.metricStep, demand, perPodLimits, search.target,
+			search.candidates, forecast),
+	}
+	if search.warning != nil {
+		recommendation.warnings = append(recommendation.warnings, *search.warning)
+	}
+	return recommendation, nil
+}
+
+// targetOfRequests converts a target utilization of the memory limits to the utilization of the memory requests it
+// stands for. It may exceed 100 for pods with limits above their requests.
+func targetOfRequests(targetOfLimits int, perPodLimits float64, perPodRequests float64) int {
+	return int(math.Floor(float64(targetOfLimits) * perPodLimits / perPodRequests))
+}
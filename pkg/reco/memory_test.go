@@ -0,0 +1,80 @@
+package reco
+
+import (
+	"errors"
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MemoryResourcesScraper returns the configured memory limits and requests per pod, and the error if one is set.
+type MemoryResourcesScraper struct {
+	FakeScraper
+	limits   float64
+	requests float64
+	err      error
+}
+
+func (ms *MemoryResourcesScraper) GetMemoryLimitsPerPodByWorkload(namespace,
+	workload string) (float64, error) {
+	return ms.limits, ms.err
+}
+
+func (ms *MemoryResourcesScraper) GetMemoryRequestsPerPodByWorkload(namespace,
+	workload string) (float64, error) {
+	return ms.requests, ms.err
+}
+
+var _ = Describe("MemoryUtilizationBasedRecommender", func() {
+	workloadSpec := v1alpha1.WorkloadSpec{
+		Name:      "test-memory-workload",
+		Namespace: "default",
+		TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+	}
+
+	newRecommender := func(scraper metrics.Scraper) *MemoryUtilizationBasedRecommender {
+		return newMemoryRecommender(recommenderOptions{scraper: scraper})
+	}
+
+	It("should size the pods on the limits and report the target against the requests", func() {
+		// The fake working set is the same as the cpu utilization, and the limits match the cpu limits of the
+		// cpu recommender tests, so the replicas match and the target of the limits is 52.
+		recommendation, err := newRecommender(fakeScraper).Recommend(workloadSpec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recommendation.HPAConfiguration.Min).To(Equal(8))
+		Expect(recommendation.HPAConfiguration.Max).To(Equal(24))
+		Expect(recommendation.HPAConfiguration.TargetMetricValue).To(BeZero())
+		Expect(recommendation.HPAConfiguration.Metrics).To(Equal([]v1alpha1.MetricTarget{
+			{Name: MemoryMetric, Type: autoscalingv2.ResourceMetricSourceType, TargetValue: 104},
+		}))
+		Expect(recommendation.BreachBudget).NotTo(BeNil())
+	})
+
+	It("should return InvalidResources when the containers have no memory limits", func() {
+		_, err := newRecommender(&MemoryResourcesScraper{requests: 4.1}).Recommend(workloadSpec)
+		Expect(KindOf(err)).To(Equal(InvalidResources))
+		Expect(err.Error()).To(ContainSubstring("no memory limits"))
+	})
+
+	It("should return InvalidResources when the containers have no memory requests", func() {
+		_, err := newRecommender(&MemoryResourcesScraper{limits: 8.2}).Recommend(workloadSpec)
+		Expect(KindOf(err)).To(Equal(InvalidResources))
+		Expect(err.Error()).To(ContainSubstring("no memory requests"))
+	})
+
+	It("should return MetricsUnavailable when the memory resources can't be scraped", func() {
+		_, err := newRecommender(&MemoryResourcesScraper{err: errors.New("prometheus is down")}).
+			Recommend(workloadSpec)
+		Expect(KindOf(err)).To(Equal(MetricsUnavailable))
+		Expect(IsRetryable(err)).To(BeTrue())
+	})
+
+	It("should convert a target of the limits to a target of the requests", func() {
+		Expect(targetOfRequests(50, 2, 1)).To(Equal(100))
+		Expect(targetOfRequests(60, 1, 1)).To(Equal(60))
+		Expect(targetOfRequests(45, 3, 2)).To(Equal(67))
+	})
+})
@@ -0,0 +1,337 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	"github.com/go-logr/logr"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"math"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+const (
+	// CPUMetric is the name of the MetricTarget for the cpu utilization.
+	CPUMetric = "cpu"
+	// MemoryMetric is the name of the MetricTarget for the memory utilization.
+	MemoryMetric = "memory"
+)
+
+// metricSeries is the demand of a workload on one of the metrics its HPA scales on, in the units of the capacity of
+// a pod for the metric. target is the target utilization of that capacity.
+type metricSeries struct {
+	name string
+	// step is the interval between the data points.
+	step            time.Duration
+	dataPoints      []metrics.DataPoint
+	perPodResources float64
+	target          int
+}
+
+// metricRecommendation is what a metricRecommender found for a workload on its metric.
+type metricRecommendation struct {
+	series metricSeries
+	// metricType is the type of the source of the metric on the HPA. Empty means Resource.
+	metricType autoscalingv2.MetricSourceType
+	// targetValue is the target of the metric as set on the HPA, which may be in other units than the target of the
+	// series.
+	targetValue int
+	// averageValue is the target of the value of the metric per pod, for Pods and External metrics.
+	averageValue *resource.Quantity
+	minReplicas  int
+	maxReplicas  int
+	// replicas is the replicas the HPA scaled the workload to at every data point of the series, set by the CPU
+	// metric.
+	replicas     []float64
+	breachBudget v1alpha1.BreachBudgetUsage
+	forecast     *Forecast
+	acl          *v1alpha1.AutoscalingCycleLag
+	// explanation is the trace of the search for the target of the metric.
+	explanation *MetricExplanation
+	// podResources are the CPU resources of a pod of the workload, set by the CPU metric.
+	podResources podResources
+	// excludedRanges are the time ranges of the metric window the metric was simulated without.
+	excludedRanges []v1alpha1.ExcludedMetricRange
+	warnings       []Warning
+}
+
+// metricTarget returns the MetricTarget of the HPA for the recommendation.
+func (r *metricRecommendation) metricTarget() v1alpha1.MetricTarget {
+	return v1alpha1.MetricTarget{
+		Name:         r.series.name,
+		Type:         r.metricType,
+		TargetValue:  r.targetValue,
+		AverageValue: r.averageValue,
+	}
+}
+
+// metricRecommender is a Recommender that sizes the HPA on a single metric. The MultiMetricRecommender combines the
+// metrics of several of them.
+type metricRecommender interface {
+	Recommender
+	recommendMetric(workloadSpec v1alpha1.WorkloadSpec) (*metricRecommendation, error)
+}
+
+// multiMetricSimulation is the outcome of simulating an HPA that scales on several metrics.
+type multiMetricSimulation struct {
+	minReplicas int
+	maxReplicas int
+	// replicas is the replicas the HPA had scaled the workload to at each of the data points of the first series.
+	replicas []float64
+	// peakReplicas holds the replicas each metric asked for when the simulated replicas peaked, in the order of the
+	// series.
+	peakReplicas []int
+}
+
+// simulateMultiMetricHPA simulates the replicas of an HPA scaling on all the metrics at their targets. At every step
+// each metric proposes replicas for its target, within the tolerance, and the HPA scales to the most replicas
+// proposed, as autoscaling/v2 HPAs do. The capacity isn't simulated, as every metric was checked against its breach
+// budget when its target was found, and scaling on more metrics only adds replicas. The steps are those of the first
+// series, and the other series are sampled at their latest data point no later than the step that isn't a Gap.
+func (s *simulator) simulateMultiMetricHPA(series []metricSeries, bounds ReplicaBounds) *multiMetricSimulation {
+
+	simulation := &multiMetricSimulation{peakReplicas: make([]int, len(series))}
+	if len(series) == 0 || len(series[0].dataPoints) == 0 {
+		return simulation
+	}
+	simulation.replicas = make([]float64, len(series[0].dataPoints))
+
+	// next holds the index of the next data point of each series to sample.
+	next := make([]int, len(series))
+	values := make([]float64, len(series))
+	sample := func(timestamp time.Time) {
+		for i := range series {
+			for next[i] < len(series[i].dataPoints) && !series[i].dataPoints[next[i]].Timestamp.After(timestamp) {
+				if !series[i].dataPoints[next[i]].Gap {
+					values[i] = series[i].dataPoints[next[i]].Value
+				}
+				next[i]++
+			}
+		}
+	}
+	desiredByMetric := func() []float64 {
+		desired := make([]float64, len(series))
+		for i := range series {
+			desired[i] = math.Ceil((100 * values[i]) / float64(series[i].target) / series[i].perPodResources)
+		}
+		return desired
+	}
+	recordPeak := func(desired []float64) {
+		for i := range desired {
+			simulation.peakReplicas[i] = int(desired[i])
+		}
+	}
+
+	// The simulation starts at the first data point of the first series that isn't a Gap.
+	first := 0
+	for first < len(series[0].dataPoints)-1 && series[0].dataPoints[first].Gap {
+		first++
+	}
+	sample(series[0].dataPoints[first].Timestamp)
+	desired := desiredByMetric()
+	currentReplicas := 0.0
+	for _, replicas := range desired {
+		currentReplicas = math.Max(currentReplicas, replicas)
+	}
+	currentReplicas, _ = bounds.clamp(currentReplicas)
+	simulation.replicas[first] = currentReplicas
+	minReplicas, maxReplicas := currentReplicas, currentReplicas
+	recordPeak(desired)
+
+	scaler := newHPAScaler(s.hpaBehavior)
+	for i := first + 1; i < len(series[0].dataPoints); i++ {
+		dp := series[0].dataPoints[i]
+		// The HPA holds its replicas through a gap in the metrics of the first series, which sets the steps.
+		simulation.replicas[i] = currentReplicas
+		if dp.Gap {
+			continue
+		}
+		sample(dp.Timestamp)
+		desired = desiredByMetric()
+
+		proposedReplicas := 0.0
+		for i := range series {
+			utilizationRatio := (100 * values[i]) / (currentReplicas * series[i].perPodResources) /
+				float64(series[i].target)
+			proposedReplicas = math.Max(proposedReplicas,
+				scaler.applyTolerance(currentReplicas, desired[i], utilizationRatio))
+		}
+		newReplicas, _ := bounds.clamp(scaler.scaleToDesired(dp.Timestamp, currentReplicas, proposedReplicas))
+		currentReplicas = newReplicas
+		simulation.replicas[i] = newReplicas
+
+		minReplicas = math.Min(newReplicas, minReplicas)
+		if newReplicas > maxReplicas {
+			maxReplicas = newReplicas
+			recordPeak(desired)
+		}
+	}
+
+	simulation.minReplicas = int(minReplicas)
+	simulation.maxReplicas = int(maxReplicas)
+	return simulation
+}
+
+// MultiMetricRecommender generates the HPAConfiguration of an HPA that scales on the metrics of several
+// Recommenders. Every metric keeps the target its own Recommender found, and the replicas are those of an HPA scaling
+// on all of them.
+type MultiMetricRecommender struct {
+	simulator
+	k8sClient         client.Client
+	recommenders      []metricRecommender
+	maxHeadroomFactor float64
+	replicaBounds     ReplicaBounds
+}
+
+func NewMultiMetricRecommender(k8sClient client.Client,
+	maxHeadroomFactor float64,
+	hpaBehavior HPABehavior,
+	replicaBounds ReplicaBounds,
+	logger logr.Logger,
+	recommenders ...metricRecommender) *MultiMetricRecommender {
+	return &MultiMetricRecommender{
+		simulator: simulator{
+			hpaBehavior: hpaBehavior,
+			logger:      logger,
+		},
+		k8sClient:         k8sClient,
+		recommenders:      recommenders,
+		maxHeadroomFactor: maxHeadroomFactor,
+		replicaBounds:     replicaBounds,
+	}
+}
+
+func (m *MultiMetricRecommender) Recommend(workloadSpec v1alpha1.WorkloadSpec) (*Recommendation, error) {
+	recommendation := &Recommendation{}
+	series := make([]metricSeries, 0, len(m.recommenders))
+	metricTargets := make([]v1alpha1.MetricTarget, 0, len(m.recommenders))
+	explanations := make([]*MetricExplanation, 0, len(m.recommenders))
+	// The cpu of a pod is known when the cpu metric sets the steps of the simulation.
+	var cpuRecommended *metricRecommendation
+	for i, recommender := range m.recommenders {
+		recommended, err := recommender.recommendMetric(workloadSpec)
+		if err != nil {
+			return nil, err
+		}
+		// The current core hours are known over the data points of the cpu metric, so the cost is only estimated
+		// when they set the steps of the simulation.
+		if i == 0 && recommended.series.name == CPUMetric {
+			cpuRecommended = recommended
+		}
+		series = append(series, recommended.series)
+		metricTargets = append(metricTargets, recommended.metricTarget())
+		// The budget of the metric that consumed the most of it is the one reported.
+		if recommendation.BreachBudget == nil ||
+			recommended.breachBudget.Consumed.Duration > recommendation.BreachBudget.Consumed.Duration {
+			breachBudget := recommended.breachBudget
+			recommendation.BreachBudget = &breachBudget
+		}
+		recommendation.Warnings = append(recommendation.Warnings, recommended.warnings...)
+		// The forecast of the first metric is reported, as every metric is forecast the same way.
+		if recommendation.Forecast == nil {
+			recommendation.Forecast = recommended.forecast
+		}
+		if recommendation.ACL == nil {
+			recommendation.ACL = recommended.acl
+		}
+		recommendation.ExcludedMetricRanges = appendExcludedMetricRanges(recommendation.ExcludedMetricRanges,
+			recommended.excludedRanges...)
+		explanations = append(explanations, recommended.explanation)
+	}
+	recommendation.Explanation = newExplanation(recommendation.ACL, explanations...)
+
+	simulation := m.simulateMultiMetricHPA(series, m.replicaBounds)
+	for i := range series {
+		recommendation.MetricContributions = append(recommendation.MetricContributions, v1alpha1.MetricContribution{
+			Name:         series[i].name,
+			PeakReplicas: simulation.peakReplicas[i],
+		})
+	}
+	maxReplicas := applyMaxHeadroom(simulation.maxReplicas, m.maxHeadroomFactor)
+	var warning *Warning
+	var err error
+	if cpuRecommended != nil {
+		maxReplicas, warning, err = capMaxReplicasByQuota(m.k8sClient, workloadSpec.Namespace,
+			cpuRecommended.podResources, simulation.minReplicas, maxReplicas)
+	} else {
+		maxReplicas, warning, err = capMaxReplicasOfWorkloadByQuota(m.k8sClient, workloadSpec, simulation.minReplicas,
+			maxReplicas)
+	}
+	if err != nil {
+		m.logger.Error(err, "Error while capping the max replicas by the namespace quota")
+		return nil, err
+	}
+	if warning != nil {
+		recommendation.Warnings = append(recommendation.Warnings, *warning)
+	}
+
+	recommendation.HPAConfiguration = v1alpha1.HPAConfiguration{
+		Min:     simulation.minReplicas,
+		Max:     maxReplicas,
+		Metrics: metricTargets,
+	}
+	for _, metricTarget := range metricTargets {
+		if metricTarget.Name == CPUMetric {
+			recommendation.HPAConfiguration.TargetMetricValue = metricTarget.TargetValue
+		}
+	}
+
+	if cpuRecommended != nil {
+		// The cost is only informational, so failing to estimate it doesn't hold the recommendation back.
+		recommendation.costModel, err = m.newCostModel(m.k8sClient, workloadSpec, cpuRecommended,
+			recommendation.HPAConfiguration, simulation.replicas,
+			func(hpaConfiguration v1alpha1.HPAConfiguration) ([]float64, error) {
+				return m.simulateMultiMetricHPA(seriesAtTargets(series, hpaConfiguration, cpuRecommended.podResources),
+					ReplicaBounds{Min: hpaConfiguration.Min, Max: hpaConfiguration.Max}).replicas, nil
+			})
+		if err != nil {
+			m.logger.Error(err, "Error while estimating the cost of the recommendation")
+		}
+	}
+	return recommendation, nil
+}
+
+// seriesAtTargets returns the series with the cpu series at the cpu target of the HPAConfiguration, which is a
+// utilization of the requests of a pod. The target of the other metrics isn't bounded by a policy, so they are
+// returned as is.
+func seriesAtTargets(series []metricSeries,
+	hpaConfiguration v1alpha1.HPAConfiguration,
+	cpuPodResources podResources) []metricSeries {
+
+	atTargets := make([]metricSeries, len(series))
+	copy(atTargets, series)
+	for i := range atTargets {
+		if atTargets[i].name != CPUMetric {
+			continue
+		}
+		for _, metricTarget := range hpaConfiguration.Metrics {
+			if metricTarget.Name == CPUMetric {
+				// The target may be over 100% of the requests, so the series is at the full utilization of the
+				// cores a pod is scaled at instead, which asks for the same replicas.
+				atTargets[i].target = 100
+				atTargets[i].perPodResources = cpuPodResources.requests * float64(metricTarget.TargetValue) / 100
+			}
+		}
+	}
+	return atTargets
+}
+
+// appendExcludedMetricRanges appends the ranges of the MetricExclusions not listed yet, as the same exclusion may
+// apply to several metrics.
+func appendExcludedMetricRanges(excludedRanges []v1alpha1.ExcludedMetricRange,
+	ranges ...v1alpha1.ExcludedMetricRange) []v1alpha1.ExcludedMetricRange {
+	for _, excludedRange := range ranges {
+		listed := false
+		for _, existing := range excludedRanges {
+			if existing.Exclusion == excludedRange.Exclusion {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			excludedRanges = append(excludedRanges, excludedRange)
+		}
+	}
+	return excludedRanges
+}
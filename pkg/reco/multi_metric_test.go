@@ -0,0 +1,143 @@
+package reco
+
+import (
+	"errors"
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+// FixedMetricRecommender returns the configured metricRecommendation, or the error if one is set.
+type FixedMetricRecommender struct {
+	recommendation metricRecommendation
+	err            error
+}
+
+func (fr *FixedMetricRecommender) Recommend(workloadSpec v1alpha1.WorkloadSpec) (*Recommendation, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (fr *FixedMetricRecommender) recommendMetric(workloadSpec v1alpha1.WorkloadSpec) (*metricRecommendation,
+	error) {
+	if fr.err != nil {
+		return nil, fr.err
+	}
+	recommendation := fr.recommendation
+	return &recommendation, nil
+}
+
+var _ = Describe("MultiMetricRecommender", func() {
+	start := time.Now().Add(-time.Hour)
+
+	// series returns data points at one minute step with the given values. With a target of 50 and a single unit
+	// per pod, a metric asks for twice its value in replicas.
+	series := func(name string, values ...float64) metricSeries {
+		dataPoints := make([]metrics.DataPoint, len(values))
+		for i, value := range values {
+			dataPoints[i] = metrics.DataPoint{Timestamp: start.Add(time.Duration(i) * time.Minute), Value: value}
+		}
+		return metricSeries{name: name, dataPoints: dataPoints, perPodResources: 1, target: 50}
+	}
+
+	newSimulator := func() *simulator {
+		return &simulator{redLineUtil: redLineUtil, metricStep: time.Minute, logger: logger}
+	}
+
+	Context("simulateMultiMetricHPA", func() {
+		It("should scale to the most replicas any of the metrics asks for", func() {
+			simulation := newSimulator().simulateMultiMetricHPA([]metricSeries{
+				series(CPUMetric, 10, 30, 10, 10),
+				series(MemoryMetric, 20, 20, 20, 40),
+			}, ReplicaBounds{})
+			Expect(simulation.minReplicas).To(Equal(40))
+			Expect(simulation.maxReplicas).To(Equal(80))
+			// The memory peak at the last step set the max replicas.
+			Expect(simulation.peakReplicas).To(Equal([]int{20, 80}))
+		})
+
+		It("should clamp the replicas to the bounds", func() {
+			simulation := newSimulator().simulateMultiMetricHPA([]metricSeries{
+				series(CPUMetric, 10, 30, 10, 10),
+				series(MemoryMetric, 20, 20, 20, 40),
+			}, ReplicaBounds{Max: 70})
+			Expect(simulation.maxReplicas).To(Equal(70))
+			// The memory still set the max replicas, though it asked for more than the bound.
+			Expect(simulation.peakReplicas).To(Equal([]int{20, 80}))
+		})
+
+		It("should sample the other metrics at their latest data point", func() {
+			memory := series(MemoryMetric, 25)
+			simulation := newSimulator().simulateMultiMetricHPA([]metricSeries{
+				series(CPUMetric, 10, 30, 10),
+				memory,
+			}, ReplicaBounds{})
+			Expect(simulation.minReplicas).To(Equal(50))
+			Expect(simulation.maxReplicas).To(Equal(60))
+			Expect(simulation.peakReplicas).To(Equal([]int{60, 50}))
+		})
+
+		It("should return no replicas without data points", func() {
+			simulation := newSimulator().simulateMultiMetricHPA([]metricSeries{}, ReplicaBounds{})
+			Expect(simulation.maxReplicas).To(BeZero())
+		})
+	})
+
+	Context("Recommend", func() {
+		workloadSpec := v1alpha1.WorkloadSpec{
+			Name:      "test-multi-metric-workload",
+			Namespace: "default",
+			TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+		}
+
+		cpuRecommender := &FixedMetricRecommender{recommendation: metricRecommendation{
+			series:       series(CPUMetric, 10, 30, 10, 10),
+			targetValue:  50,
+			breachBudget: v1alpha1.BreachBudgetUsage{Consumed: metav1.Duration{Duration: time.Minute}},
+		}}
+		memoryRecommender := &FixedMetricRecommender{recommendation: metricRecommendation{
+			series:       series(MemoryMetric, 20, 20, 20, 40),
+			targetValue:  100,
+			breachBudget: v1alpha1.BreachBudgetUsage{Consumed: metav1.Duration{Duration: 2 * time.Minute}},
+			warnings:     []Warning{{Reason: BreachesAtMaxReplicas, Message: "memory needs more replicas"}},
+		}}
+
+		It("should list the target of every metric and report their contribution to the peak", func() {
+			recommendation, err := NewMultiMetricRecommender(k8sClient, 1.0, HPABehavior{}, ReplicaBounds{}, logger,
+				cpuRecommender, memoryRecommender).Recommend(workloadSpec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recommendation.HPAConfiguration).To(Equal(v1alpha1.HPAConfiguration{
+				Min:               40,
+				Max:               80,
+				TargetMetricValue: 50,
+				Metrics: []v1alpha1.MetricTarget{
+					{Name: CPUMetric, TargetValue: 50},
+					{Name: MemoryMetric, TargetValue: 100},
+				},
+			}))
+			Expect(recommendation.MetricContributions).To(Equal([]v1alpha1.MetricContribution{
+				{Name: CPUMetric, PeakReplicas: 20},
+				{Name: MemoryMetric, PeakReplicas: 80},
+			}))
+			Expect(recommendation.BreachBudget.Consumed.Duration).To(Equal(2 * time.Minute))
+			Expect(recommendation.Warnings).To(HaveLen(1))
+		})
+
+		It("should apply the max headroom to the combined max replicas", func() {
+			recommendation, err := NewMultiMetricRecommender(k8sClient, 1.5, HPABehavior{}, ReplicaBounds{}, logger,
+				cpuRecommender, memoryRecommender).Recommend(workloadSpec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recommendation.HPAConfiguration.Max).To(Equal(120))
+		})
+
+		It("should fail when any of the metrics fails", func() {
+			failingRecommender := &FixedMetricRecommender{
+				err: newRecommendationError(MetricsUnavailable, "prometheus is down")}
+			_, err := NewMultiMetricRecommender(k8sClient, 1.0, HPABehavior{}, ReplicaBounds{}, logger,
+				cpuRecommender, failingRecommender).Recommend(workloadSpec)
+			Expect(KindOf(err)).To(Equal(MetricsUnavailable))
+		})
+	})
+})
@@ -0,0 +1,158 @@
+package reco
+
+import (
+	"context"
+	"fmt"
+	rolloutv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"math"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CapacityBasis tells which of the CPU resources of a pod its capacity is measured against.
+type CapacityBasis string
+
+const (
+	// RequestsCapacity measures the capacity of a pod against its CPU requests, which is what the HPA measures the
+	// utilization against.
+	RequestsCapacity CapacityBasis = "Requests"
+	// LimitsCapacity measures the capacity of a pod against its CPU limits. The recommended target is converted to a
+	// utilization of the requests.
+	LimitsCapacity CapacityBasis = "Limits"
+)
+
+// podResources are the CPU cores a pod of the workload requests and is limited to. requests and limits are the sums
+// over its containers, which is what the HPA measures the utilization against, and scheduledRequests and
+// scheduledLimits are what the scheduler and the resource quotas account for the pod.
+type podResources struct {
+	requests          float64
+	limits            float64
+	scheduledRequests float64
+	scheduledLimits   float64
+}
+
+// capacity returns the CPU cores of the pod the basis measures the capacity against. Anything but LimitsCapacity
+// measures it against the requests.
+func (r podResources) capacity(basis CapacityBasis) float64 {
+	if basis == LimitsCapacity {
+		return r.limits
+	}
+	return r.requests
+}
+
+// getPodResources returns the CPU resources of a pod of the workload. They are read off the newest pod of the
+// workload when there is one, so that the sidecars injected at admission and the overhead of the RuntimeClass are
+// accounted for, and off the pod template otherwise.
+func getPodResources(k8sClient client.Client, namespace, objectKind, objectName string) (podResources, error) {
+	var obj client.Object
+	switch objectKind {
+	case "Deployment":
+		obj = &appsv1.Deployment{}
+	case "Rollout":
+		obj = &rolloutv1alpha1.Rollout{}
+	default:
+		return podResources{}, newRecommendationError(UnsupportedWorkloadKind, "unsupported objectKind: %s",
+			objectKind)
+	}
+
+	if err := k8sClient.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: objectName}, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return podResources{}, &RecommendationError{Kind: WorkloadNotFound, Err: err}
+		}
+		return podResources{}, err
+	}
+
+	var podTemplateSpec *corev1.PodTemplateSpec
+	var selector *metav1.LabelSelector
+	switch v := obj.(type) {
+	case *appsv1.Deployment:
+		podTemplateSpec = &v.Spec.Template
+		selector = v.Spec.Selector
+	case *rolloutv1alpha1.Rollout:
+		podTemplateSpec = &v.Spec.Template
+		selector = v.Spec.Selector
+	default:
+		return podResources{}, fmt.Errorf("unsupported object type")
+	}
+
+	pod, err := getNewestPod(k8sClient, namespace, selector)
+	if err != nil {
+		return podResources{}, err
+	}
+	if pod != nil {
+		return podResourcesOf(pod.Spec), nil
+	}
+	return podResourcesOf(podTemplateSpec.Spec), nil
+}
+
+// getNewestPod returns the newest pod selected by the selector that isn't terminating, or nil if there is none.
+func getNewestPod(k8sClient client.Client, namespace string, selector *metav1.LabelSelector) (*corev1.Pod, error) {
+	if selector == nil {
+		return nil, nil
+	}
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	pods := corev1.PodList{}
+	if err := k8sClient.List(context.Background(), &pods, client.InNamespace(namespace),
+		client.MatchingLabelsSelector{Selector: labelSelector}); err != nil {
+		return nil, err
+	}
+
+	var newest *corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if newest == nil || newest.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			newest = pod
+		}
+	}
+	return newest, nil
+}
+
+// podResourcesOf returns the CPU resources of the pod spec. A container without a CPU request requests its limit, as
+// the API server defaults it, and a container without a CPU limit is sized on its request, as nothing caps it below
+// that. The scheduler accounts for the larger of the sum of the containers and the largest init container, as the
+// init containers run one at a time before the containers, plus the overhead of the pod.
+func podResourcesOf(spec corev1.PodSpec) podResources {
+	var resources podResources
+	for _, container := range spec.Containers {
+		requests, limits := containerCPU(container)
+		resources.requests += requests
+		resources.limits += limits
+		if limits <= 0 {
+			resources.limits += requests
+		}
+		resources.scheduledRequests += requests
+		resources.scheduledLimits += limits
+	}
+	for _, container := range spec.InitContainers {
+		requests, limits := containerCPU(container)
+		resources.scheduledRequests = math.Max(resources.scheduledRequests, requests)
+		resources.scheduledLimits = math.Max(resources.scheduledLimits, limits)
+	}
+	if overhead, ok := spec.Overhead[corev1.ResourceCPU]; ok {
+		resources.scheduledRequests += float64(overhead.MilliValue()) / 1000
+		resources.scheduledLimits += float64(overhead.MilliValue()) / 1000
+	}
+	return resources
+}
+
+func containerCPU(container corev1.Container) (float64, float64) {
+	var requests, limits float64
+	if limit, ok := container.Resources.Limits[corev1.ResourceCPU]; ok {
+		limits = float64(limit.MilliValue()) / 1000
+		requests = limits
+	}
+	if request, ok := container.Resources.Requests[corev1.ResourceCPU]; ok {
+		requests = float64(request.MilliValue()) / 1000
+	}
+	return requests, limits
+}
@@ -0,0 +1,175 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Pod resources", func() {
+	cpu := func(requests, limits string) corev1.ResourceRequirements {
+		resources := corev1.ResourceRequirements{Requests: corev1.ResourceList{}, Limits: corev1.ResourceList{}}
+		if requests != "" {
+			resources.Requests[corev1.ResourceCPU] = resource.MustParse(requests)
+		}
+		if limits != "" {
+			resources.Limits[corev1.ResourceCPU] = resource.MustParse(limits)
+		}
+		return resources
+	}
+	container := func(name, requests, limits string) corev1.Container {
+		return corev1.Container{Name: name, Image: "container-image", Resources: cpu(requests, limits)}
+	}
+
+	Context("podResourcesOf", func() {
+		It("should sum the requests and the limits of the containers", func() {
+			resources := podResourcesOf(corev1.PodSpec{Containers: []corev1.Container{
+				container("app", "1", "2"),
+				container("sidecar", "250m", "500m"),
+			}})
+			Expect(resources).To(Equal(podResources{requests: 1.25, limits: 2.5, scheduledRequests: 1.25,
+				scheduledLimits: 2.5}))
+		})
+
+		It("should default the request of a container to its limit and its limit to its request", func() {
+			resources := podResourcesOf(corev1.PodSpec{Containers: []corev1.Container{
+				container("app", "", "2"),
+				container("sidecar", "100m", ""),
+			}})
+			Expect(resources).To(Equal(podResources{requests: 2.1, limits: 2.1, scheduledRequests: 2.1,
+				scheduledLimits: 2}))
+		})
+
+		It("should account for the largest init container and the overhead in the scheduled resources alone", func() {
+			spec := corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					container("migrate", "3", "4"),
+					container("warmup", "500m", "1"),
+				},
+				Containers: []corev1.Container{container("app", "1", "2")},
+				Overhead:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+			}
+			Expect(podResourcesOf(spec)).To(Equal(podResources{requests: 1, limits: 2, scheduledRequests: 3.25,
+				scheduledLimits: 4.25}))
+
+			spec.InitContainers = spec.InitContainers[1:]
+			Expect(podResourcesOf(spec)).To(Equal(podResources{requests: 1, limits: 2, scheduledRequests: 1.25,
+				scheduledLimits: 2.25}))
+		})
+
+		It("should measure the capacity against the basis", func() {
+			resources := podResources{requests: 1, limits: 2}
+			Expect(resources.capacity(RequestsCapacity)).To(Equal(1.0))
+			Expect(resources.capacity("")).To(Equal(1.0))
+			Expect(resources.capacity(LimitsCapacity)).To(Equal(2.0))
+		})
+	})
+
+	Context("with a workload", func() {
+		const namespace = "default"
+
+		var (
+			deployment *appsv1.Deployment
+			pods       []*corev1.Pod
+		)
+
+		createDeployment := func(name string, containers ...corev1.Container) {
+			deployment = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+						Spec:       corev1.PodSpec{Containers: containers},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+		}
+
+		createPod := func(name string, labels map[string]string, containers ...corev1.Container) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+				Spec:       corev1.PodSpec{Containers: containers},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			pods = append(pods, pod)
+		}
+
+		workloadSpec := func() v1alpha1.WorkloadSpec {
+			return v1alpha1.WorkloadSpec{
+				Name:      deployment.Name,
+				Namespace: namespace,
+				TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			}
+		}
+
+		newRecommender := func(capacityBasis CapacityBasis) *CpuUtilizationBasedRecommender {
+			return newCpuRecommender(recommenderOptions{capacityBasis: capacityBasis})
+		}
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, deployment)).To(Succeed())
+			for _, pod := range pods {
+				Expect(k8sClient.Delete(ctx, pod)).To(Succeed())
+			}
+			pods = nil
+		})
+
+		It("should account for the sidecars injected in the pods of the workload", func() {
+			createDeployment("test-sidecar-deployment", container("app", "1", "2"))
+			labels := map[string]string{"app": "test-sidecar-deployment"}
+			createPod("test-sidecar-pod", labels, container("app", "1", "2"), container("istio-proxy", "500m", "1"))
+			createPod("test-unrelated-pod", map[string]string{"app": "other"}, container("app", "8", "8"))
+
+			resources, err := getPodResources(k8sClient, namespace, "Deployment", deployment.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resources).To(Equal(podResources{requests: 1.5, limits: 3, scheduledRequests: 1.5,
+				scheduledLimits: 3}))
+		})
+
+		It("should fall back to the pod template without pods", func() {
+			createDeployment("test-template-deployment", container("app", "1", "2"))
+
+			resources, err := getPodResources(k8sClient, namespace, "Deployment", deployment.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resources).To(Equal(podResources{requests: 1, limits: 2, scheduledRequests: 1, scheduledLimits: 2}))
+		})
+
+		It("should fail with a clear error without cpu requests or limits", func() {
+			createDeployment("test-no-cpu-deployment", container("app", "", ""))
+
+			_, err := newRecommender(RequestsCapacity).Recommend(workloadSpec())
+			Expect(err).To(HaveOccurred())
+			Expect(KindOf(err)).To(Equal(InvalidResources))
+			Expect(err.Error()).To(ContainSubstring("no cpu requests or limits set"))
+		})
+
+		It("should size the containers without cpu limits on their requests when sizing on the limits", func() {
+			createDeployment("test-no-limits-deployment", container("app", "4.1", ""))
+
+			limitsRecommendation, err := newRecommender(LimitsCapacity).Recommend(workloadSpec())
+			Expect(err).NotTo(HaveOccurred())
+			requestsRecommendation, err := newRecommender(RequestsCapacity).Recommend(workloadSpec())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(limitsRecommendation.HPAConfiguration).To(Equal(requestsRecommendation.HPAConfiguration))
+		})
+
+		It("should convert the target of the limits to a target of the requests", func() {
+			createDeployment("test-limits-basis-deployment", container("app", "4.1", "8.2"))
+
+			limitsRecommendation, err := newRecommender(LimitsCapacity).Recommend(workloadSpec())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(limitsRecommendation.HPAConfiguration.TargetMetricValue).To(Equal(104))
+
+			requestsRecommendation, err := newRecommender(RequestsCapacity).Recommend(workloadSpec())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requestsRecommendation.HPAConfiguration.Max).To(
+				BeNumerically(">", limitsRecommendation.HPAConfiguration.Max))
+		})
+	})
+})
@@ -0,0 +1,71 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"math"
+)
+
+// ApplyPolicy merges the optimal HPAConfiguration generated by a Recommender with the Policy assigned to the
+// workload. The more conservative of the two wins for every field: the lower target utilization, and a min replica
+// floor taken from the policy. An HPA at a lower target needs proportionally more replicas at the peak, so the max
+// replicas are scaled up with the target the policy lowers. It also returns which side set each field of the
+// resulting configuration. Policy fields that are not set (zero values) do not bound the recommendation.
+func ApplyPolicy(optimal v1alpha1.HPAConfiguration,
+	policy v1alpha1.Policy) (v1alpha1.HPAConfiguration, v1alpha1.HPAConfigurationSource) {
+
+	target := optimal
+	source := v1alpha1.HPAConfigurationSource{
+		Min:               v1alpha1.RecommenderSource,
+		Max:               v1alpha1.RecommenderSource,
+		TargetMetricValue: v1alpha1.RecommenderSource,
+	}
+
+	if policy.Spec.TargetUtilization > 0 && policy.Spec.TargetUtilization < optimal.TargetMetricValue {
+		target.TargetMetricValue = policy.Spec.TargetUtilization
+		source.TargetMetricValue = v1alpha1.PolicySource
+		if scaleMaxToTarget(&target, optimal.Max, optimal.TargetMetricValue, policy.Spec.TargetUtilization) {
+			source.Max = v1alpha1.PolicySource
+		}
+	}
+
+	// The target utilization of the policy is a cpu utilization, so it bounds the cpu target of a multi metric
+	// configuration alone.
+	if len(optimal.Metrics) > 0 {
+		target.Metrics = make([]v1alpha1.MetricTarget, len(optimal.Metrics))
+		copy(target.Metrics, optimal.Metrics)
+		for i := range target.Metrics {
+			if target.Metrics[i].Name == CPUMetric && policy.Spec.TargetUtilization > 0 &&
+				policy.Spec.TargetUtilization < target.Metrics[i].TargetValue {
+				if scaleMaxToTarget(&target, optimal.Max, target.Metrics[i].TargetValue,
+					policy.Spec.TargetUtilization) {
+					source.Max = v1alpha1.PolicySource
+				}
+				target.Metrics[i].TargetValue = policy.Spec.TargetUtilization
+			}
+		}
+	}
+
+	if policy.Spec.Min > optimal.Min {
+		target.Min = policy.Spec.Min
+		source.Min = v1alpha1.PolicySource
+	}
+
+	// The min replica floor from the policy may exceed the max replicas seen in the metric window.
+	if target.Min > target.Max {
+		target.Max = target.Min
+		source.Max = v1alpha1.PolicySource
+	}
+
+	return target, source
+}
+
+// scaleMaxToTarget raises the max replicas of the configuration to those an HPA at the lower target needs for the
+// peak that optimalMax replicas served at the optimal target, and tells whether it raised them.
+func scaleMaxToTarget(target *v1alpha1.HPAConfiguration, optimalMax int, optimalTarget int, lowerTarget int) bool {
+	scaledMax := int(math.Ceil(float64(optimalMax) * float64(optimalTarget) / float64(lowerTarget)))
+	if scaledMax <= target.Max {
+		return false
+	}
+	target.Max = scaledMax
+	return true
+}
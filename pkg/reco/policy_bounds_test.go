@@ -0,0 +1,70 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ApplyPolicy", func() {
+	var optimal v1alpha1.HPAConfiguration
+
+	BeforeEach(func() {
+		optimal = v1alpha1.HPAConfiguration{Min: 5, Max: 20, TargetMetricValue: 55}
+	})
+
+	It("should retain the recommendation when the policy is less conservative", func() {
+		policy := v1alpha1.Policy{Spec: v1alpha1.PolicySpec{Min: 2, TargetUtilization: 70}}
+
+		target, source := ApplyPolicy(optimal, policy)
+		Expect(target).To(Equal(optimal))
+		Expect(source).To(Equal(v1alpha1.HPAConfigurationSource{
+			Min:               v1alpha1.RecommenderSource,
+			Max:               v1alpha1.RecommenderSource,
+			TargetMetricValue: v1alpha1.RecommenderSource,
+		}))
+	})
+
+	It("should bound the target utilization and min replicas with the policy", func() {
+		policy := v1alpha1.Policy{Spec: v1alpha1.PolicySpec{Min: 8, TargetUtilization: 40}}
+
+		target, source := ApplyPolicy(optimal, policy)
+		// The 20 replicas at 55% serve the peak, which takes 27.5 replicas at 40%.
+		Expect(target).To(Equal(v1alpha1.HPAConfiguration{Min: 8, Max: 28, TargetMetricValue: 40}))
+		Expect(source).To(Equal(v1alpha1.HPAConfigurationSource{
+			Min:               v1alpha1.PolicySource,
+			Max:               v1alpha1.PolicySource,
+			TargetMetricValue: v1alpha1.PolicySource,
+		}))
+	})
+
+	It("should raise max replicas when the policy min exceeds it", func() {
+		policy := v1alpha1.Policy{Spec: v1alpha1.PolicySpec{Min: 25, TargetUtilization: 60}}
+
+		target, source := ApplyPolicy(optimal, policy)
+		Expect(target).To(Equal(v1alpha1.HPAConfiguration{Min: 25, Max: 25, TargetMetricValue: 55}))
+		Expect(source.Max).To(Equal(v1alpha1.PolicySource))
+	})
+
+	It("should ignore policy fields that are not set", func() {
+		target, _ := ApplyPolicy(optimal, v1alpha1.Policy{})
+		Expect(target).To(Equal(optimal))
+	})
+
+	It("should bound the cpu target of a multi metric configuration alone", func() {
+		optimal.Metrics = []v1alpha1.MetricTarget{
+			{Name: CPUMetric, TargetValue: 55},
+			{Name: MemoryMetric, TargetValue: 90},
+		}
+		policy := v1alpha1.Policy{Spec: v1alpha1.PolicySpec{TargetUtilization: 40}}
+
+		target, _ := ApplyPolicy(optimal, policy)
+		Expect(target.TargetMetricValue).To(Equal(40))
+		Expect(target.Max).To(Equal(28))
+		Expect(target.Metrics).To(Equal([]v1alpha1.MetricTarget{
+			{Name: CPUMetric, TargetValue: 40},
+			{Name: MemoryMetric, TargetValue: 90},
+		}))
+		Expect(optimal.Metrics[0].TargetValue).To(Equal(55))
+	})
+})
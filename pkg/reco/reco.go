@@ -1,34 +1,30 @@
 package reco
 
 import (
-	"context"
-	"errors"
-	"fmt"
-	rolloutv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
 	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
 	"github.com/go-logr/logr"
-	appsv1 "k8s.io/api/apps/v1"
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
-	"math"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"time"
 )
 
+// Recommender generates the HPAConfiguration for a workload. Failures are reported as a RecommendationError where
+// the cause is known, so that callers can tell the errors worth retrying from the ones that aren't.
 type Recommender interface {
-	Recommend(workloadSpec v1alpha1.WorkloadSpec) (*v1alpha1.HPAConfiguration, error)
+	Recommend(workloadSpec v1alpha1.WorkloadSpec) (*Recommendation, error)
 }
 
 type CpuUtilizationBasedRecommender struct {
-	k8sClient    client.Client
-	redLineUtil  float64
-	metricWindow time.Duration
-	scraper      metrics.Scraper
-	metricStep   time.Duration
-	minTarget    int
-	maxTarget    int
-	logger       logr.Logger
+	simulator
+	k8sClient         client.Client
+	metricWindow      time.Duration
+	scraper           metrics.Scraper
+	minTarget         int
+	maxTarget         int
+	maxHeadroomFactor float64
+	replicaBounds     ReplicaBounds
+	// capacityBasis tells whether the capacity of a pod is its CPU requests or its CPU limits.
+	capacityBasis CapacityBasis
 }
 
 func NewCpuUtilizationBasedRecommender(k8sClient client.Client,
@@ -38,24 +34,96 @@ func NewCpuUtilizationBasedRecommender(k8sClient client.Client,
 	metricStep time.Duration,
 	minTarget int,
 	maxTarget int,
+	maxHeadroomFactor float64,
+	breachBudget BreachBudget,
+	hpaBehavior HPABehavior,
+	replicaBounds ReplicaBounds,
+	forecast ForecastConfig,
+	capacityBasis CapacityBasis,
 	logger logr.Logger) *CpuUtilizationBasedRecommender {
 	return &CpuUtilizationBasedRecommender{
-		k8sClient:    k8sClient,
-		redLineUtil:  redLineUtil,
-		metricWindow: metricWindow,
-		scraper:      scraper,
-		metricStep:   metricStep,
-		minTarget:    minTarget,
-		maxTarget:    maxTarget,
-		logger:       logger,
+		simulator: simulator{
+			redLineUtil:  redLineUtil,
+			metricStep:   metricStep,
+			breachBudget: breachBudget,
+			hpaBehavior:  hpaBehavior,
+			forecast:     forecast,
+			logger:       logger,
+		},
+		k8sClient:         k8sClient,
+		metricWindow:      metricWindow,
+		scraper:           scraper,
+		minTarget:         minTarget,
+		maxTarget:         maxTarget,
+		maxHeadroomFactor: maxHeadroomFactor,
+		replicaBounds:     replicaBounds,
+		capacityBasis:     capacityBasis,
 	}
 }
 
-func (c *CpuUtilizationBasedRecommender) Recommend(workloadSpec v1alpha1.WorkloadSpec) (*v1alpha1.HPAConfiguration,
+func (c *CpuUtilizationBasedRecommender) Recommend(workloadSpec v1alpha1.WorkloadSpec) (*Recommendation, error) {
+	recommendation, _, err := c.recommend(workloadSpec)
+	return recommendation, err
+}
+
+// recommend returns the Recommendation for the workload, and the recommendation of its cpu it was made from.
+func (c *CpuUtilizationBasedRecommender) recommend(workloadSpec v1alpha1.WorkloadSpec) (*Recommendation,
+	*metricRecommendation, error) {
+
+	cpuRecommendation, err := c.recommendMetric(workloadSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recommendation := &Recommendation{
+		BreachBudget: &cpuRecommendation.breachBudget,
+		Warnings:     cpuRecommendation.warnings,
+		Forecast:     cpuRecommendation.forecast,
+		ACL:          cpuRecommendation.acl,
+		Explanation:  newExplanation(cpuRecommendation.acl, cpuRecommendation.explanation),
+		// The time ranges of the metric window left out of the simulation.
+		ExcludedMetricRanges: cpuRecommendation.excludedRanges,
+	}
+	maxReplicas := applyMaxHeadroom(cpuRecommendation.maxReplicas, c.maxHeadroomFactor)
+	maxReplicas, warning, err := capMaxReplicasByQuota(c.k8sClient, workloadSpec.Namespace,
+		cpuRecommendation.podResources, cpuRecommendation.minReplicas, maxReplicas)
+	if err != nil {
+		c.logger.Error(err, "Error while capping the max replicas by the namespace quota")
+		return nil, nil, err
+	}
+	if warning != nil {
+		recommendation.Warnings = append(recommendation.Warnings, *warning)
+	}
+
+	recommendation.HPAConfiguration = v1alpha1.HPAConfiguration{Min: cpuRecommendation.minReplicas, Max: maxReplicas,
+		TargetMetricValue: cpuRecommendation.targetValue}
+
+	// The cost is only informational, so failing to estimate it doesn't hold the recommendation back.
+	recommendation.costModel, err = c.newCostModel(c.k8sClient, workloadSpec, cpuRecommendation,
+		recommendation.HPAConfiguration, cpuRecommendation.replicas,
+		func(hpaConfiguration v1alpha1.HPAConfiguration) ([]float64, error) {
+			simulation, err := c.simulateAtRequestsTarget(cpuRecommendation.series.dataPoints,
+				cpuRecommendation.acl.Total.Duration, hpaConfiguration.TargetMetricValue,
+				cpuRecommendation.podResources,
+				ReplicaBounds{Min: hpaConfiguration.Min, Max: hpaConfiguration.Max})
+			if err != nil {
+				return nil, err
+			}
+			return simulation.replicas, nil
+		})
+	if err != nil {
+		c.logger.Error(err, "Error while estimating the cost of the recommendation")
+	}
+	return recommendation, cpuRecommendation, nil
+}
+
+// recommendMetric finds the optimal target of the cpu utilization for the workload, and the replicas the simulated
+// HPA scaled between at that target.
+func (c *CpuUtilizationBasedRecommender) recommendMetric(workloadSpec v1alpha1.WorkloadSpec) (*metricRecommendation,
 	error) {
 
 	end := time.Now()
-	start := end.Add(c.metricWindow)
+	start := end.Add(-c.metricWindow)
 
 	dataPoints, err := c.scraper.GetAverageCPUUtilizationByWorkload(workloadSpec.Namespace,
 		workloadSpec.Name,
@@ -63,183 +131,84 @@ func (c *CpuUtilizationBasedRecommender) Recommend(workloadSpec v1alpha1.Workloa
 		end,
 		c.metricStep)
 	if err != nil {
-		c.logger.Error(err, "Error while scraping GetCPUUtilizationBreachDataPoints.")
-		return nil, nil
+		c.logger.Error(err, "Error while scraping GetAverageCPUUtilizationByWorkload.")
+		return nil, &RecommendationError{Kind: MetricsUnavailable, Err: err}
 	}
-
-	acl, err := c.scraper.GetACLByWorkload(workloadSpec.Namespace, workloadSpec.Name)
-	if err != nil {
-		c.logger.Error(err, "Error while getting GetACL.")
-		return nil, nil
+	if len(dataPoints) == 0 {
+		return nil, newRecommendationError(InsufficientData, "no cpu utilization data points found between %s and %s",
+			start.Format(time.RFC3339), end.Format(time.RFC3339))
 	}
 
-	perPodResources, err := c.getContainerCPULimitsSum(workloadSpec.Namespace, workloadSpec.Kind, workloadSpec.Name)
+	dataPoints, excludedRanges, err := excludeMetricRanges(c.k8sClient, workloadSpec, dataPoints)
 	if err != nil {
-		c.logger.Error(err, "Error while getting getContainerCPULimitsSum")
+		c.logger.Error(err, "Error while getting the metric exclusions")
 		return nil, err
 	}
+	if len(observedDataPoints(dataPoints)) == 0 {
+		return nil, newRecommendationError(InsufficientData,
+			"all the cpu utilization data points between %s and %s are excluded",
+			start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
 
-	optimalTargetUtil, minReplicas, maxReplicas, err := c.findOptimalTargetUtilization(dataPoints,
-		acl,
-		c.minTarget,
-		c.maxTarget,
-		perPodResources)
+	acl, err := getAutoscalingCycleLag(c.k8sClient, c.scraper, workloadSpec)
 	if err != nil {
-		c.logger.Error(err, "Error while executing findOptimalTargetUtilization")
+		c.logger.Error(err, "Error while getting GetACL.")
 		return nil, err
 	}
 
-	return &v1alpha1.HPAConfiguration{Min: minReplicas, Max: maxReplicas, TargetMetricValue: optimalTargetUtil}, nil
-}
-
-type TimerEvent struct {
-	Timestamp time.Time
-	Delta     float64
-}
-
-// simulateHPA simulates the operation of HPA by adding a delay of amount Autoscaling Cycle Lag (ACL)
-// to all upscale events. It takes as input
-// dataPoints - sum of cpu utilization data points for a workload.
-// acl - Autoscaling Cycle Lag for the workload
-// perPodResources - these are required ot more accurately mimic the working of HPA by making the available resources
-// multiples of perPodResources.
-
-func (c *CpuUtilizationBasedRecommender) simulateHPA(dataPoints []metrics.DataPoint,
-	acl time.Duration,
-	targetUtilization int,
-	perPodResources float64) ([]metrics.DataPoint, int, int, error) {
-
-	if len(dataPoints) == 0 {
-		return []metrics.DataPoint{}, 0, 0, nil
-	}
-	if targetUtilization < 1 || targetUtilization > 100 {
-		return []metrics.DataPoint{}, 0, 0, errors.New(fmt.Sprintf("Invalid value of target utilization: %v."+
-			" Value should be between 1 and 100", targetUtilization))
-	}
-
-	simulatedDataPoints := make([]metrics.DataPoint, len(dataPoints))
-
-	currentReplicas := math.Ceil((dataPoints[0].Value * 100) / float64(targetUtilization) / perPodResources)
-	minReplicas := currentReplicas
-	maxReplicas := currentReplicas
-	currentResources := currentReplicas * perPodResources
-	readyResources := currentResources
-
-	simulatedDataPoints[0] = metrics.DataPoint{Timestamp: dataPoints[0].Timestamp,
-		Value: currentResources * c.redLineUtil}
-
-	//stores the list of all upscale events with a time delay of acl added.
-	readyResourcesTimerList := []TimerEvent{}
-
-	for i, dp := range dataPoints[1:] {
-
-		// Consume timers for all upscale events before the current time.
-		for len(readyResourcesTimerList) > 0 && !dp.Timestamp.Before(readyResourcesTimerList[0].Timestamp) {
-			readyResources += readyResourcesTimerList[0].Delta
-			readyResourcesTimerList = readyResourcesTimerList[1:]
-		}
-		newReplicas := math.Ceil((100 * dp.Value) / float64(targetUtilization) / perPodResources)
-		minReplicas = math.Min(newReplicas, minReplicas)
-		maxReplicas = math.Max(newReplicas, maxReplicas)
-
-		newResources := newReplicas * perPodResources
-		currentResources = newResources
-
-		if newResources > readyResources {
-			delta := newResources - readyResources
-
-			//subtract delta that is already in queue.
-			for _, timer := range readyResourcesTimerList {
-				delta -= timer.Delta
-			}
-
-			if delta > 0 {
-				readyReplicasTimer := TimerEvent{Timestamp: dp.Timestamp.Add(acl), Delta: delta}
-				readyResourcesTimerList = append(readyResourcesTimerList, readyReplicasTimer)
-			}
-
-		} else {
-			readyResources = newResources
-			readyResourcesTimerList = []TimerEvent{}
-		}
-
-		availableResources := readyResources * c.redLineUtil
-		simulatedDataPoints[i+1] = metrics.DataPoint{Timestamp: dp.Timestamp, Value: availableResources}
+	resources, err := getPodResources(c.k8sClient, workloadSpec.Namespace, workloadSpec.Kind, workloadSpec.Name)
+	if err != nil {
+		c.logger.Error(err, "Error while getting getPodResources")
+		return nil, err
 	}
-
-	return simulatedDataPoints, int(minReplicas), int(maxReplicas), nil
-}
-
-func (c *CpuUtilizationBasedRecommender) hasNoBreachOccurred(original, simulated []metrics.DataPoint) bool {
-	for i := range original {
-		if original[i].Value > simulated[i].Value {
-			return false
-		}
+	if resources.requests <= 0 {
+		return nil, newRecommendationError(InvalidResources,
+			"no cpu requests or limits set on the containers of %s %s/%s", workloadSpec.Kind, workloadSpec.Namespace,
+			workloadSpec.Name)
 	}
-	return true
-}
+	perPodResources := resources.capacity(c.capacityBasis)
 
-func (c *CpuUtilizationBasedRecommender) findOptimalTargetUtilization(dataPoints []metrics.DataPoint,
-	acl time.Duration,
-	minTarget,
-	maxTarget int,
-	perPodResources float64) (int, int, int, error) {
-	low := minTarget
-	high := maxTarget
-	minReplicas := 0
-	maxReplicas := 0
-
-	for low <= high {
-		mid := low + (high-low)/2
-		target := mid
-		var simulatedHPAList []metrics.DataPoint
-		var err error
-		simulatedHPAList, minReplicas, maxReplicas, err = c.simulateHPA(dataPoints, acl, target, perPodResources)
-		if err != nil {
-			c.logger.Error(err, "Error while simulating HPA")
-			return -1, minReplicas, maxReplicas, err
-		}
-
-		if c.hasNoBreachOccurred(dataPoints, simulatedHPAList) {
-			low = mid + 1
-		} else {
-			high = mid - 1
-		}
-	}
-	return high, minReplicas, maxReplicas, nil
-}
+	// The HPA is simulated against the forecast of the demand, if one is configured.
+	demand, forecast := c.forecastDemand(dataPoints)
 
-func (c *CpuUtilizationBasedRecommender) getContainerCPULimitsSum(namespace, objectKind, objectName string) (float64,
-	error) {
-	var obj client.Object
-	switch objectKind {
-	case "Deployment":
-		obj = &appsv1.Deployment{}
-	case "Rollout":
-		obj = &rolloutv1alpha1.Rollout{}
-	default:
-		return 0, fmt.Errorf("unsupported objectKind: %s", objectKind)
+	search, err := c.findOptimalTargetUtilization(
+		demand,
+		acl.Total.Duration,
+		c.minTarget,
+		c.maxTarget,
+		perPodResources,
+		c.replicaBounds)
+	if err != nil {
+		c.logger.Error(err, "Error while executing findOptimalTargetUtilization")
+		return nil, err
 	}
 
-	if err := c.k8sClient.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: objectName}, obj); err != nil {
-		return 0, err
+	recommendation := &metricRecommendation{
+		series: metricSeries{
+			name:            CPUMetric,
+			step:            c.metricStep,
+			dataPoints:      demand,
+			perPodResources: perPodResources,
+			target:          search.target,
+		},
+		targetValue:    search.target,
+		podResources:   resources,
+		minReplicas:    search.minReplicas,
+		maxReplicas:    search.maxReplicas,
+		replicas:       search.replicas,
+		breachBudget:   search.usage,
+		forecast:       forecast,
+		acl:            acl,
+		excludedRanges: excludedRanges,
+		explanation: newMetricExplanation(CPUMetric, c.metricStep, demand, perPodResources, search.target,
+			search.candidates, forecast),
 	}
-
-	var podTemplateSpec *corev1.PodTemplateSpec
-	switch v := obj.(type) {
-	case *appsv1.Deployment:
-		podTemplateSpec = &v.Spec.Template
-	case *rolloutv1alpha1.Rollout:
-		podTemplateSpec = &v.Spec.Template
-	default:
-		return 0, fmt.Errorf("unsupported object type")
+	// The HPA measures the utilization against the requests.
+	if c.capacityBasis == LimitsCapacity {
+		recommendation.targetValue = targetOfRequests(search.target, resources.limits, resources.requests)
 	}
-
-	cpuLimitsSum := int64(0)
-	for _, container := range podTemplateSpec.Spec.Containers {
-		if limit, ok := container.Resources.Limits[corev1.ResourceCPU]; ok {
-			cpuLimitsSum += limit.MilliValue()
-		}
+	if search.warning != nil {
+		recommendation.warnings = append(recommendation.warnings, *search.warning)
 	}
-	return float64(cpuLimitsSum) / 1000, nil
+	return recommendation, nil
 }
@@ -29,13 +29,59 @@ var _ = Describe("CpuUtilizationBasedRecommender", func() {
 			maxTarget := 60
 			perPodResources := 8.2
 
-			optimalTarget, min, max, err := recommender.findOptimalTargetUtilization(
-				dataPoints, acl, minTarget, maxTarget, perPodResources)
+			search, err := recommender.findOptimalTargetUtilization(dataPoints, acl, minTarget, maxTarget,
+				perPodResources, ReplicaBounds{})
 
 			Expect(err).To(Not(HaveOccurred()))
-			Expect(optimalTarget).To(Equal(52))
-			Expect(min).To(Equal(7))
-			Expect(max).To(Equal(24))
+			Expect(search.warning).To(BeNil())
+			Expect(search.usage.Consumed.Duration).To(BeZero())
+			Expect(search.target).To(Equal(52))
+			Expect(search.minReplicas).To(Equal(8))
+			Expect(search.maxReplicas).To(Equal(24))
+		})
+
+		It("should return the replicas of the optimal target when the last target tried is rejected", func() {
+			dataPoints := []metrics.DataPoint{
+				{Timestamp: time.Now().Add(-10 * time.Minute), Value: 60},
+				{Timestamp: time.Now().Add(-9 * time.Minute), Value: 80},
+				{Timestamp: time.Now().Add(-8 * time.Minute), Value: 100},
+				{Timestamp: time.Now().Add(-7 * time.Minute), Value: 50},
+				{Timestamp: time.Now().Add(-6 * time.Minute), Value: 30},
+			}
+
+			search, err := recommender.findOptimalTargetUtilization(dataPoints, 5*time.Minute, 10, 60, 8.2,
+				ReplicaBounds{})
+			Expect(err).NotTo(HaveOccurred())
+			last := search.candidates[len(search.candidates)-1]
+			Expect(last.Accepted).To(BeFalse())
+			Expect(last.Target).NotTo(Equal(search.target))
+
+			simulation, err := recommender.simulateHPA(dataPoints, 5*time.Minute, search.target, 8.2, ReplicaBounds{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(search.minReplicas).To(Equal(simulation.minReplicas))
+			Expect(search.maxReplicas).To(Equal(simulation.maxReplicas))
+		})
+
+		It("should fall back to the min target with a warning when no target fits in the breach budget", func() {
+			dataPoints := []metrics.DataPoint{
+				{Timestamp: time.Now().Add(-10 * time.Minute), Value: 60},
+				{Timestamp: time.Now().Add(-9 * time.Minute), Value: 800},
+			}
+
+			search, err := recommender.findOptimalTargetUtilization(dataPoints, 5*time.Minute, 10, 60, 8.2,
+				ReplicaBounds{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(search.candidates).NotTo(BeEmpty())
+			Expect(search.candidates).NotTo(ContainElement(HaveField("Accepted", true)))
+			Expect(search.target).To(Equal(10))
+			Expect(search.warning).NotTo(BeNil())
+			Expect(search.warning.Reason).To(Equal(BreachesBeyondBudget))
+
+			simulation, err := recommender.simulateHPA(dataPoints, 5*time.Minute, 10, 8.2, ReplicaBounds{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(search.minReplicas).To(Equal(simulation.minReplicas))
+			Expect(search.maxReplicas).To(Equal(simulation.maxReplicas))
+			Expect(search.minReplicas).To(BeNumerically(">", 0))
 		})
 	})
 
@@ -82,8 +128,9 @@ var _ = Describe("CpuUtilizationBasedRecommender", func() {
 
 		Context("with valid inputs", func() {
 			It("should simulate HPA correctly", func() {
-				simulatedDataPoints, min, max, err := recommender.simulateHPA(dataPoints, acl, targetUtilization, 8.2)
+				simulation, err := recommender.simulateHPA(dataPoints, acl, targetUtilization, 8.2, ReplicaBounds{})
 				Expect(err).NotTo(HaveOccurred())
+				simulatedDataPoints, min, max := simulation.dataPoints, simulation.minReplicas, simulation.maxReplicas
 
 				Expect(simulatedDataPoints).ToNot(BeNil())
 				Expect(len(simulatedDataPoints)).To(Equal(len(dataPoints)))
@@ -101,8 +148,9 @@ var _ = Describe("CpuUtilizationBasedRecommender", func() {
 			It("should handle empty dataPoints", func() {
 				dataPoints = []metrics.DataPoint{}
 
-				simulatedDataPoints, _, _, err := recommender.simulateHPA(dataPoints, acl, targetUtilization, 8.2)
+				simulation, err := recommender.simulateHPA(dataPoints, acl, targetUtilization, 8.2, ReplicaBounds{})
 				Expect(err).NotTo(HaveOccurred())
+				simulatedDataPoints := simulation.dataPoints
 				Expect(simulatedDataPoints).ToNot(BeNil())
 				Expect(len(simulatedDataPoints)).To(Equal(0))
 			})
@@ -110,13 +158,13 @@ var _ = Describe("CpuUtilizationBasedRecommender", func() {
 			It("should handle zero targetUtilization", func() {
 				targetUtilization = 0
 
-				_, _, _, err := recommender.simulateHPA(dataPoints, acl, targetUtilization, 8.2)
+				_, err := recommender.simulateHPA(dataPoints, acl, targetUtilization, 8.2, ReplicaBounds{})
 				Expect(err).To(HaveOccurred())
 			})
 		})
 	})
 
-	var _ = Describe("hasNoBreachOccurred", func() {
+	var _ = Describe("measureBreaches", func() {
 		var (
 			original  []metrics.DataPoint
 			simulated []metrics.DataPoint
@@ -141,7 +189,8 @@ var _ = Describe("CpuUtilizationBasedRecommender", func() {
 			})
 
 			It("should return true", func() {
-				Expect(recommender.hasNoBreachOccurred(original, simulated)).To(BeTrue())
+				withinBudget, _ := measureBreaches(&recommender.simulator, original, simulated)
+				Expect(withinBudget).To(BeTrue())
 			})
 		})
 
@@ -163,7 +212,8 @@ var _ = Describe("CpuUtilizationBasedRecommender", func() {
 			})
 
 			It("should return false", func() {
-				Expect(recommender.hasNoBreachOccurred(original, simulated)).To(BeFalse())
+				withinBudget, _ := measureBreaches(&recommender.simulator, original, simulated)
+				Expect(withinBudget).To(BeFalse())
 			})
 		})
 	})
@@ -172,7 +222,7 @@ var _ = Describe("CpuUtilizationBasedRecommender", func() {
 		// Add test cases for the findOptimalTargetUtilization method
 	})
 
-	Describe("getContainerCPULimitsSum", func() {
+	Describe("getPodResources", func() {
 		var (
 			deploymentNamespace = "default"
 			deploymentName      = "test-deployment"
@@ -282,25 +332,26 @@ var _ = Describe("CpuUtilizationBasedRecommender", func() {
 
 		})
 
-		It("should return the correct sum of CPU limits for a Deployment", func() {
-			actualSum, err := recommender.getContainerCPULimitsSum(deploymentNamespace, "Deployment", deploymentName)
+		It("should return the correct sum of CPU limits and requests for a Deployment", func() {
+			resources, err := getPodResources(k8sClient, deploymentNamespace, "Deployment", deploymentName)
 			Expect(err).To(BeNil())
-			Expect(actualSum).To(Equal(float64(1.5)))
+			Expect(resources.limits).To(Equal(float64(1.5)))
+			Expect(resources.requests).To(Equal(float64(1.5)))
 		})
 
 		It("should return the correct sum of CPU limits for a Rollout", func() {
-			actualSum, err := recommender.getContainerCPULimitsSum(rolloutNamespace, "Rollout", rolloutName)
+			resources, err := getPodResources(k8sClient, rolloutNamespace, "Rollout", rolloutName)
 			Expect(err).To(BeNil())
-			Expect(actualSum).To(Equal(float64(1.2)))
+			Expect(resources.limits).To(Equal(float64(1.2)))
 		})
 
 		It("should return an error for an unsupported object kind", func() {
-			_, err := recommender.getContainerCPULimitsSum(deploymentNamespace, "UnsupportedKind", deploymentName)
+			_, err := getPodResources(k8sClient, deploymentNamespace, "UnsupportedKind", deploymentName)
 			Expect(err).NotTo(BeNil())
 		})
 
 		It("should return an error if the object is not found", func() {
-			_, err := recommender.getContainerCPULimitsSum(deploymentNamespace, "Deployment", "non-existent-deployment")
+			_, err := getPodResources(k8sClient, deploymentNamespace, "Deployment", "non-existent-deployment")
 			Expect(err).NotTo(BeNil())
 		})
 	})
@@ -381,12 +432,22 @@ var _ = Describe("CpuUtilizationBasedRecommender", func() {
 					APIVersion: "apps/v1",
 				},
 			}
-			hpaConfig, err := recommender.Recommend(workloadSpec)
+			recommendation, err := recommender.Recommend(workloadSpec)
 
 			Expect(err).To(Not(HaveOccurred()))
+			hpaConfig := recommendation.HPAConfiguration
 			Expect(hpaConfig.TargetMetricValue).To(Equal(52))
-			Expect(hpaConfig.Min).To(Equal(7))
+			Expect(hpaConfig.Min).To(Equal(8))
 			Expect(hpaConfig.Max).To(Equal(24))
+
+			explanation := recommendation.Explanation
+			Expect(explanation).NotTo(BeNil())
+			Expect(explanation.ACL.Total.Duration).To(Equal(5 * time.Minute))
+			Expect(explanation.Metrics).To(HaveLen(1))
+			Expect(explanation.Metrics[0].Target).To(Equal(52))
+			Expect(explanation.Metrics[0].PerPodResources).To(Equal(8.2))
+			Expect(explanation.Metrics[0].Candidates).To(ContainElement(And(
+				HaveField("Target", 52), HaveField("Accepted", true))))
 		})
 	})
 })
@@ -0,0 +1,62 @@
+package reco
+
+import "github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+
+// Recommendation is what a Recommender generates for a workload.
+type Recommendation struct {
+	HPAConfiguration v1alpha1.HPAConfiguration
+	// Warnings lists the adjustments made to the data driven HPAConfiguration that the owners of the workload need to
+	// know about.
+	Warnings []Warning
+	// BreachBudget reports the breach budget consumed at the recommended target, if the Recommender uses one.
+	BreachBudget *v1alpha1.BreachBudgetUsage
+	// MetricContributions reports the contribution of each metric to the peak replicas, if the Recommender combines
+	// more than one metric.
+	MetricContributions []v1alpha1.MetricContribution
+	// Forecast is the forecast of the metric the HPA was simulated against, if the Recommender forecasts its metric.
+	Forecast *Forecast
+	// ExcludedMetricRanges lists the time ranges of the metric window that were left out of the simulation, as
+	// declared by the MetricExclusions that cover the workload.
+	ExcludedMetricRanges []v1alpha1.ExcludedMetricRange
+	// ACL is the autoscaling cycle lag the HPA was simulated with.
+	ACL *v1alpha1.AutoscalingCycleLag
+	// Explanation records how the recommendation was reached.
+	Explanation *Explanation
+	// MinReplicasSchedule lists the blocks of the week the minReplicas of the HPA should be raised over, if the
+	// Recommender schedules them.
+	MinReplicasSchedule []v1alpha1.MinReplicasScheduleEntry
+	// costModel estimates the cost of the HPAConfigurations of the workload, if the Recommender sizes the workload on
+	// its cpu.
+	costModel *costModel
+}
+
+// EstimateCost estimates the savings of the HPAConfiguration applied to the workload, i.e. the recommended one once
+// bounded by the policy, over the current scaling of the workload. It returns nil if the Recommender doesn't size the
+// workload on its cpu.
+func (r *Recommendation) EstimateCost(hpaConfiguration v1alpha1.HPAConfiguration) (*v1alpha1.CostEstimate, error) {
+	if r.costModel == nil {
+		return nil, nil
+	}
+	return r.costModel.estimate(hpaConfiguration)
+}
+
+// Warning describes an adjustment made to the HPAConfiguration of a Recommendation.
+type Warning struct {
+	Reason  string
+	Message string
+}
+
+const (
+	// MaxCappedByQuota is the reason of the Warning raised when the max replicas is capped by the CPU quota of the
+	// namespace.
+	MaxCappedByQuota = "MaxCappedByQuota"
+	// BreachesAtMaxReplicas is the reason of the Warning raised when the max replicas bound of the recommender, rather
+	// than the target utilization, causes the breaches at the recommended target.
+	BreachesAtMaxReplicas = "BreachesAtMaxReplicas"
+	// ScheduleBreachesAtMaxReplicas is the reason of the Warning raised when the minReplicas schedule leaves breaches
+	// that only more max replicas would avoid.
+	ScheduleBreachesAtMaxReplicas = "ScheduleBreachesAtMaxReplicas"
+	// BreachesBeyondBudget is the reason of the Warning raised when no target utilization keeps the breaches within
+	// the breach budget, and the min target is recommended anyway.
+	BreachesBeyondBudget = "BreachesBeyondBudget"
+)
@@ -0,0 +1,50 @@
+package reco
+
+// CpuUtilizationBasedRecommenderName is the name the CpuUtilizationBasedRecommender is registered with.
+const CpuUtilizationBasedRecommenderName = "cpuUtilizationBased"
+
+// MemoryUtilizationBasedRecommenderName is the name the MemoryUtilizationBasedRecommender is registered with.
+const MemoryUtilizationBasedRecommenderName = "memoryUtilizationBased"
+
+// MultiMetricRecommenderName is the name the MultiMetricRecommender on the cpu and the memory utilization is
+// registered with.
+const MultiMetricRecommenderName = "multiMetric"
+
+// CustomMetricRecommenderName is the name the CustomMetricRecommender is registered with.
+const CustomMetricRecommenderName = "customMetric"
+
+// MinReplicasScheduleRecommenderName is the name the MinReplicasScheduleRecommender is registered with.
+const MinReplicasScheduleRecommenderName = "minReplicasSchedule"
+
+// Registry holds the Recommenders available to the operator by name, so that the Recommender can be chosen per
+// workload. The default Recommender is used for the workloads that don't ask for one.
+type Registry struct {
+	recommenders       map[string]Recommender
+	defaultRecommender string
+}
+
+func NewRegistry(defaultRecommender string) *Registry {
+	return &Registry{
+		recommenders:       map[string]Recommender{},
+		defaultRecommender: defaultRecommender,
+	}
+}
+
+// Register adds the Recommender to the registry, replacing any Recommender registered with the same name.
+func (r *Registry) Register(name string, recommender Recommender) {
+	r.recommenders[name] = recommender
+}
+
+// Get returns the Recommender registered with the name, or the default Recommender if the name is empty. The
+// returned name is the one the Recommender was found with.
+func (r *Registry) Get(name string) (string, Recommender, error) {
+	if name == "" {
+		name = r.defaultRecommender
+	}
+	recommender, ok := r.recommenders[name]
+	if !ok {
+		return name, nil, newRecommendationError(UnknownRecommender, "no recommender registered with the name %q",
+			name)
+	}
+	return name, recommender, nil
+}
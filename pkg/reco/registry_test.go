@@ -0,0 +1,58 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type FixedRecommender struct {
+	hpaConfiguration v1alpha1.HPAConfiguration
+}
+
+func (r *FixedRecommender) Recommend(workloadSpec v1alpha1.WorkloadSpec) (*Recommendation, error) {
+	return &Recommendation{HPAConfiguration: r.hpaConfiguration}, nil
+}
+
+var _ = Describe("Registry", func() {
+	var (
+		registry    *Registry
+		fixed       *FixedRecommender
+		alternative *FixedRecommender
+	)
+
+	BeforeEach(func() {
+		fixed = &FixedRecommender{hpaConfiguration: v1alpha1.HPAConfiguration{Min: 1, Max: 10, TargetMetricValue: 50}}
+		alternative = &FixedRecommender{hpaConfiguration: v1alpha1.HPAConfiguration{Min: 2, Max: 8, TargetMetricValue: 70}}
+		registry = NewRegistry("fixed")
+		registry.Register("fixed", fixed)
+		registry.Register("alternative", alternative)
+	})
+
+	It("should return the default recommender for an empty name", func() {
+		name, recommender, err := registry.Get("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("fixed"))
+		Expect(recommender).To(BeIdenticalTo(fixed))
+	})
+
+	It("should return the recommender registered with the name", func() {
+		name, recommender, err := registry.Get("alternative")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("alternative"))
+		Expect(recommender).To(BeIdenticalTo(alternative))
+	})
+
+	It("should return a non retryable error for an unknown name", func() {
+		name, recommender, err := registry.Get("missing")
+		Expect(name).To(Equal("missing"))
+		Expect(recommender).To(BeNil())
+		Expect(KindOf(err)).To(Equal(UnknownRecommender))
+		Expect(IsRetryable(err)).To(BeFalse())
+	})
+
+	It("should fail when the default recommender isn't registered", func() {
+		_, _, err := NewRegistry(CpuUtilizationBasedRecommenderName).Get("")
+		Expect(KindOf(err)).To(Equal(UnknownRecommender))
+	})
+})
@@ -0,0 +1,72 @@
+package reco
+
+import (
+	"fmt"
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	"time"
+)
+
+// ReplicaBounds are the min and max replicas the simulated HPA scales within. Bounds that are not set (zero values)
+// leave the replicas unbounded on that side.
+type ReplicaBounds struct {
+	Min int
+	Max int
+}
+
+// clamp returns the replicas within the bounds, and whether the max bound held them back.
+func (b ReplicaBounds) clamp(replicas float64) (float64, bool) {
+	if b.Max > 0 && replicas > float64(b.Max) {
+		return float64(b.Max), true
+	}
+	if b.Min > 0 && replicas < float64(b.Min) {
+		return float64(b.Min), false
+	}
+	return replicas, false
+}
+
+// hpaSimulation is the outcome of simulating the HPA of a workload over its data points.
+type hpaSimulation struct {
+	// dataPoints is the capacity available to the workload at the red line utilization.
+	dataPoints  []metrics.DataPoint
+	minReplicas int
+	maxReplicas int
+	// replicas is the replicas the HPA had scaled the workload to at each of the data points.
+	replicas []float64
+	// atMaxReplicas marks the data points at which the HPA wanted more replicas than the max bound allows.
+	atMaxReplicas []bool
+	// desiredMaxReplicas is the most replicas the HPA wanted, regardless of the max bound.
+	desiredMaxReplicas int
+	// usage is the breach budget consumed by the breaches of the simulated capacity, and usageBelowMaxReplicas the
+	// budget consumed by the breaches at the data points not held back by the max bound, which are the ones that come
+	// from the target utilization.
+	usage                 v1alpha1.BreachBudgetUsage
+	usageBelowMaxReplicas v1alpha1.BreachBudgetUsage
+	// breaches is the number of data points the demand exceeded the simulated capacity at, the first of them at
+	// firstBreach.
+	breaches    int
+	firstBreach time.Time
+	// stoppedEarly is true when the simulation stopped at a breach that rejects the target, in which case the data
+	// points after it aren't simulated.
+	stoppedEarly bool
+}
+
+// hitMaxReplicas tells whether the max bound held the HPA back at any of the data points.
+func (s *hpaSimulation) hitMaxReplicas() bool {
+	for _, atMax := range s.atMaxReplicas {
+		if atMax {
+			return true
+		}
+	}
+	return false
+}
+
+// maxReplicasWarning returns the Warning raised when the breaches at the recommended target come from the max bound,
+// which lowering the target utilization doesn't fix.
+func maxReplicasWarning(bounds ReplicaBounds, desiredMaxReplicas int) *Warning {
+	return &Warning{
+		Reason: BreachesAtMaxReplicas,
+		Message: fmt.Sprintf("the max replicas bound of %d causes breaches beyond the breach budget; "+
+			"a max of %d replicas avoids them", bounds.Max, desiredMaxReplicas),
+	}
+}
@@ -0,0 +1,70 @@
+package reco
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"time"
+)
+
+var _ = Describe("ReplicaBounds", func() {
+	const (
+		step            = time.Minute
+		target          = 50
+		perPodResources = 1.0
+	)
+
+	newRecommender := func() *CpuUtilizationBasedRecommender {
+		return newCpuRecommender(recommenderOptions{step: step})
+	}
+
+	Context("simulateHPA", func() {
+		It("should clamp the capacity to the max replicas and mark the data points held back", func() {
+			simulation, err := newRecommender().simulateHPA(dataPointsOf(step, 10, 20, 30), 0, target, perPodResources,
+				ReplicaBounds{Max: 30})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(simulation.maxReplicas).To(Equal(30))
+			Expect(simulation.desiredMaxReplicas).To(Equal(60))
+			Expect(simulation.atMaxReplicas).To(Equal([]bool{false, true, true}))
+			Expect(simulation.hitMaxReplicas()).To(BeTrue())
+			Expect(simulation.dataPoints[2].Value).To(BeNumerically("~", 30*redLineUtil))
+		})
+
+		It("should hold the replicas at the min replicas", func() {
+			simulation, err := newRecommender().simulateHPA(dataPointsOf(step, 10, 1, 1), 0, target, perPodResources,
+				ReplicaBounds{Min: 5})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(simulation.minReplicas).To(Equal(5))
+			Expect(simulation.hitMaxReplicas()).To(BeFalse())
+			Expect(simulation.dataPoints[2].Value).To(BeNumerically("~", 5*redLineUtil))
+		})
+	})
+
+	Context("findOptimalTargetUtilization", func() {
+		// A spike that the capacity only absorbs at low targets, as the capacity lags the demand by a data point.
+		values := make([]float64, 21)
+		for i := range values {
+			values[i] = 10
+		}
+		values[10] = 50
+
+		It("should lower the target when the breaches come from the target utilization", func() {
+			search, err := newRecommender().findOptimalTargetUtilization(dataPointsOf(step, values...), 0, minTarget, maxTarget,
+				perPodResources, ReplicaBounds{Max: 1000})
+			Expect(err).NotTo(HaveOccurred())
+			// 59 replicas at a target of 17 are the fewest to absorb the spike at the red line.
+			Expect(search.target).To(Equal(17))
+			Expect(search.warning).To(BeNil())
+		})
+
+		It("should keep the target and warn when the breaches come from the max replicas", func() {
+			search, err := newRecommender().findOptimalTargetUtilization(dataPointsOf(step, values...), 0, minTarget, maxTarget,
+				perPodResources, ReplicaBounds{Max: 20})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(search.target).To(Equal(maxTarget))
+			Expect(search.maxReplicas).To(Equal(20))
+			Expect(search.warning).NotTo(BeNil())
+			Expect(search.warning.Reason).To(Equal(BreachesAtMaxReplicas))
+			Expect(search.warning.Message).To(ContainSubstring("a max of 84 replicas"))
+		})
+	})
+})
@@ -0,0 +1,224 @@
+package reco
+
+import (
+	"fmt"
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	"math"
+	"time"
+)
+
+// defaultScheduleBlock is the length of the blocks of the week a minReplicas schedule is made of, when the one
+// configured isn't a whole number of minutes that divides a week.
+const defaultScheduleBlock = time.Hour
+
+// ScheduleTimezone is the timezone the weeks of a minReplicas schedule start in, and the cron expressions of its
+// entries are in.
+const ScheduleTimezone = "UTC"
+
+// MinReplicasScheduleRecommender generates the HPAConfiguration of the CpuUtilizationBasedRecommender along with a
+// schedule of the minReplicas of the HPA per block of the week. An HPA trails a ramp of the load by the ACL, and the
+// schedule raises its minReplicas ahead of the ramps that recur every week, so that the workload doesn't breach in
+// the simulation at all. The schedule is meant to be applied by a controller that sets the minReplicas of the HPA, or
+// by the cron triggers of a KEDA ScaledObject.
+type MinReplicasScheduleRecommender struct {
+	cpu *CpuUtilizationBasedRecommender
+	// block is the length of the blocks of the week the minReplicas are scheduled for.
+	block time.Duration
+}
+
+func NewMinReplicasScheduleRecommender(cpu *CpuUtilizationBasedRecommender,
+	block time.Duration) *MinReplicasScheduleRecommender {
+	if block < time.Minute || block%time.Minute != 0 || week%block != 0 {
+		block = defaultScheduleBlock
+	}
+	return &MinReplicasScheduleRecommender{
+		cpu:   cpu,
+		block: block,
+	}
+}
+
+func (m *MinReplicasScheduleRecommender) Recommend(workloadSpec v1alpha1.WorkloadSpec) (*Recommendation, error) {
+	recommendation, cpuRecommendation, err := m.cpu.recommend(workloadSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	// The schedule is found for the HPA as recommended.
+	bounds := ReplicaBounds{Min: recommendation.HPAConfiguration.Min, Max: recommendation.HPAConfiguration.Max}
+	series := cpuRecommendation.series
+	schedule, breachesAtMaxReplicas, err := m.cpu.findMinReplicasSchedule(series, cpuRecommendation.acl.Total.Duration,
+		bounds, m.block)
+	if err != nil {
+		m.cpu.logger.Error(err, "Error while finding the minReplicas schedule")
+		return nil, err
+	}
+	recommendation.MinReplicasSchedule = scheduleEntries(schedule, weeklyLoadProfile(series.dataPoints, m.block),
+		bounds.Min)
+	if breachesAtMaxReplicas > 0 {
+		recommendation.Warnings = append(recommendation.Warnings, Warning{
+			Reason: ScheduleBreachesAtMaxReplicas,
+			Message: fmt.Sprintf("The minReplicas schedule can't avoid %d breaches at the max replicas %d of the HPA",
+				breachesAtMaxReplicas, bounds.Max),
+		})
+	}
+	return recommendation, nil
+}
+
+// findMinReplicasSchedule finds the fewest replicas to schedule over the blocks of the week for the simulation of the
+// HPA at the target of the series to show no breach. A breach raises the blocks from one ACL and one step before it
+// to the replicas whose capacity covers the demand at the breach, as the simulation delays a scheduled raise of the
+// replicas by the ACL like any other upscale. Every block stands for the same time of all the weeks of the series,
+// and the HPA is simulated again with the raised schedule until no breach is left but at the max replicas, which no
+// schedule avoids. It returns the schedule and the number of breaches left at the max replicas.
+func (s *simulator) findMinReplicasSchedule(series metricSeries,
+	acl time.Duration,
+	bounds ReplicaBounds,
+	block time.Duration) (*weeklySchedule, int, error) {
+
+	schedule := newWeeklySchedule(block)
+	hpaSimulator := s.newHPASimulator(series.dataPoints, acl, series.perPodResources, bounds)
+	hpaSimulator.schedule = schedule
+	for {
+		simulation, err := hpaSimulator.run(series.target, false)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		raised := false
+		var breaches []time.Time
+		breachesAtMaxReplicas := 0
+		for i, dp := range series.dataPoints {
+			if dp.Gap || dp.Value <= simulation.dataPoints[i].Value {
+				continue
+			}
+			if simulation.atMaxReplicas[i] {
+				breachesAtMaxReplicas++
+				continue
+			}
+			replicas := math.Ceil(dp.Value / (series.perPodResources * s.redLineUtil))
+			raised = schedule.raise(dp.Timestamp.Add(-acl-series.step), dp.Timestamp, replicas) || raised
+			breaches = append(breaches, dp.Timestamp)
+		}
+		if len(breaches) == 0 {
+			return schedule, breachesAtMaxReplicas, nil
+		}
+		// The replicas scheduled cover the demand at the breaches left but for the rounding of the simulated capacity.
+		if !raised {
+			replicas := make([]float64, len(breaches))
+			for i, breach := range breaches {
+				replicas[i] = schedule.minReplicasAt(breach) + 1
+			}
+			for i, breach := range breaches {
+				schedule.raise(breach, breach, replicas[i])
+			}
+		}
+	}
+}
+
+// weeklySchedule holds the minReplicas scheduled for each block of the week, which starts on Sunday at midnight in
+// the ScheduleTimezone. A block without replicas scheduled holds zero.
+type weeklySchedule struct {
+	block       time.Duration
+	minReplicas []float64
+}
+
+func newWeeklySchedule(block time.Duration) *weeklySchedule {
+	return &weeklySchedule{
+		block:       block,
+		minReplicas: make([]float64, week/block),
+	}
+}
+
+// blockOf returns the block of the week the timestamp falls in.
+func (w *weeklySchedule) blockOf(timestamp time.Time) int {
+	return int(sinceStartOfWeek(timestamp) / w.block)
+}
+
+// minReplicasAt returns the replicas scheduled for the block of the timestamp.
+func (w *weeklySchedule) minReplicasAt(timestamp time.Time) float64 {
+	return w.minReplicas[w.blockOf(timestamp)]
+}
+
+// raise schedules at least the replicas for the blocks from the one of start to the one of end, wrapping around the
+// end of the week, and tells whether it raised any of them.
+func (w *weeklySchedule) raise(start, end time.Time, replicas float64) bool {
+	first, last := w.blockOf(start), w.blockOf(end)
+	if end.Sub(start) >= week {
+		first, last = 0, len(w.minReplicas)-1
+	}
+	raised := false
+	for i := first; ; i = (i + 1) % len(w.minReplicas) {
+		if w.minReplicas[i] < replicas {
+			w.minReplicas[i] = replicas
+			raised = true
+		}
+		if i == last {
+			return raised
+		}
+	}
+}
+
+// sinceStartOfWeek returns the time from the start of the week, on Sunday at midnight in the ScheduleTimezone, to the
+// timestamp.
+func sinceStartOfWeek(timestamp time.Time) time.Duration {
+	timestamp = timestamp.UTC()
+	year, month, day := timestamp.Date()
+	startOfDay := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return time.Duration(timestamp.Weekday())*24*time.Hour + timestamp.Sub(startOfDay)
+}
+
+// weeklyLoadProfile returns the peak of the data points in each block of the week, over all the weeks they span.
+// The data points marked as a Gap are skipped.
+func weeklyLoadProfile(dataPoints []metrics.DataPoint, block time.Duration) []float64 {
+	profile := make([]float64, week/block)
+	for _, dp := range dataPoints {
+		if dp.Gap {
+			continue
+		}
+		i := int(sinceStartOfWeek(dp.Timestamp) / block)
+		profile[i] = math.Max(profile[i], dp.Value)
+	}
+	return profile
+}
+
+// scheduleEntries returns the MinReplicasScheduleEntries of the blocks the schedule raises above the min replicas of
+// the HPA, with the consecutive blocks that schedule the same replicas merged into one entry. The PeakLoad of an
+// entry is the peak of the load profile over its blocks.
+func scheduleEntries(schedule *weeklySchedule,
+	profile []float64,
+	minReplicas int) []v1alpha1.MinReplicasScheduleEntry {
+
+	var entries []v1alpha1.MinReplicasScheduleEntry
+	for i := 0; i < len(schedule.minReplicas); {
+		replicas := int(schedule.minReplicas[i])
+		if replicas <= minReplicas {
+			i++
+			continue
+		}
+		end := i
+		peakLoad := 0.0
+		for end < len(schedule.minReplicas) && int(schedule.minReplicas[end]) == replicas {
+			peakLoad = math.Max(peakLoad, profile[end])
+			end++
+		}
+		entries = append(entries, v1alpha1.MinReplicasScheduleEntry{
+			Start:       weeklyCron(time.Duration(i) * schedule.block),
+			End:         weeklyCron(time.Duration(end) * schedule.block),
+			Timezone:    ScheduleTimezone,
+			MinReplicas: replicas,
+			PeakLoad:    *milliQuantity(peakLoad),
+		})
+		i = end
+	}
+	return entries
+}
+
+// weeklyCron returns the cron expression that fires every week at the time from the start of the week.
+func weeklyCron(sinceStartOfWeek time.Duration) string {
+	sinceStartOfWeek %= week
+	day := sinceStartOfWeek / (24 * time.Hour)
+	sinceStartOfDay := sinceStartOfWeek % (24 * time.Hour)
+	return fmt.Sprintf("%d %d * * %d", int((sinceStartOfDay%time.Hour)/time.Minute),
+		int(sinceStartOfDay/time.Hour), int(day))
+}
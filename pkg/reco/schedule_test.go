@@ -0,0 +1,142 @@
+package reco
+
+import (
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+var _ = Describe("MinReplicasSchedule", func() {
+	const (
+		step = time.Minute
+		acl  = 10 * time.Minute
+	)
+
+	// series returns two weeks at one minute step of a demand of 2 cores, which ramps up to 20 cores over two minutes
+	// at 09:00 on weekdays and stays there for an hour.
+	series := func() metricSeries {
+		// A Sunday.
+		start := time.Date(2023, 7, 2, 0, 0, 0, 0, time.UTC)
+		dataPoints := make([]metrics.DataPoint, 2*week/step)
+		for i := range dataPoints {
+			timestamp := start.Add(time.Duration(i) * step)
+			value := 2.0
+			if timestamp.Weekday() != time.Saturday && timestamp.Weekday() != time.Sunday && timestamp.Hour() == 9 {
+				value = 20
+				if timestamp.Minute() == 0 {
+					value = 11
+				}
+			}
+			dataPoints[i] = metrics.DataPoint{Timestamp: timestamp, Value: value}
+		}
+		return metricSeries{name: CPUMetric, step: step, dataPoints: dataPoints, perPodResources: 1, target: 60}
+	}
+
+	newSimulator := func() *simulator {
+		return &simulator{redLineUtil: redLineUtil, metricStep: step, logger: logger}
+	}
+
+	It("should schedule the replicas ahead of the ramps so that the simulation shows no breach", func() {
+		s := newSimulator()
+		cpu := series()
+		bounds := ReplicaBounds{Min: 1}
+
+		simulation, err := s.simulateHPA(cpu.dataPoints, acl, cpu.target, cpu.perPodResources, bounds)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.usage.Consumed.Duration).To(BeNumerically(">", 0))
+
+		schedule, breachesAtMaxReplicas, err := s.findMinReplicasSchedule(cpu, acl, bounds, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(breachesAtMaxReplicas).To(BeZero())
+
+		hpaSimulator := s.newHPASimulator(cpu.dataPoints, acl, cpu.perPodResources, bounds)
+		hpaSimulator.schedule = schedule
+		simulation, err = hpaSimulator.run(cpu.target, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.breaches).To(BeZero())
+
+		// 24 pods at the red line cover the 20 cores, and the block before the ramp gets them ready in time.
+		entries := scheduleEntries(schedule, weeklyLoadProfile(cpu.dataPoints, time.Hour), bounds.Min)
+		Expect(entries).To(HaveLen(5))
+		for i, entry := range entries {
+			Expect(entry.Start).To(Equal(weeklyCron(time.Duration(i+1)*24*time.Hour + 8*time.Hour)))
+			Expect(entry.End).To(Equal(weeklyCron(time.Duration(i+1)*24*time.Hour + 10*time.Hour)))
+			Expect(entry.Timezone).To(Equal(ScheduleTimezone))
+			Expect(entry.MinReplicas).To(Equal(24))
+			Expect(entry.PeakLoad.AsApproximateFloat64()).To(BeNumerically("~", 20, 0.001))
+		}
+	})
+
+	It("should leave the breaches at the max replicas to a warning", func() {
+		cpu := series()
+		schedule, breachesAtMaxReplicas, err := newSimulator().findMinReplicasSchedule(cpu, acl,
+			ReplicaBounds{Min: 1, Max: 10}, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(breachesAtMaxReplicas).To(BeNumerically(">", 0))
+		for _, replicas := range schedule.minReplicas {
+			Expect(replicas).To(BeNumerically("<=", 24))
+		}
+	})
+
+	It("should raise the blocks around the end of the week", func() {
+		schedule := newWeeklySchedule(time.Hour)
+		saturday := time.Date(2023, 7, 8, 23, 55, 0, 0, time.UTC)
+		Expect(schedule.raise(saturday, saturday.Add(10*time.Minute), 4)).To(BeTrue())
+		Expect(schedule.raise(saturday, saturday.Add(10*time.Minute), 3)).To(BeFalse())
+		Expect(schedule.minReplicas[167]).To(Equal(4.0))
+		Expect(schedule.minReplicas[0]).To(Equal(4.0))
+		Expect(schedule.minReplicas[1]).To(BeZero())
+	})
+
+	It("should write the times of the week as weekly cron expressions", func() {
+		Expect(weeklyCron(0)).To(Equal("0 0 * * 0"))
+		Expect(weeklyCron(3*24*time.Hour + 9*time.Hour + 30*time.Minute)).To(Equal("30 9 * * 3"))
+		Expect(weeklyCron(week)).To(Equal("0 0 * * 0"))
+	})
+
+	It("should fall back to hour long blocks when the block doesn't divide a week", func() {
+		Expect(NewMinReplicasScheduleRecommender(recommender, 90*time.Second).block).To(Equal(time.Hour))
+		Expect(NewMinReplicasScheduleRecommender(recommender, 30*time.Minute).block).To(Equal(30 * time.Minute))
+	})
+
+	It("should recommend the HPAConfiguration of the cpu recommender along with the schedule", func() {
+		const namespace = "default"
+		replicas := int32(4)
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-min-replicas-schedule", Namespace: namespace},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test-min-replicas-schedule"}},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test-min-replicas-schedule"}},
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "container-1",
+						Image: "container-image", Resources: corev1.ResourceRequirements{
+							Limits: corev1.ResourceList{corev1.ResourceCPU: *milliQuantity(8.2)}}}}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+		defer func() {
+			Expect(k8sClient.Delete(ctx, deployment)).To(Succeed())
+		}()
+		workloadSpec := v1alpha1.WorkloadSpec{
+			Name:      deployment.Name,
+			Namespace: namespace,
+			TypeMeta:  metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+		}
+
+		expected, err := recommender.Recommend(workloadSpec)
+		Expect(err).NotTo(HaveOccurred())
+		recommendation, err := NewMinReplicasScheduleRecommender(recommender, time.Hour).Recommend(workloadSpec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recommendation.HPAConfiguration).To(Equal(expected.HPAConfiguration))
+		// The fake metrics don't breach the HPA at the recommended target, so there is nothing to schedule.
+		Expect(recommendation.MinReplicasSchedule).To(BeEmpty())
+		Expect(recommendation.Warnings).To(Equal(expected.Warnings))
+	})
+})
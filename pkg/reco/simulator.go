@@ -0,0 +1,395 @@
+package reco
+
+import (
+	"errors"
+	"fmt"
+	"github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	"github.com/go-logr/logr"
+	"math"
+	"time"
+)
+
+// simulator replays the HPA of a workload over its metrics to find the highest target utilization the workload can
+// run at within the breach budget. It is shared by the recommenders that size the HPA on the utilization of a
+// resource, and works in the units of the data points it is given.
+type simulator struct {
+	redLineUtil  float64
+	metricStep   time.Duration
+	breachBudget BreachBudget
+	hpaBehavior  HPABehavior
+	forecast     ForecastConfig
+	logger       logr.Logger
+}
+
+type TimerEvent struct {
+	Timestamp time.Time
+	Delta     float64
+}
+
+// timerQueue holds the upscale events of the simulated HPA until the ACL elapses. The ACL is the same for all the
+// events, so they fire in the order they are queued. The queue is a ring buffer that keeps its storage across
+// simulations.
+type timerQueue struct {
+	events []TimerEvent
+	head   int
+	size   int
+}
+
+func (q *timerQueue) push(event TimerEvent) {
+	if q.size == len(q.events) {
+		events := make([]TimerEvent, 2*len(q.events)+8)
+		for i := 0; i < q.size; i++ {
+			events[i] = q.events[(q.head+i)%len(q.events)]
+		}
+		q.events = events
+		q.head = 0
+	}
+	q.events[(q.head+q.size)%len(q.events)] = event
+	q.size++
+}
+
+// peek returns the next event to fire. The queue must not be empty.
+func (q *timerQueue) peek() TimerEvent {
+	return q.events[q.head]
+}
+
+func (q *timerQueue) pop() {
+	q.head = (q.head + 1) % len(q.events)
+	q.size--
+}
+
+func (q *timerQueue) clear() {
+	q.head = 0
+	q.size = 0
+}
+
+// subtractQueued returns the value less the deltas of the queued events, subtracted in the order they were queued.
+func (q *timerQueue) subtractQueued(value float64) float64 {
+	for i := 0; i < q.size; i++ {
+		value -= q.events[(q.head+i)%len(q.events)].Delta
+	}
+	return value
+}
+
+// hpaSimulator simulates the HPA of a workload over the same data points at one target utilization after another, as
+// the search for the optimal target does, and reuses its buffers across the simulations. It isn't safe for
+// concurrent use, so every search creates its own.
+type hpaSimulator struct {
+	*simulator
+	dataPoints      []metrics.DataPoint
+	acl             time.Duration
+	perPodResources float64
+	bounds          ReplicaBounds
+	// budget is the usage of the breach budget over the data points before any breach.
+	budget v1alpha1.BreachBudgetUsage
+	// schedule raises the min bound over the blocks of the week it schedules more replicas for, if set.
+	schedule   *weeklySchedule
+	simulation hpaSimulation
+	timers     timerQueue
+	scaler     hpaScaler
+}
+
+// newHPASimulator returns an hpaSimulator of the HPA over the data points. The data points marked as a Gap are
+// skipped.
+// acl - Autoscaling Cycle Lag for the workload
+// perPodResources - these are required ot more accurately mimic the working of HPA by making the available resources
+// multiples of perPodResources.
+// bounds - the min and max replicas of the HPA.
+func (s *simulator) newHPASimulator(dataPoints []metrics.DataPoint,
+	acl time.Duration,
+	perPodResources float64,
+	bounds ReplicaBounds) *hpaSimulator {
+
+	return &hpaSimulator{
+		simulator:       s,
+		dataPoints:      dataPoints,
+		acl:             acl,
+		perPodResources: perPodResources,
+		bounds:          bounds,
+		budget:          s.newBreachBudgetUsage(dataPoints),
+		scaler:          hpaScaler{behavior: s.hpaBehavior},
+	}
+}
+
+// simulateHPA simulates the operation of HPA by adding a delay of amount Autoscaling Cycle Lag (ACL)
+// to all upscale events. The replicas follow the configured HPABehavior, so that scale downs are held back by the
+// stabilization window and the tolerance band, and both directions are limited by the scaling policies. The replicas
+// are clamped to the bounds, and the simulation marks the data points at which the max bound held the HPA back.
+// It takes as input
+// dataPoints - sum of cpu utilization data points for a workload. The data points marked as a Gap are skipped.
+// acl - Autoscaling Cycle Lag for the workload
+// perPodResources - these are required ot more accurately mimic the working of HPA by making the available resources
+// multiples of perPodResources.
+// bounds - the min and max replicas of the HPA.
+
+func (s *simulator) simulateHPA(dataPoints []metrics.DataPoint,
+	acl time.Duration,
+	targetUtilization int,
+	perPodResources float64,
+	bounds ReplicaBounds) (*hpaSimulation, error) {
+
+	return s.newHPASimulator(dataPoints, acl, perPodResources, bounds).run(targetUtilization, false)
+}
+
+// run simulates the HPA at the target utilization, and accounts for the breaches of the simulated capacity against
+// the breach budget as it goes. With stopEarly, the simulation stops at the breach that rejects the target whatever
+// the max replicas bound does, i.e. once the breaches below the max bound alone exceed the budget, as the rest of
+// the data points can't bring the target back within it. The simulation returned is overwritten by the next run.
+func (h *hpaSimulator) run(targetUtilization int, stopEarly bool) (*hpaSimulation, error) {
+	dataPoints := h.dataPoints
+	simulation := h.reset(len(dataPoints))
+	if len(dataPoints) == 0 {
+		return simulation, nil
+	}
+	if targetUtilization < 1 || targetUtilization > 100 {
+		return nil, errors.New(fmt.Sprintf("Invalid value of target utilization: %v."+
+			" Value should be between 1 and 100", targetUtilization))
+	}
+
+	// The simulation starts at the first data point that isn't a Gap.
+	first := 0
+	for first < len(dataPoints)-1 && dataPoints[first].Gap {
+		simulation.dataPoints[first] = metrics.DataPoint{Timestamp: dataPoints[first].Timestamp, Gap: true}
+		first++
+	}
+
+	perPodResources := h.perPodResources
+	currentReplicas := math.Ceil((dataPoints[first].Value * 100) / float64(targetUtilization) / perPodResources)
+	desiredMaxReplicas := currentReplicas
+	currentReplicas, simulation.atMaxReplicas[first] = h.clamp(dataPoints[first].Timestamp, currentReplicas)
+	simulation.replicas[first] = currentReplicas
+	minReplicas := currentReplicas
+	maxReplicas := currentReplicas
+	currentResources := currentReplicas * perPodResources
+	readyResources := currentResources
+
+	simulation.dataPoints[first] = metrics.DataPoint{Timestamp: dataPoints[first].Timestamp,
+		Value: currentResources * h.redLineUtil, Gap: dataPoints[first].Gap}
+	breaches := breachAccount{
+		all:              h.newBreachMeter(&simulation.usage),
+		belowMaxReplicas: h.newBreachMeter(&simulation.usageBelowMaxReplicas),
+	}
+	if !dataPoints[first].Gap {
+		h.account(&breaches, first)
+	}
+
+	end := len(dataPoints)
+	for i := first + 1; i < end; i++ {
+		dp := dataPoints[i]
+
+		// Consume timers for all upscale events before the current time.
+		for h.timers.size > 0 && !dp.Timestamp.Before(h.timers.peek().Timestamp) {
+			readyResources += h.timers.peek().Delta
+			h.timers.pop()
+		}
+		// The HPA holds its replicas through a gap in the metrics, as it can't measure the utilization either.
+		simulation.replicas[i] = currentReplicas
+		if dp.Gap {
+			simulation.dataPoints[i] = metrics.DataPoint{Timestamp: dp.Timestamp, Value: readyResources * h.redLineUtil,
+				Gap: true}
+			breaches.endBreach()
+			continue
+		}
+		desiredReplicas := math.Ceil((100 * dp.Value) / float64(targetUtilization) / perPodResources)
+		utilizationRatio := (100 * dp.Value) / (currentReplicas * perPodResources) / float64(targetUtilization)
+		newReplicas := h.scaler.scale(dp.Timestamp, currentReplicas, desiredReplicas, utilizationRatio)
+		desiredMaxReplicas = math.Max(newReplicas, desiredMaxReplicas)
+		newReplicas, simulation.atMaxReplicas[i] = h.clamp(dp.Timestamp, newReplicas)
+		currentReplicas = newReplicas
+		simulation.replicas[i] = newReplicas
+		minReplicas = math.Min(newReplicas, minReplicas)
+		maxReplicas = math.Max(newReplicas, maxReplicas)
+
+		newResources := newReplicas * perPodResources
+		currentResources = newResources
+
+		if newResources > readyResources {
+			//subtract delta that is already in queue.
+			delta := h.timers.subtractQueued(newResources - readyResources)
+			if delta > 0 {
+				h.timers.push(TimerEvent{Timestamp: dp.Timestamp.Add(h.acl), Delta: delta})
+			}
+		} else {
+			readyResources = newResources
+			h.timers.clear()
+		}
+
+		availableResources := readyResources * h.redLineUtil
+		simulation.dataPoints[i] = metrics.DataPoint{Timestamp: dp.Timestamp, Value: availableResources}
+		h.account(&breaches, i)
+		if stopEarly && !isBreachBudgetUsageWithinBudget(simulation.usageBelowMaxReplicas) {
+			simulation.stoppedEarly = true
+			end = i + 1
+		}
+	}
+
+	simulation.dataPoints = simulation.dataPoints[:end]
+	simulation.replicas = simulation.replicas[:end]
+	simulation.atMaxReplicas = simulation.atMaxReplicas[:end]
+	simulation.minReplicas = int(minReplicas)
+	simulation.maxReplicas = int(maxReplicas)
+	simulation.desiredMaxReplicas = int(desiredMaxReplicas)
+	return simulation, nil
+}
+
+// clamp returns the replicas within the bounds at the timestamp, and whether the max bound held them back. The min
+// bound is raised to the replicas scheduled for the timestamp, if any.
+func (h *hpaSimulator) clamp(timestamp time.Time, replicas float64) (float64, bool) {
+	if h.schedule != nil {
+		replicas = math.Max(replicas, h.schedule.minReplicasAt(timestamp))
+	}
+	return h.bounds.clamp(replicas)
+}
+
+// reset readies the buffers of the simulation and the state of the HPA for a new run over n data points.
+func (h *hpaSimulator) reset(n int) *hpaSimulation {
+	simulation := &h.simulation
+	if simulation.dataPoints == nil || cap(simulation.dataPoints) < n {
+		simulation.dataPoints = make([]metrics.DataPoint, n)
+		simulation.replicas = make([]float64, n)
+		simulation.atMaxReplicas = make([]bool, n)
+	}
+	simulation.dataPoints = simulation.dataPoints[:n]
+	simulation.replicas = simulation.replicas[:n]
+	simulation.atMaxReplicas = simulation.atMaxReplicas[:n]
+	for i := range simulation.atMaxReplicas {
+		simulation.atMaxReplicas[i] = false
+	}
+
+	simulation.usage = h.budget
+	simulation.usageBelowMaxReplicas = h.budget
+	simulation.breaches = 0
+	simulation.firstBreach = time.Time{}
+	simulation.stoppedEarly = false
+	simulation.minReplicas = 0
+	simulation.maxReplicas = 0
+	simulation.desiredMaxReplicas = 0
+
+	h.timers.clear()
+	h.scaler.reset()
+	return simulation
+}
+
+// breachAccount measures the breaches of a simulation, across all the data points and across those not held back by
+// the max bound.
+type breachAccount struct {
+	all              breachMeter
+	belowMaxReplicas breachMeter
+}
+
+// endBreach ends the breach in progress, as a gap in the metrics does.
+func (b *breachAccount) endBreach() {
+	b.all.end()
+	b.belowMaxReplicas.end()
+}
+
+// account accounts for the breach, if any, of the simulated capacity at the data point.
+func (h *hpaSimulator) account(breaches *breachAccount, i int) {
+	simulation := &h.simulation
+	demand := h.dataPoints[i].Value
+	capacity := simulation.dataPoints[i].Value
+	if !breaches.all.measure(demand, capacity) {
+		breaches.belowMaxReplicas.end()
+		return
+	}
+	if simulation.breaches == 0 {
+		simulation.firstBreach = h.dataPoints[i].Timestamp
+	}
+	simulation.breaches++
+
+	// Lowering the target adds no capacity beyond the max bound, so the breaches held back by it are accounted apart.
+	if simulation.atMaxReplicas[i] {
+		breaches.belowMaxReplicas.end()
+		return
+	}
+	breaches.belowMaxReplicas.measure(demand, capacity)
+}
+
+// targetSearch is the outcome of the search for the optimal target utilization of a workload.
+type targetSearch struct {
+	// target is the highest target utilization whose breaches fit in the breach budget, and is the min target when
+	// none does.
+	target int
+	// minReplicas and maxReplicas are the replicas the HPA scaled the workload between at the target, and replicas
+	// the replicas it scaled the workload to at every data point.
+	minReplicas int
+	maxReplicas int
+	replicas    []float64
+	// usage is the breach budget consumed at the target.
+	usage v1alpha1.BreachBudgetUsage
+	// warning is set when the target was only accepted because the breaches over the budget were held back by the
+	// max replicas bound, or when no target fits in the budget.
+	warning *Warning
+	// candidates is every target tried, to explain the search.
+	candidates []CandidateTarget
+}
+
+// findOptimalTargetUtilization binary searches for the highest target utilization whose simulated breaches fit in the
+// breach budget. Lowering the target adds no capacity beyond the max replicas bound, so a target whose breaches are
+// within budget but for the ones at the max bound is accepted, with a Warning with the max replicas that would avoid
+// them. The simulation of a target stops at the first breach that rejects it. When no target fits in the budget, the
+// search falls back to the min target, the one that breaches the least, with a Warning.
+func (s *simulator) findOptimalTargetUtilization(dataPoints []metrics.DataPoint,
+	acl time.Duration,
+	minTarget,
+	maxTarget int,
+	perPodResources float64,
+	bounds ReplicaBounds) (*targetSearch, error) {
+	low := minTarget
+	high := maxTarget
+	// The replicas, the usage and the warning are those of the last target accepted, which is the one
+	// the search settles on. The simulation of an accepted target always runs to the end.
+	search := &targetSearch{}
+
+	hpaSimulator := s.newHPASimulator(dataPoints, acl, perPodResources, bounds)
+	for low <= high {
+		mid := low + (high-low)/2
+		target := mid
+		simulation, err := hpaSimulator.run(target, true)
+		if err != nil {
+			s.logger.Error(err, "Error while simulating HPA")
+			return nil, err
+		}
+
+		withinBudget := isBreachBudgetUsageWithinBudget(simulation.usage)
+		var warning *Warning
+		if !withinBudget && simulation.hitMaxReplicas() &&
+			isBreachBudgetUsageWithinBudget(simulation.usageBelowMaxReplicas) {
+			withinBudget = true
+			warning = maxReplicasWarning(bounds, simulation.desiredMaxReplicas)
+		}
+		search.candidates = append(search.candidates, newCandidateTarget(target, withinBudget, simulation))
+		if withinBudget {
+			search.minReplicas, search.maxReplicas = simulation.minReplicas, simulation.maxReplicas
+			search.replicas = append(search.replicas[:0], simulation.replicas...)
+			search.usage = simulation.usage
+			search.warning = warning
+			low = mid + 1
+		} else {
+			high = mid - 1
+		}
+	}
+	search.target = high
+	if search.target >= minTarget {
+		return search, nil
+	}
+
+	// The simulation of the min target may have stopped at the breach that rejected it, so it's run again in full.
+	simulation, err := hpaSimulator.run(minTarget, false)
+	if err != nil {
+		s.logger.Error(err, "Error while simulating HPA")
+		return nil, err
+	}
+	search.target = minTarget
+	search.minReplicas, search.maxReplicas = simulation.minReplicas, simulation.maxReplicas
+	search.replicas = append(search.replicas[:0], simulation.replicas...)
+	search.usage = simulation.usage
+	search.warning = &Warning{
+		Reason: BreachesBeyondBudget,
+		Message: fmt.Sprintf("no target utilization between %d and %d keeps the breaches within the breach budget; "+
+			"the min target of %d consumes %s of the %s allowed", minTarget, maxTarget, minTarget,
+			simulation.usage.Consumed.Duration, simulation.usage.Allowed.Duration),
+	}
+	return search, nil
+}
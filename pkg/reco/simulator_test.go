@@ -0,0 +1,296 @@
+package reco
+
+import (
+	"fmt"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+const benchmarkStep = 30 * time.Second
+
+// benchmarkSeries returns 28 days of the cpu demand of a workload at 30 second steps, about 80k data points, with a
+// daily and a weekly season, noise, short spikes and a few gaps in the metrics.
+func benchmarkSeries() []metrics.DataPoint {
+	random := rand.New(rand.NewSource(42))
+	start := time.Date(2023, 7, 3, 0, 0, 0, 0, time.UTC)
+	dataPoints := make([]metrics.DataPoint, 28*24*time.Hour/benchmarkStep)
+	for i := range dataPoints {
+		timestamp := start.Add(time.Duration(i) * benchmarkStep)
+		hour := float64(timestamp.Hour()) + float64(timestamp.Minute())/60
+		daily := math.Max(0, math.Sin((hour-6)/24*2*math.Pi))
+		weekly := 1.0
+		if timestamp.Weekday() == time.Saturday || timestamp.Weekday() == time.Sunday {
+			weekly = 0.6
+		}
+		value := 20 + 180*daily*weekly + random.NormFloat64()*5
+		if random.Float64() < 0.001 {
+			value *= 1.5
+		}
+		dataPoints[i] = metrics.DataPoint{Timestamp: timestamp, Value: math.Max(value, 1)}
+	}
+	for _, gap := range []int{5000, 30000, 60000} {
+		for i := gap; i < gap+20; i++ {
+			dataPoints[i] = metrics.DataPoint{Timestamp: dataPoints[i].Timestamp, Gap: true}
+		}
+	}
+	return dataPoints
+}
+
+var _ = Describe("simulator", func() {
+	// The search must settle on the same target, replicas and usage as a search that simulates every target in full
+	// and measures its breaches afterwards.
+	DescribeTable("findOptimalTargetUtilization should agree with the simulation of every target in full",
+		func(breachBudget BreachBudget, hpaBehavior HPABehavior, bounds ReplicaBounds) {
+			s := benchmarkSimulator(breachBudget, hpaBehavior)
+			dataPoints := benchmarkSeries()[:2*24*time.Hour/benchmarkStep]
+			dataPoints[100].Gap = true
+
+			search, err := s.findOptimalTargetUtilization(dataPoints, 5*time.Minute, 10, 90, 4, bounds)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(search.candidates).NotTo(BeEmpty())
+
+			for _, candidate := range search.candidates {
+				simulation, err := s.simulateHPA(dataPoints, 5*time.Minute, candidate.Target, 4, bounds)
+				Expect(err).NotTo(HaveOccurred())
+				withinBudget, fullUsage := measureBreaches(s, dataPoints, simulation.dataPoints)
+				Expect(fullUsage).To(Equal(simulation.usage))
+				if !withinBudget && simulation.hitMaxReplicas() {
+					withinBudget = isBreachBudgetUsageWithinBudget(simulation.usageBelowMaxReplicas)
+				}
+				Expect(candidate.Accepted).To(Equal(withinBudget), "target %d", candidate.Target)
+				if candidate.Accepted {
+					Expect(candidate.Breaches).To(Equal(simulation.breaches))
+					Expect(candidate.BreachBudgetConsumed).To(Equal(simulation.usage.Consumed))
+				}
+				if candidate.Target == search.target && candidate.Accepted {
+					Expect(search.usage).To(Equal(simulation.usage))
+					Expect(search.warning != nil).To(Equal(simulation.hitMaxReplicas() && !isBreachBudgetUsageWithinBudget(
+						simulation.usage)))
+				}
+			}
+
+			// The search settles on the target of the reference search, or falls back to the min target.
+			reference, err := referenceFindOptimalTargetUtilization(s, dataPoints, 5*time.Minute, 10, 90, 4, bounds)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(search.target).To(Equal(int(math.Max(float64(reference), 10))))
+
+			simulation, err := s.simulateHPA(dataPoints, 5*time.Minute, search.target, 4, bounds)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(search.minReplicas).To(Equal(simulation.minReplicas))
+			Expect(search.maxReplicas).To(Equal(simulation.maxReplicas))
+			Expect(search.usage).To(Equal(simulation.usage))
+		},
+		Entry("without a breach budget", BreachBudget{}, HPABehavior{}, ReplicaBounds{}),
+		Entry("with a breach budget", BreachBudget{MaxBreachDurationPerWeek: time.Hour,
+			MaxConsecutiveBreachDuration: 5 * time.Minute, MagnitudeWeighted: true}, HPABehavior{}, ReplicaBounds{}),
+		Entry("with an HPA behavior", BreachBudget{MaxBreachDurationPerWeek: 30 * time.Minute},
+			HPABehavior{Tolerance: 0.1}, ReplicaBounds{}),
+		Entry("with replica bounds", BreachBudget{MaxBreachDurationPerWeek: 30 * time.Minute}, HPABehavior{},
+			ReplicaBounds{Min: 2, Max: 30}),
+	)
+
+	It("should reuse the timer queue across simulations", func() {
+		queue := timerQueue{}
+		for i := 0; i < 20; i++ {
+			queue.push(TimerEvent{Delta: float64(i)})
+			if i%2 == 0 {
+				Expect(queue.peek().Delta).To(Equal(float64(i / 2)))
+				queue.pop()
+			}
+		}
+		Expect(queue.size).To(Equal(10))
+		Expect(queue.peek().Delta).To(Equal(10.0))
+		Expect(queue.subtractQueued(200)).To(Equal(200.0 - 145))
+		queue.clear()
+		Expect(queue.size).To(BeZero())
+	})
+})
+
+func benchmarkSimulator(breachBudget BreachBudget, hpaBehavior HPABehavior) *simulator {
+	return &simulator{
+		redLineUtil:  0.85,
+		metricStep:   benchmarkStep,
+		breachBudget: breachBudget,
+		hpaBehavior:  hpaBehavior,
+		logger:       logr.Discard(),
+	}
+}
+
+// benchmarkFindOptimalTargetUtilization times the search against referenceFindOptimalTargetUtilization over the same
+// series, as the sub-benchmarks "search" and "reference", so that the two can be compared with benchstat.
+func benchmarkFindOptimalTargetUtilization(b *testing.B, s *simulator, bounds ReplicaBounds) {
+	dataPoints := benchmarkSeries()
+	b.Run("search", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := s.findOptimalTargetUtilization(dataPoints, 5*time.Minute, 10, 90, 4, bounds); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("reference", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := referenceFindOptimalTargetUtilization(s, dataPoints, 5*time.Minute, 10, 90, 4,
+				bounds); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// referenceFindOptimalTargetUtilization is the search for the optimal target utilization as it was before the
+// simulations shared their buffers: every target is simulated in full by referenceSimulateHPA, and its breaches are
+// measured in a second pass. It is kept as the baseline of the benchmarks and doesn't know about schedules. It
+// returns minTarget-1 when no target fits in the breach budget.
+func referenceFindOptimalTargetUtilization(s *simulator,
+	dataPoints []metrics.DataPoint,
+	acl time.Duration,
+	minTarget,
+	maxTarget int,
+	perPodResources float64,
+	bounds ReplicaBounds) (int, error) {
+
+	low := minTarget
+	high := maxTarget
+	for low <= high {
+		mid := low + (high-low)/2
+		simulated, atMaxReplicas, err := referenceSimulateHPA(s, dataPoints, acl, mid, perPodResources, bounds)
+		if err != nil {
+			return 0, err
+		}
+		withinBudget, _ := measureBreaches(s, dataPoints, simulated)
+		if !withinBudget {
+			// The breaches at the data points held back by the max bound are taken out.
+			hitMaxReplicas := false
+			for i := range simulated {
+				if atMaxReplicas[i] {
+					simulated[i].Value = math.Max(simulated[i].Value, dataPoints[i].Value)
+					hitMaxReplicas = true
+				}
+			}
+			if hitMaxReplicas {
+				withinBudget, _ = measureBreaches(s, dataPoints, simulated)
+			}
+		}
+		if withinBudget {
+			low = mid + 1
+		} else {
+			high = mid - 1
+		}
+	}
+	return high, nil
+}
+
+// referenceSimulateHPA simulates the HPA the way simulateHPA did before the simulations shared their buffers. It
+// returns the simulated capacity and the data points at which the max bound held the HPA back.
+func referenceSimulateHPA(s *simulator,
+	dataPoints []metrics.DataPoint,
+	acl time.Duration,
+	targetUtilization int,
+	perPodResources float64,
+	bounds ReplicaBounds) ([]metrics.DataPoint, []bool, error) {
+
+	if targetUtilization < 1 || targetUtilization > 100 {
+		return nil, nil, fmt.Errorf("invalid value of target utilization: %v", targetUtilization)
+	}
+	simulatedDataPoints := make([]metrics.DataPoint, len(dataPoints))
+	atMaxReplicas := make([]bool, len(dataPoints))
+
+	first := 0
+	for first < len(dataPoints)-1 && dataPoints[first].Gap {
+		simulatedDataPoints[first] = metrics.DataPoint{Timestamp: dataPoints[first].Timestamp, Gap: true}
+		first++
+	}
+
+	currentReplicas := math.Ceil((dataPoints[first].Value * 100) / float64(targetUtilization) / perPodResources)
+	currentReplicas, atMaxReplicas[first] = bounds.clamp(currentReplicas)
+	readyResources := currentReplicas * perPodResources
+	simulatedDataPoints[first] = metrics.DataPoint{Timestamp: dataPoints[first].Timestamp,
+		Value: readyResources * s.redLineUtil, Gap: dataPoints[first].Gap}
+
+	var timers []TimerEvent
+	scaler := newHPAScaler(s.hpaBehavior)
+	for i := first + 1; i < len(dataPoints); i++ {
+		dp := dataPoints[i]
+		for len(timers) > 0 && !dp.Timestamp.Before(timers[0].Timestamp) {
+			readyResources += timers[0].Delta
+			timers = timers[1:]
+		}
+		if dp.Gap {
+			simulatedDataPoints[i] = metrics.DataPoint{Timestamp: dp.Timestamp, Value: readyResources * s.redLineUtil,
+				Gap: true}
+			continue
+		}
+		desiredReplicas := math.Ceil((100 * dp.Value) / float64(targetUtilization) / perPodResources)
+		utilizationRatio := (100 * dp.Value) / (currentReplicas * perPodResources) / float64(targetUtilization)
+		newReplicas := scaler.scale(dp.Timestamp, currentReplicas, desiredReplicas, utilizationRatio)
+		newReplicas, atMaxReplicas[i] = bounds.clamp(newReplicas)
+		currentReplicas = newReplicas
+
+		newResources := newReplicas * perPodResources
+		if newResources > readyResources {
+			delta := newResources - readyResources
+			for _, timer := range timers {
+				delta -= timer.Delta
+			}
+			if delta > 0 {
+				timers = append(timers, TimerEvent{Timestamp: dp.Timestamp.Add(acl), Delta: delta})
+			}
+		} else {
+			readyResources = newResources
+			timers = []TimerEvent{}
+		}
+		simulatedDataPoints[i] = metrics.DataPoint{Timestamp: dp.Timestamp, Value: readyResources * s.redLineUtil}
+	}
+	return simulatedDataPoints, atMaxReplicas, nil
+}
+
+func BenchmarkSimulateHPA(b *testing.B) {
+	s := benchmarkSimulator(BreachBudget{}, HPABehavior{})
+	dataPoints := benchmarkSeries()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.simulateHPA(dataPoints, 5*time.Minute, 50, 4, ReplicaBounds{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFindOptimalTargetUtilization(b *testing.B) {
+	benchmarkFindOptimalTargetUtilization(b, benchmarkSimulator(BreachBudget{}, HPABehavior{}), ReplicaBounds{})
+}
+
+func BenchmarkFindOptimalTargetUtilizationWithBreachBudget(b *testing.B) {
+	breachBudget := BreachBudget{MaxBreachDurationPerWeek: time.Hour, MagnitudeWeighted: true}
+	benchmarkFindOptimalTargetUtilization(b, benchmarkSimulator(breachBudget, HPABehavior{}), ReplicaBounds{})
+}
+
+func BenchmarkFindOptimalTargetUtilizationWithHPABehavior(b *testing.B) {
+	stabilizationWindow := int32(300)
+	hpaBehavior := HPABehavior{
+		Tolerance: 0.1,
+		Behavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
+			ScaleDown: &autoscalingv2.HPAScalingRules{
+				StabilizationWindowSeconds: &stabilizationWindow,
+				Policies: []autoscalingv2.HPAScalingPolicy{
+					{Type: autoscalingv2.PercentScalingPolicy, Value: 10, PeriodSeconds: 60},
+				},
+			},
+		},
+	}
+	benchmarkFindOptimalTargetUtilization(b, benchmarkSimulator(BreachBudget{}, hpaBehavior), ReplicaBounds{})
+}
+
+func BenchmarkFindOptimalTargetUtilizationWithMaxReplicas(b *testing.B) {
+	benchmarkFindOptimalTargetUtilization(b, benchmarkSimulator(BreachBudget{}, HPABehavior{}),
+		ReplicaBounds{Min: 2, Max: 40})
+}
@@ -32,10 +32,65 @@ var (
 	metricStep   = 5 * time.Minute
 	minTarget    = 10
 	maxTarget    = 60
-	fakeScraper  metrics.Scraper
-	recommender  *CpuUtilizationBasedRecommender
+	// No headroom, so that the max replicas is the one seen in the simulation.
+	maxHeadroomFactor = 1.0
+	fakeScraper       metrics.Scraper
+	recommender       *CpuUtilizationBasedRecommender
 )
 
+// recommenderOptions overrides the suite defaults of the recommenders built by the tests. The zero values stand for
+// the defaults.
+type recommenderOptions struct {
+	scraper       metrics.Scraper
+	step          time.Duration
+	breachBudget  BreachBudget
+	hpaBehavior   HPABehavior
+	forecast      ForecastConfig
+	capacityBasis CapacityBasis
+}
+
+func (o recommenderOptions) withDefaults() recommenderOptions {
+	if o.scraper == nil {
+		o.scraper = fakeScraper
+	}
+	if o.step == 0 {
+		o.step = metricStep
+	}
+	if o.capacityBasis == "" {
+		o.capacityBasis = RequestsCapacity
+	}
+	return o
+}
+
+func newCpuRecommender(options recommenderOptions) *CpuUtilizationBasedRecommender {
+	o := options.withDefaults()
+	return NewCpuUtilizationBasedRecommender(k8sClient, redLineUtil, metricWindow, o.scraper, o.step, minTarget,
+		maxTarget, maxHeadroomFactor, o.breachBudget, o.hpaBehavior, ReplicaBounds{}, o.forecast, o.capacityBasis,
+		logger)
+}
+
+func newMemoryRecommender(options recommenderOptions) *MemoryUtilizationBasedRecommender {
+	o := options.withDefaults()
+	return NewMemoryUtilizationBasedRecommender(k8sClient, redLineUtil, metricWindow, o.scraper, o.step, minTarget,
+		maxTarget, maxHeadroomFactor, o.breachBudget, o.hpaBehavior, ReplicaBounds{}, o.forecast, logger)
+}
+
+func newCustomMetricRecommender(options recommenderOptions) *CustomMetricRecommender {
+	o := options.withDefaults()
+	return NewCustomMetricRecommender(k8sClient, redLineUtil, metricWindow, o.scraper, o.step, minTarget, maxTarget,
+		maxHeadroomFactor, o.breachBudget, o.hpaBehavior, ReplicaBounds{}, o.forecast, logger)
+}
+
+// dataPointsOf returns data points at the given step with the given values, starting an hour ago.
+func dataPointsOf(step time.Duration, values ...float64) []metrics.DataPoint {
+	start := time.Now().Add(-time.Hour)
+	dataPoints := make([]metrics.DataPoint, len(values))
+	for i, value := range values {
+		dataPoints[i] = metrics.DataPoint{Timestamp: start.Add(time.Duration(i) * step), Value: value}
+	}
+	return dataPoints
+}
+
 type FakeScraper struct{}
 
 func (fs *FakeScraper) GetAverageCPUUtilizationByWorkload(namespace,
@@ -64,8 +119,36 @@ func (fs *FakeScraper) GetCPUUtilizationBreachDataPoints(namespace,
 	return []metrics.DataPoint{datapoint}, nil
 }
 func (fs *FakeScraper) GetACLByWorkload(namespace,
-	workload string) (time.Duration, error) {
-	return 5 * time.Minute, nil
+	workload string) (metrics.AutoscalingCycleLag, error) {
+	return metrics.AutoscalingCycleLag{Readiness: 5 * time.Minute}, nil
+}
+
+// GetMemoryWorkingSetByWorkload returns the same series as the cpu utilization, so that the recommenders can be
+// compared on the same demand.
+func (fs *FakeScraper) GetMemoryWorkingSetByWorkload(namespace,
+	workload string,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]metrics.DataPoint, error) {
+	return fs.GetAverageCPUUtilizationByWorkload(namespace, workload, start, end, step)
+}
+
+func (fs *FakeScraper) GetMemoryLimitsPerPodByWorkload(namespace,
+	workload string) (float64, error) {
+	return 8.2, nil
+}
+
+func (fs *FakeScraper) GetMemoryRequestsPerPodByWorkload(namespace,
+	workload string) (float64, error) {
+	return 4.1, nil
+}
+
+// GetMetricByQuery returns the same series as the cpu utilization, whatever the query.
+func (fs *FakeScraper) GetMetricByQuery(query string,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]metrics.DataPoint, error) {
+	return fs.GetAverageCPUUtilizationByWorkload("", "", start, end, step)
 }
 func TestPolicies(t *testing.T) {
 	RegisterFailHandler(Fail)
@@ -97,8 +180,7 @@ var _ = BeforeSuite(func() {
 
 	fakeScraper = &FakeScraper{}
 
-	recommender = NewCpuUtilizationBasedRecommender(k8sClient, redLineUtil,
-		metricWindow, fakeScraper, metricStep, minTarget, maxTarget, logger)
+	recommender = newCpuRecommender(recommenderOptions{})
 
 	go func() {
 		defer GinkgoRecover()
@@ -0,0 +1,150 @@
+package trigger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	ottoscaleriov1alpha1 "github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	"github.com/flipkart-incubator/ottoscalr/pkg/policy"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"math"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+// maxBreachTimestamps is the number of breached data points whose timestamps are retained as breach evidence.
+const maxBreachTimestamps = 20
+
+// BreachHandler reacts to the breaches detected by the Monitor. It demotes the workload to the previous safer policy,
+// or straight to the safest policy for severe breaches, records the breach evidence on the PolicyRecommendation and
+// queues it for execution so that a new target HPA configuration is generated for the demoted policy. A workload
+// demoted within the last breach window isn't demoted again, as the breaches of that window may predate the HPA of the
+// policy it was demoted to.
+type BreachHandler struct {
+	k8sClient               client.Client
+	policyStore             policy.Store
+	severeBreachUtilization float64
+	breachWindow            time.Duration
+	logger                  logr.Logger
+}
+
+func NewBreachHandler(k8sClient client.Client,
+	policyStore policy.Store,
+	severeBreachUtilization float64,
+	breachWindow time.Duration,
+	logger logr.Logger) *BreachHandler {
+	return &BreachHandler{
+		k8sClient:               k8sClient,
+		policyStore:             policyStore,
+		severeBreachUtilization: severeBreachUtilization,
+		breachWindow:            breachWindow,
+		logger:                  logger,
+	}
+}
+
+func (h *BreachHandler) HandleBreach(workload types.NamespacedName, breaches []metrics.DataPoint) {
+	if len(breaches) == 0 {
+		return
+	}
+
+	policyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{}
+	if err := h.k8sClient.Get(context.Background(), workload, policyRecommendation); err != nil {
+		h.logger.Error(err, "Error while getting policyRecommendation.", "workload", workload)
+		return
+	}
+
+	evidence := h.breachEvidence(breaches)
+	currentPolicy := policyRecommendation.Spec.Policy
+
+	var saferPolicy *ottoscaleriov1alpha1.Policy
+	var err error
+	lastDemotion := lastDemotionTime(policyRecommendation)
+	if lastDemotion.After(evidence.DetectedAt.Add(-h.breachWindow)) {
+		h.logger.Info("Skipping the demotion of a workload demoted within the breach window.", "workload", workload,
+			"lastDemotion", lastDemotion)
+	} else if evidence.Severe {
+		saferPolicy, err = h.policyStore.GetSafestPolicy()
+	} else {
+		saferPolicy, err = h.policyStore.GetPreviousPolicy(&currentPolicy)
+		if errors.Is(err, policy.ErrNoPreviousPolicy) {
+			saferPolicy, err = nil, nil
+		}
+	}
+	if err != nil {
+		h.logger.Error(err, "Error while getting a safer policy.", "workload", workload)
+		return
+	}
+
+	// The status is captured before the spec update, as the update overwrites the object with the server state.
+	status := policyRecommendation.Status.DeepCopy()
+	status.LastBreach = &evidence
+	breachReason := ottoscaleriov1alpha1.ReasonBreached
+	if evidence.Severe {
+		breachReason = ottoscaleriov1alpha1.ReasonSevereBreach
+	}
+	status.SetCondition(ottoscaleriov1alpha1.BreachDetected, metav1.ConditionTrue, breachReason,
+		fmt.Sprintf("CPU utilization peaked at %d%% across %d breached data points",
+			evidence.PeakUtilizationPercent, len(breaches)))
+	status.SetCondition(ottoscaleriov1alpha1.RecommendationQueued, metav1.ConditionTrue,
+		ottoscaleriov1alpha1.ReasonBreached, "Queued for execution after a breach")
+	if saferPolicy != nil && saferPolicy.Spec.ID != currentPolicy.Spec.ID {
+		h.logger.Info("Demoting the workload after a breach.", "workload", workload,
+			"from", currentPolicy.Spec.ID, "to", saferPolicy.Spec.ID, "severe", evidence.Severe)
+		status.PolicyTransitions = policy.AppendTransition(status.PolicyTransitions, ottoscaleriov1alpha1.PolicyTransition{
+			Type:           ottoscaleriov1alpha1.PolicyDemoted,
+			FromPolicy:     currentPolicy.Spec.ID,
+			ToPolicy:       saferPolicy.Spec.ID,
+			TransitionTime: evidence.DetectedAt,
+		})
+		policyRecommendation.Spec.Policy = *saferPolicy
+	}
+
+	policyRecommendation.Spec.QueuedForExecution = true
+	policyRecommendation.Spec.QueuedForExecutionAt = evidence.DetectedAt
+	if err := h.k8sClient.Update(context.Background(), policyRecommendation); err != nil {
+		h.logger.Error(err, "Error while demoting policyRecommendation.", "workload", workload)
+		return
+	}
+
+	policyRecommendation.Status = *status
+	if err := h.k8sClient.Status().Update(context.Background(), policyRecommendation); err != nil {
+		h.logger.Error(err, "Error while recording breach evidence.", "workload", workload)
+	}
+}
+
+// lastDemotionTime returns the time the workload was last demoted at, and the zero time if it never was.
+func lastDemotionTime(policyRecommendation *ottoscaleriov1alpha1.PolicyRecommendation) time.Time {
+	transitions := policyRecommendation.Status.PolicyTransitions
+	for i := len(transitions) - 1; i >= 0; i-- {
+		if transitions[i].Type == ottoscaleriov1alpha1.PolicyDemoted {
+			return transitions[i].TransitionTime.Time
+		}
+	}
+	return time.Time{}
+}
+
+func (h *BreachHandler) breachEvidence(breaches []metrics.DataPoint) ottoscaleriov1alpha1.BreachEvidence {
+	peakUtilization := 0.0
+	for _, breach := range breaches {
+		peakUtilization = math.Max(peakUtilization, breach.Value)
+	}
+
+	latestBreaches := breaches
+	if len(latestBreaches) > maxBreachTimestamps {
+		latestBreaches = latestBreaches[len(latestBreaches)-maxBreachTimestamps:]
+	}
+	timestamps := make([]metav1.Time, 0, len(latestBreaches))
+	for _, breach := range latestBreaches {
+		timestamps = append(timestamps, metav1.NewTime(breach.Timestamp))
+	}
+
+	return ottoscaleriov1alpha1.BreachEvidence{
+		DetectedAt:             metav1.NewTime(time.Now()),
+		Timestamps:             timestamps,
+		PeakUtilizationPercent: int(math.Round(peakUtilization * 100)),
+		Severe:                 peakUtilization >= h.severeBreachUtilization,
+	}
+}
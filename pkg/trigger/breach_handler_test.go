@@ -0,0 +1,174 @@
+package trigger
+
+import (
+	"context"
+	ottoscaleriov1alpha1 "github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
+	"github.com/flipkart-incubator/ottoscalr/pkg/metrics"
+	"github.com/flipkart-incubator/ottoscalr/pkg/policy"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"time"
+)
+
+var ladder = []ottoscaleriov1alpha1.Policy{
+	{Spec: ottoscaleriov1alpha1.PolicySpec{ID: "safe", RiskIndex: "1", Min: 4, TargetUtilization: 30}},
+	{Spec: ottoscaleriov1alpha1.PolicySpec{ID: "moderate", RiskIndex: "2", Min: 2, TargetUtilization: 50}},
+	{Spec: ottoscaleriov1alpha1.PolicySpec{ID: "risky", RiskIndex: "3", Min: 1, TargetUtilization: 70}},
+}
+
+type FakePolicyStore struct{}
+
+func (ps *FakePolicyStore) GetSafestPolicy() (*ottoscaleriov1alpha1.Policy, error) {
+	return &ladder[0], nil
+}
+
+func (ps *FakePolicyStore) GetNextPolicy(currentPolicy *ottoscaleriov1alpha1.Policy) (*ottoscaleriov1alpha1.Policy,
+	error) {
+	for i, p := range ladder {
+		if p.Spec.RiskIndex == currentPolicy.Spec.RiskIndex && i+1 < len(ladder) {
+			return &ladder[i+1], nil
+		}
+	}
+	return nil, policy.ErrNoNextPolicy
+}
+
+func (ps *FakePolicyStore) GetPreviousPolicy(currentPolicy *ottoscaleriov1alpha1.Policy) (*ottoscaleriov1alpha1.Policy,
+	error) {
+	for i, p := range ladder {
+		if p.Spec.RiskIndex == currentPolicy.Spec.RiskIndex && i > 0 {
+			return &ladder[i-1], nil
+		}
+	}
+	return nil, policy.ErrNoPreviousPolicy
+}
+
+var _ = Describe("BreachHandler", func() {
+	var (
+		handler              *BreachHandler
+		ctx                  context.Context
+		policyRecommendation *ottoscaleriov1alpha1.PolicyRecommendation
+		recoName             = types.NamespacedName{Name: "test-breached-workload", Namespace: "default"}
+	)
+
+	createPolicyRecommendation := func(currentPolicy ottoscaleriov1alpha1.Policy) {
+		policyRecommendation = &ottoscaleriov1alpha1.PolicyRecommendation{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      recoName.Name,
+				Namespace: recoName.Namespace,
+			},
+			Spec: ottoscaleriov1alpha1.PolicyRecommendationSpec{
+				Policy:             currentPolicy,
+				QueuedForExecution: false,
+			},
+		}
+		Expect(k8sClient.Create(ctx, policyRecommendation)).To(Succeed())
+	}
+
+	getPolicyRecommendation := func() *ottoscaleriov1alpha1.PolicyRecommendation {
+		updatedPolicyRecommendation := &ottoscaleriov1alpha1.PolicyRecommendation{}
+		Expect(k8sClient.Get(ctx, recoName, updatedPolicyRecommendation)).To(Succeed())
+		return updatedPolicyRecommendation
+	}
+
+	BeforeEach(func() {
+		handler = NewBreachHandler(k8sClient, &FakePolicyStore{}, 0.95, time.Minute,
+			zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
+		ctx = context.TODO()
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.Delete(ctx, policyRecommendation)).To(Succeed())
+	})
+
+	It("should demote the workload to the previous policy and record the breach evidence", func() {
+		createPolicyRecommendation(ladder[1])
+		breachTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+		handler.HandleBreach(recoName, []metrics.DataPoint{
+			{Timestamp: breachTime, Value: 0.88},
+			{Timestamp: breachTime.Add(30 * time.Second), Value: 0.9},
+		})
+
+		updatedPolicyRecommendation := getPolicyRecommendation()
+		Expect(updatedPolicyRecommendation.Spec.Policy.Spec.ID).To(Equal("safe"))
+		Expect(updatedPolicyRecommendation.Spec.QueuedForExecution).To(BeTrue())
+
+		lastBreach := updatedPolicyRecommendation.Status.LastBreach
+		Expect(lastBreach).NotTo(BeNil())
+		Expect(lastBreach.PeakUtilizationPercent).To(Equal(90))
+		Expect(lastBreach.Severe).To(BeFalse())
+		Expect(lastBreach.Timestamps).To(HaveLen(2))
+		Expect(lastBreach.Timestamps[0].Time.Equal(breachTime)).To(BeTrue())
+
+		transitions := updatedPolicyRecommendation.Status.PolicyTransitions
+		Expect(transitions).To(HaveLen(1))
+		Expect(transitions[0].Type).To(Equal(ottoscaleriov1alpha1.PolicyDemoted))
+		Expect(transitions[0].FromPolicy).To(Equal("moderate"))
+		Expect(transitions[0].ToPolicy).To(Equal("safe"))
+
+		breachDetected := meta.FindStatusCondition(updatedPolicyRecommendation.Status.Conditions,
+			ottoscaleriov1alpha1.BreachDetected)
+		Expect(breachDetected).NotTo(BeNil())
+		Expect(breachDetected.Status).To(Equal(metav1.ConditionTrue))
+		Expect(breachDetected.Reason).To(Equal(ottoscaleriov1alpha1.ReasonBreached))
+		Expect(meta.IsStatusConditionTrue(updatedPolicyRecommendation.Status.Conditions,
+			ottoscaleriov1alpha1.RecommendationQueued)).To(BeTrue())
+	})
+
+	It("should demote the workload straight to the safest policy for severe breaches", func() {
+		createPolicyRecommendation(ladder[2])
+
+		handler.HandleBreach(recoName, []metrics.DataPoint{{Timestamp: time.Now(), Value: 0.98}})
+
+		updatedPolicyRecommendation := getPolicyRecommendation()
+		Expect(updatedPolicyRecommendation.Spec.Policy.Spec.ID).To(Equal("safe"))
+		Expect(updatedPolicyRecommendation.Status.LastBreach.Severe).To(BeTrue())
+		Expect(updatedPolicyRecommendation.Status.PolicyTransitions[0].FromPolicy).To(Equal("risky"))
+		Expect(meta.FindStatusCondition(updatedPolicyRecommendation.Status.Conditions,
+			ottoscaleriov1alpha1.BreachDetected).Reason).To(Equal(ottoscaleriov1alpha1.ReasonSevereBreach))
+	})
+
+	It("should only record the breach evidence when the workload is on the safest policy", func() {
+		createPolicyRecommendation(ladder[0])
+
+		handler.HandleBreach(recoName, []metrics.DataPoint{{Timestamp: time.Now(), Value: 0.9}})
+
+		updatedPolicyRecommendation := getPolicyRecommendation()
+		Expect(updatedPolicyRecommendation.Spec.Policy.Spec.ID).To(Equal("safe"))
+		Expect(updatedPolicyRecommendation.Spec.QueuedForExecution).To(BeTrue())
+		Expect(updatedPolicyRecommendation.Status.LastBreach).NotTo(BeNil())
+		Expect(updatedPolicyRecommendation.Status.PolicyTransitions).To(BeEmpty())
+	})
+
+	It("should not demote the workload again for a breach within the breach window of its demotion", func() {
+		createPolicyRecommendation(ladder[2])
+
+		handler.HandleBreach(recoName, []metrics.DataPoint{{Timestamp: time.Now(), Value: 0.9}})
+		handler.HandleBreach(recoName, []metrics.DataPoint{{Timestamp: time.Now(), Value: 0.9}})
+
+		updatedPolicyRecommendation := getPolicyRecommendation()
+		Expect(updatedPolicyRecommendation.Spec.Policy.Spec.ID).To(Equal("moderate"))
+		Expect(updatedPolicyRecommendation.Status.PolicyTransitions).To(HaveLen(1))
+		// The evidence of the second breach is recorded all the same.
+		Expect(updatedPolicyRecommendation.Status.LastBreach).NotTo(BeNil())
+		Expect(updatedPolicyRecommendation.Spec.QueuedForExecution).To(BeTrue())
+	})
+
+	It("should demote the workload again for a breach after the breach window of its demotion", func() {
+		createPolicyRecommendation(ladder[2])
+		handler.HandleBreach(recoName, []metrics.DataPoint{{Timestamp: time.Now(), Value: 0.9}})
+
+		// The first demotion falls out of the window of the second breach.
+		NewBreachHandler(k8sClient, &FakePolicyStore{}, 0.95, time.Nanosecond,
+			zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true))).HandleBreach(recoName,
+			[]metrics.DataPoint{{Timestamp: time.Now(), Value: 0.9}})
+
+		updatedPolicyRecommendation := getPolicyRecommendation()
+		Expect(updatedPolicyRecommendation.Spec.Policy.Spec.ID).To(Equal("safe"))
+		Expect(updatedPolicyRecommendation.Status.PolicyTransitions).To(HaveLen(2))
+	})
+})
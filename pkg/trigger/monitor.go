@@ -22,6 +22,7 @@ type PolicyRecommendationMonitorManager struct {
 	periodicRequeueFrequency time.Duration
 	breachCheckFrequency     time.Duration
 	handlerFunc              func(workloadName types.NamespacedName)
+	breachHandlerFunc        func(workloadName types.NamespacedName, breaches []metrics.DataPoint)
 	monitors                 map[string]*Monitor
 	monitorMutex             sync.Mutex
 	logger                   logr.Logger
@@ -31,6 +32,7 @@ func NewPolicyRecommendationMonitorManager(metricScraper metrics.Scraper,
 	periodicRequeueFrequency time.Duration,
 	breachCheckFrequency time.Duration,
 	handlerFunc func(workloadName types.NamespacedName),
+	breachHandlerFunc func(workloadName types.NamespacedName, breaches []metrics.DataPoint),
 	stepSec int,
 	cpuRedLine float64,
 	logger logr.Logger) *PolicyRecommendationMonitorManager {
@@ -42,6 +44,7 @@ func NewPolicyRecommendationMonitorManager(metricScraper metrics.Scraper,
 		periodicRequeueFrequency: periodicRequeueFrequency,
 		breachCheckFrequency:     breachCheckFrequency,
 		handlerFunc:              handlerFunc,
+		breachHandlerFunc:        breachHandlerFunc,
 		monitors:                 make(map[string]*Monitor),
 		logger:                   logger,
 	}
@@ -66,6 +69,7 @@ func (mf *PolicyRecommendationMonitorManager) RegisterMonitor(workloadType strin
 		mf.periodicRequeueFrequency,
 		mf.breachCheckFrequency,
 		mf.handlerFunc,
+		mf.breachHandlerFunc,
 		mf.logger)
 
 	mf.monitors[workload.String()] = monitor
@@ -103,6 +107,7 @@ type Monitor struct {
 	periodicRequeueFrequency time.Duration
 	breachCheckFrequency     time.Duration
 	handlerFunc              func(workload types.NamespacedName)
+	breachHandlerFunc        func(workload types.NamespacedName, breaches []metrics.DataPoint)
 	ctx                      context.Context
 	cancel                   context.CancelFunc
 	wg                       sync.WaitGroup
@@ -118,6 +123,7 @@ func NewMonitor(namespace string,
 	periodicRequeueFrequency time.Duration,
 	breachCheckFrequency time.Duration,
 	handlerFunc func(workload types.NamespacedName),
+	breachHandlerFunc func(workload types.NamespacedName, breaches []metrics.DataPoint),
 	logger logr.Logger) *Monitor {
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -131,6 +137,7 @@ func NewMonitor(namespace string,
 		periodicRequeueFrequency: periodicRequeueFrequency,
 		breachCheckFrequency:     breachCheckFrequency,
 		handlerFunc:              handlerFunc,
+		breachHandlerFunc:        breachHandlerFunc,
 		ctx:                      ctx,
 		cancel:                   cancel,
 		logger:                   logger,
@@ -175,7 +182,9 @@ func (m *Monitor) monitorBreaches() {
 					"workloadName", m.workload.Name)
 			}
 			if len(dataPoints) > 0 {
-				m.handlerFunc(m.workload)
+				// Breaches are handled ahead of the periodic workflow, so that the workload can be moved to a
+				// safer policy right away.
+				m.breachHandlerFunc(m.workload, dataPoints)
 			}
 		}
 	}
@@ -33,8 +33,33 @@ func (fs *FakeScraper) GetCPUUtilizationBreachDataPoints(namespace,
 	return []metrics.DataPoint{datapoint}, nil
 }
 func (fs *FakeScraper) GetACLByWorkload(namespace,
-	workload string) (time.Duration, error) {
-	return 5 * time.Minute, nil
+	workload string) (metrics.AutoscalingCycleLag, error) {
+	return metrics.AutoscalingCycleLag{Readiness: 5 * time.Minute}, nil
+}
+
+func (fs *FakeScraper) GetMemoryWorkingSetByWorkload(namespace,
+	workload string,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]metrics.DataPoint, error) {
+	return []metrics.DataPoint{}, nil
+}
+
+func (fs *FakeScraper) GetMemoryLimitsPerPodByWorkload(namespace,
+	workload string) (float64, error) {
+	return 0.0, nil
+}
+
+func (fs *FakeScraper) GetMemoryRequestsPerPodByWorkload(namespace,
+	workload string) (float64, error) {
+	return 0.0, nil
+}
+
+func (fs *FakeScraper) GetMetricByQuery(query string,
+	start time.Time,
+	end time.Time,
+	step time.Duration) ([]metrics.DataPoint, error) {
+	return []metrics.DataPoint{}, nil
 }
 
 func (fs *FakeScraper) GetPodReadyLatencyByWorkload(namespace,
@@ -49,50 +74,57 @@ var _ = Describe("PolicyRecommendationMonitorManager and Monitor", func() {
 		handlerFunc        = func(workload types.NamespacedName) {
 			atomic.AddInt32(&handlerCallCounter, 1)
 		}
+		breachHandlerCallCounter int32
+		breachHandlerFunc        = func(workload types.NamespacedName, breaches []metrics.DataPoint) {
+			atomic.AddInt32(&breachHandlerCallCounter, 1)
+		}
 	)
 
 	BeforeEach(func() {
 		handlerCallCounter = 0
+		breachHandlerCallCounter = 0
 	})
 
 	AfterEach(func() {
 		manager.Shutdown()
 	})
 
-	It("should call handler when breaches are detected", func() {
+	It("should call breach handler when breaches are detected", func() {
 
 		By("Creating a monitor mgr that only detects breaches")
 		manager = NewPolicyRecommendationMonitorManager(&FakeScraper{},
 			1*time.Hour,
 			1*time.Second,
 			handlerFunc,
+			breachHandlerFunc,
 			10,
 			80,
 			zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
 		workload := types.NamespacedName{Name: "test-workload", Namespace: "default"}
 		workloadType := "test-workload-type"
 
-		By("Registering the monitor and checking that handler is called periodically")
+		By("Registering the monitor and checking that breach handler is called periodically")
 		monitor := manager.RegisterMonitor(workloadType, workload)
 		Expect(monitor).ToNot(BeNil())
 
 		time.Sleep(3 * time.Second)
-		Expect(handlerCallCounter).To(BeNumerically(">=", 2))
-		Expect(handlerCallCounter).To(BeNumerically("<=", 3))
+		Expect(breachHandlerCallCounter).To(BeNumerically(">=", 2))
+		Expect(breachHandlerCallCounter).To(BeNumerically("<=", 3))
+		Expect(handlerCallCounter).To(BeZero())
 
-		By("Deregistering the counter and checking that handler is not called anymore")
+		By("Deregistering the counter and checking that breach handler is not called anymore")
 		manager.DeregisterMonitor(workload)
 
-		currentCallCounter := handlerCallCounter
+		currentCallCounter := breachHandlerCallCounter
 		time.Sleep(3 * time.Second)
-		Expect(handlerCallCounter).To(Equal(currentCallCounter))
+		Expect(breachHandlerCallCounter).To(Equal(currentCallCounter))
 
-		By("Reregistering the counter and checking that handler is not called anymore")
+		By("Reregistering the counter and checking that breach handler is not called anymore")
 		monitor = manager.RegisterMonitor(workloadType, workload)
 
-		currentCallCounter = handlerCallCounter
+		currentCallCounter = breachHandlerCallCounter
 		time.Sleep(3 * time.Second)
-		Expect(handlerCallCounter).To(BeNumerically(">", currentCallCounter))
+		Expect(breachHandlerCallCounter).To(BeNumerically(">", currentCallCounter))
 	})
 
 	It("should call handler when periodic trigger is fired", func() {
@@ -102,6 +134,7 @@ var _ = Describe("PolicyRecommendationMonitorManager and Monitor", func() {
 			1*time.Second,
 			1*time.Hour,
 			handlerFunc,
+			breachHandlerFunc,
 			10,
 			80,
 			zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
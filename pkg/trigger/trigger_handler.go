@@ -4,8 +4,10 @@ import (
 	"context"
 	ottoscaleriov1alpha1 "github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
 	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
 )
 
 type Handler interface {
@@ -55,11 +57,22 @@ func (h *K8sTriggerHandler) queuePolicyRecommendations() {
 			continue
 		}
 
+		// The status is captured before the spec update, as the update overwrites the object with the server state.
+		status := policyRecommendation.Status.DeepCopy()
+		status.SetCondition(ottoscaleriov1alpha1.RecommendationQueued, metav1.ConditionTrue,
+			ottoscaleriov1alpha1.ReasonTriggered, "Queued for execution by the periodic trigger")
+
 		policyRecommendation.Spec.QueuedForExecution = true
+		policyRecommendation.Spec.QueuedForExecutionAt = metav1.NewTime(time.Now())
 		err = h.k8sClient.Update(context.Background(), policyRecommendation)
 		if err != nil {
 			h.logger.Error(err, "Error while queueing policyRecommendation.", "workload", workload)
 			continue
 		}
+
+		policyRecommendation.Status = *status
+		if err := h.k8sClient.Status().Update(context.Background(), policyRecommendation); err != nil {
+			h.logger.Error(err, "Error while updating policyRecommendation status.", "workload", workload)
+		}
 	}
 }
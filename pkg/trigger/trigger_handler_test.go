@@ -5,6 +5,7 @@ import (
 	ottoscaleriov1alpha1 "github.com/flipkart-incubator/ottoscalr/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -54,6 +55,8 @@ var _ = Describe("K8sTriggerHandler", func() {
 
 			// Check if the QueuedForExecution field was updated
 			Expect(updatedPolicyRecommendation.Spec.QueuedForExecution).Should(BeTrue())
+			Expect(meta.IsStatusConditionTrue(updatedPolicyRecommendation.Status.Conditions,
+				ottoscaleriov1alpha1.RecommendationQueued)).Should(BeTrue())
 
 			// Clean up
 			err = k8sClient.Delete(ctx, policyRecommendation)